@@ -2,30 +2,28 @@
 // it. The characters chosen are based on the entity type / prototype, using
 // a hash mapped to A-Z/a-z/0-9.
 //
-// The public interface is unstable.
+// Each tile of the map is drawn as TileWidth by TileHeight characters, and
+// entities are drawn over all the tiles they cover, with sizes from
+// prototype_blueprint.
 //
-// BUG: The code currently has a major misunderstanding: just because
-// coordinates can be at position 0.5 does not mean we will in practice observe
-// such an offset between items. The granularity really seems to be at 1.0 for
-// blueprints. This means any use of *2 and any advice to do it is probably
-// misguided. The code is a decent start, but should not be used or seen as
-// correct. We can keep it ONLY as an internal piece of code for purposes of
-// tilemap (to use integers), but with sparse tilemap maybe even that is not
-// necessary.
+// The public interface is unstable.
 package asciiart_blueprint // badc0de.net/pkg/factorioblueprint/asciiart_blueprint
 
 import (
 	"fmt"
 	"io"
-	"math"
 	"sort"
 	"strings"
 
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
 	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
 )
 
+// vanillaPrototypes is the registry used by readers without Prototypes.
+var vanillaPrototypes = prototype_blueprint.Vanilla()
+
 // Reader is a struct that holds the blueprint schema and reads ASCII art for
-// it. Precision more than 0.5 in x and y coordinates is not supported here.
+// it.
 type Reader struct {
 	// Note: updating TileWidth and TileHeight is invalid after the initial
 	// creation, without invalidating other cache, and these fields will become
@@ -35,9 +33,14 @@ type Reader struct {
 	TileWidth  int // TileWidth is the size of the tiles in characters.
 	TileHeight int // TileHeight is the size of the tiles in characters.
 
+	// Prototypes gives the sizes of entities. If nil, the vanilla
+	// prototypes of prototype_blueprint.Vanilla are used. Setting it after
+	// the first read is invalid, like for TileWidth and TileHeight.
+	Prototypes *prototype_blueprint.Registry
+
 	displayRune               map[string]rune   // displayRune maps entity type / tile prototype to a character.
 	legend                    map[rune][]string // legend maps a character to a list of entity types / tile prototypes.
-	cachedWidth, cachedHeight float64           // cachedWidth and cachedHeight are the size of the blueprint in native factorio size.
+	cachedWidth, cachedHeight float64           // cachedWidth and cachedHeight are the size of the blueprint in tiles.
 	cachedSparseTilemap       *SparseTilemap    // cachedSparseTilemap is the sparse tilemap for the blueprint.
 
 	// buffer holds the generated ASCII art data
@@ -78,18 +81,17 @@ func (r *Reader) Read(p []byte) (n int, err error) {
 func (r *Reader) generateASCIIArt() (string, error) {
 	var sb strings.Builder
 
-	// r.Size() returns the width and height, but we need to multiply by two
-	// to get integer size.
+	// r.Size() returns the width and height in whole tiles.
 	widthF, heightF := r.Size()
-	width := int(widthF) * 2
-	height := int(heightF) * 2
+	width := int(widthF)
+	height := int(heightF)
 
 	// Loop over y and x to build the ASCII art.
 	// Note that we have to start with the smallest x and smallest y to get the
 	// correct order.
 	minX, minY, _, _ := r.box()
-	startX := int(minX*2) * r.TileWidth
-	startY := int(minY*2) * r.TileHeight
+	startX := int(minX) * r.TileWidth
+	startY := int(minY) * r.TileHeight
 	//for y := startY + int(height)*r.TileHeight - 1; y >= startY; y-- {
 	for y := startY; y < startY+int(height)*r.TileHeight; y++ { // every line
 		for x := startX; x < startX+int(width)*r.TileWidth; {
@@ -129,11 +131,9 @@ func (r *Reader) generateLegend() (string, error) {
 	return strings.Join(entries, "\n"), nil
 }
 
-// Size computes width an height of the coordinates. Factorio holds the
-// coordinates at 0.5 precision.
-//
-// Caller needs to multiply by two to get the integer size, and then also use
-// TileWidth and TileHeight to get the actual size in characters.
+// Size computes width and height of the blueprint in tiles, counting all the
+// tiles covered by entities. Use TileWidth and TileHeight to get the actual
+// size in characters.
 //
 // The blueprint is allowed to contain negative coordinates.
 func (r *Reader) Size() (float64, float64) {
@@ -151,45 +151,23 @@ func (r *Reader) Size() (float64, float64) {
 	return r.cachedWidth, r.cachedHeight
 }
 
-// box computes minxy and maxxy of the blueprint. Factorio holds the coordinates
-// at 0.5 precision. To get the box, we add 1.0 to the max x and y, since then
-// we don't return 0x0 for a blueprint of size of a single tile.
+// box computes minxy and maxxy of the blueprint: the corners of the tiles
+// covered by its entities and tiles. An empty blueprint has an empty box at
+// the origin.
 func (r *Reader) box() (float64, float64, float64, float64) {
-	minX := math.MaxFloat64
-	minY := math.MaxFloat64
-	maxX := math.MaxFloat64 * -1
-	maxY := math.MaxFloat64 * -1
-
-	for _, entity := range r.Blueprint.Entities {
-		if entity.Position.X < minX {
-			minX = entity.Position.X
-		}
-		if entity.Position.Y < minY {
-			minY = entity.Position.Y
-		}
-		if entity.Position.X > maxX {
-			maxX = entity.Position.X
-		}
-		if entity.Position.Y > maxY {
-			maxY = entity.Position.Y
-		}
-	}
-	for _, tile := range r.Blueprint.Tiles {
-		if tile.Position.X < minX {
-			minX = tile.Position.X
-		}
-		if tile.Position.Y < minY {
-			minY = tile.Position.Y
-		}
-		if tile.Position.X > maxX {
-			maxX = tile.Position.X
-		}
-		if tile.Position.Y > maxY {
-			maxY = tile.Position.Y
-		}
+	bounds, ok := r.prototypes().Bounds(r.Blueprint)
+	if !ok {
+		return 0, 0, 0, 0
 	}
+	return bounds.LeftTop.X.Float64(), bounds.LeftTop.Y.Float64(), bounds.RightBottom.X.Float64(), bounds.RightBottom.Y.Float64()
+}
 
-	return minX, minY, maxX + 1, maxY + 1
+// prototypes returns the registry giving the sizes of entities.
+func (r *Reader) prototypes() *prototype_blueprint.Registry {
+	if r.Prototypes == nil {
+		return vanillaPrototypes
+	}
+	return r.Prototypes
 }
 
 // computeLegend computes the legend and the displayRune cache for the ASCII art.
@@ -212,7 +190,7 @@ func (r *Reader) computeLegend() {
 
 	for _, entity := range r.Blueprint.Entities {
 		// n.b. it might be better to use the icon name here.
-		nameForDisplay := entity.Name
+		nameForDisplay := entityNameForDisplay(&entity)
 
 		if _, ok := r.displayRune[nameForDisplay]; !ok {
 			rn := stringToRune(nameForDisplay)
@@ -271,10 +249,20 @@ func (r *Reader) RuneForEntityOrTile(et *entityOrTile) rune {
 // name here, and then reuse this in computeLegend.
 func (r *Reader) EntityChar(entity *blueprint_schema.Entity) rune {
 	r.computeLegend() // precompute hashes if needed
-	nameForDisplay := entity.Name
+	nameForDisplay := entityNameForDisplay(entity)
 	return r.displayRune[nameForDisplay]
 }
 
+// entityNameForDisplay returns the name of the entity prototype, followed by
+// the quality unless it is normal, so that entities of different qualities
+// are told apart.
+func entityNameForDisplay(entity *blueprint_schema.Entity) string {
+	if q := blueprint_schema.QualityOf(entity.Quality); !q.IsNormal() {
+		return fmt.Sprintf("%s (%s)", entity.Name, q)
+	}
+	return entity.Name
+}
+
 // TileChar returns a character for the tile prototype.
 //
 // TODO: we may want to invert responsibility and compute the hash from the
@@ -286,9 +274,8 @@ func (r *Reader) TileChar(tile *blueprint_schema.Tile) rune {
 }
 
 // SparseTilemap is a sparse tilemap for the blueprint. Each tile in the tilemap
-// is a struct with slices of entities and tiles at that position. Since these
-// are integers, the position in blueprint is multiplied by two to get the
-// actual position we will use in the tilemap.
+// is a struct with slices of entities and tiles at that position, keyed by the
+// tile position, so an entity is found at every tile it covers.
 type SparseTilemap struct {
 	st map[int]map[int]*sparseTilemapTile
 }
@@ -319,17 +306,16 @@ func (s *sparseTilemapTile) top() *entityOrTile {
 
 // At returns the entities and tiles at a position in the sparse tilemap. If
 // there are no entities or tiles at the position, it returns nil. The
-// coordinates are the same ones as in the blueprint, not multiplied by two.
-// They will be multiplied accordingly.
+// coordinates are the same ones as in the blueprint, and are looked up in the
+// tile they are in.
 func (s *SparseTilemap) At(x, y float64) *sparseTilemapTile {
-	x *= 2
-	y *= 2
-	return s.AtInt(int(x), int(y))
+	t := blueprint_schema.Position{X: x, Y: y}.Fixed().Tile()
+	return s.AtInt(t.X, t.Y)
 }
 
 // AtInt returns the entities and tiles at a position in the sparse tilemap. If
 // there are no entities or tiles at the position, it returns nil. The
-// coordinates are the same ones as in the blueprint but multiplied by two.
+// coordinates are those of the tile, as in blueprint_schema.TilePosition.
 func (s *SparseTilemap) AtInt(x, y int) *sparseTilemapTile {
 	if s == nil {
 		return nil
@@ -349,7 +335,7 @@ func (s *SparseTilemap) AtInt(x, y int) *sparseTilemapTile {
 // EntityOrTileAtPosition returns the top entity or tile at a position. If there
 // is no entity or tile at the position, it returns nil.
 //
-// The x and y coordinates are the blueprint coordinates multiplied by two.
+// The x and y coordinates are those of the tile.
 func (s *SparseTilemap) EntityOrTileAtIntPosition(x, y int) *entityOrTile {
 	if s == nil {
 		return nil
@@ -363,9 +349,7 @@ func (s *SparseTilemap) EntityOrTileAtIntPosition(x, y int) *entityOrTile {
 //
 // The x and y coordinates are the blueprint coordinates.
 func (s *SparseTilemap) EntityOrTileAtPosition(x, y float64) *entityOrTile {
-	x *= 2
-	y *= 2
-	return s.EntityOrTileAtIntPosition(int(x), int(y))
+	return s.At(x, y).top()
 }
 
 type entityOrTile struct {
@@ -374,9 +358,8 @@ type entityOrTile struct {
 }
 
 // AsSparseTilemap returns a sparse tilemap for the blueprint. Each tile in the
-// tilemap is a struct with slices of entities and tiles at that position. Since
-// these are integers, the position in blueprint is multiplied by two to get the
-// actual position we will use in the tilemap.
+// tilemap is a struct with slices of entities and tiles at that position.
+// Entities are added to every tile they cover.
 func (r *Reader) AsSparseTilemap() *SparseTilemap {
 	if r.cachedSparseTilemap != nil {
 		return r.cachedSparseTilemap
@@ -385,39 +368,40 @@ func (r *Reader) AsSparseTilemap() *SparseTilemap {
 	tilemap := newSparseTilemap()
 
 	for _, entity := range r.Blueprint.Entities {
-		x := int(entity.Position.X * 2)
-		y := int(entity.Position.Y * 2)
-		if _, ok := tilemap.st[x]; !ok {
-			tilemap.st[x] = make(map[int]*sparseTilemapTile)
-		}
-		if _, ok := tilemap.st[x][y]; !ok {
-			tilemap.st[x][y] = &sparseTilemapTile{}
-		}
-		// fmt.Printf("adding entity %d=%q to %d, %d\n", entity.EntityNumber, entity.Name, x, y)
 		// entity is a local var and would be overwritten, so copy it
 		entity := entity
-		tilemap.st[x][y].Entities = append(tilemap.st[x][y].Entities, &entity)
+		topLeft, width, height := r.prototypes().EntityTiles(r.Blueprint, &entity)
+		for y := topLeft.Y; y < topLeft.Y+height; y++ {
+			for x := topLeft.X; x < topLeft.X+width; x++ {
+				t := tilemap.add(x, y)
+				t.Entities = append(t.Entities, &entity)
+			}
+		}
 	}
 
 	for _, tile := range r.Blueprint.Tiles {
-		x := int(tile.Position.X * 2)
-		y := int(tile.Position.Y * 2)
-		if _, ok := tilemap.st[x]; !ok {
-			tilemap.st[x] = make(map[int]*sparseTilemapTile)
-		}
-		if _, ok := tilemap.st[x][y]; !ok {
-			tilemap.st[x][y] = &sparseTilemapTile{}
-		}
-		fmt.Printf("adding tile %q to %d, %d\n", tile.Name, x, y)
 		// tile is a local var and would be overwritten, so copy it
 		tile := tile
-		tilemap.st[x][y].Tiles = append(tilemap.st[x][y].Tiles, &tile)
+		pos := tile.Position.Fixed().Tile()
+		t := tilemap.add(pos.X, pos.Y)
+		t.Tiles = append(t.Tiles, &tile)
 	}
 
 	r.cachedSparseTilemap = tilemap
 	return tilemap
 }
 
+// add returns the tile at x, y of the tilemap, creating it if needed.
+func (s *SparseTilemap) add(x, y int) *sparseTilemapTile {
+	if _, ok := s.st[x]; !ok {
+		s.st[x] = make(map[int]*sparseTilemapTile)
+	}
+	if _, ok := s.st[x][y]; !ok {
+		s.st[x][y] = &sparseTilemapTile{}
+	}
+	return s.st[x][y]
+}
+
 // StringAtScreenPosition returns a string with one or more runes at a position
 // in the returned string. It returns at minimum the rune representing the top
 // entity or tile, preferring entities over tiles.
@@ -436,72 +420,72 @@ func (r *Reader) AsSparseTilemap() *SparseTilemap {
 //
 // Example with TileWidth 1:
 // * TileHeight 1: if we would draw an entity which is at 4.5, 6.5, and the
-//   entity points up, and we are correctly invoked with x=9, y=13, then the
+//   entity points up, and we are correctly invoked with x=4, y=6, then the
 //   string returned would be "A".
 // * TileHeight 2: if we would draw an entity which is at 4.5, 6.5, and the
-//   entity points up, and we are correctly invoked with x=9, y=13*2=26, then
+//   entity points up, and we are correctly invoked with x=4, y=6*2=12, then
 //   the string returned would be "A". If we are then invoked for the line below
-//   (x=9, y=13*2+1=27), then the string returned would be "^". (If the entity
+//   (x=4, y=6*2+1=13), then the string returned would be "^". (If the entity
 //   had no direction indicated, then the string would be "A" and " ").
 // * TileHeight 3: this is an unsupported configuration and we return an error.
 //
 // Example with TileWidth 5:
 // * TileHeight 1: if we would draw an entity which is at 4.5, 6.5, and we are
-//   correctly invoked with x=9*5 for the leftmost char, y=13 for the only
+//   correctly invoked with x=4*5 for the leftmost char, y=6 for the only
 //   line, then the string returned would be " ^A  ".
 // * TileHeight 2: if we would draw an entity which is at 4.5, 6.5, and the
-//   entity points up, and we are correctly invoked with x=9*5 for the leftmost
-//   char, y=13*2=26 for the top line, then the string returned would be
+//   entity points up, and we are correctly invoked with x=4*5 for the leftmost
+//   char, y=6*2=12 for the top line, then the string returned would be
 //   "  A  ".
-//   If we are then invoked for the line below (x=9*5, y=13*2+1=27), then the
+//   If we are then invoked for the line below (x=4*5, y=6*2+1=13), then the
 //   string returned would be "  ^  ".
 
 //   - TileHeight 3: if we would draw an entity which is at 4.5, 6.5, and the
-//     entity points up, and we are correctly invoked with x=9*5 for the leftmost
-//     char, y=13*3+1=40 for the mid line, then the string returned would be
+//     entity points up, and we are correctly invoked with x=4*5 for the leftmost
+//     char, y=6*3+1=19 for the mid line, then the string returned would be
 //     "  A  ".
-//     If we are then invoked for the line below (x=9*5, y=13*3+2=41),
+//     If we are then invoked for the line below (x=4*5, y=6*3+2=20),
 //     then the string returned would be "     " (no direction indicator since we
 //     point up).
-//     If we are then invoked for the line above (x=9*5, y=13*3+0=39), then the
+//     If we are then invoked for the line above (x=4*5, y=6*3+0=18), then the
 //     string returned would be "  ^  ".
 //   - TileHeight 3 (left): if we would draw an entity which is at 4.5, 6.5, and
-//     the entity points left, and we are correctly invoked with x=9*5=45 for the
-//     leftmost char, y=13*3+1=40 for the mid line, then the string returned would
+//     the entity points left, and we are correctly invoked with x=4*5=20 for the
+//     leftmost char, y=6*3+1=19 for the mid line, then the string returned would
 //     be " <A  ".
-//     If we are then invoked for the line below (x=9*5, y=13*3+2=41),
+//     If we are then invoked for the line below (x=4*5, y=6*3+2=20),
 //     then the string returned would be "     " (no direction indicator since we
 //     point left).
-//     If we are then invoked for the line above (x=9*5, y=13*3+0=39),
+//     If we are then invoked for the line above (x=4*5, y=6*3+0=18),
 //     then the string returned would be "     " (no direction indicator since we
 //     point left).
 //   - TileHeight 3 (right): if we would draw an entity which is at 4.5, 6.5, and
-//     the entity points right, and we are correctly invoked with x=9*5=45 for the
-//     leftmost char, y=13*3+1=40 for the mid line, then the string returned would
+//     the entity points right, and we are correctly invoked with x=4*5=20 for the
+//     leftmost char, y=6*3+1=19 for the mid line, then the string returned would
 //     be "  A> ".
-//     If we are then invoked for the line below (x=9*5, y=13*3+2=41),
+//     If we are then invoked for the line below (x=4*5, y=6*3+2=20),
 //     then the string returned would be "     " (no direction indicator since we
 //     point right).
-//     If we are then invoked for the line above (x=9*5, y=13*3+0=39),
+//     If we are then invoked for the line above (x=4*5, y=6*3+0=18),
 //     then the string returned would be "     " (no direction indicator since we
 //     point right).
 //   - TileHeight 3 (up): if we would draw an entity which is at 4.5, 6.5, and
-//     the entity points up, and we are correctly invoked with x=9*5=45 for the
-//     leftmost char, y=13*3+1=40 for the mid line, then the string returned would
+//     the entity points up, and we are correctly invoked with x=4*5=20 for the
+//     leftmost char, y=6*3+1=19 for the mid line, then the string returned would
 //     be "  A  ".
-//     If we are then invoked for the line below (x=9*5, y=13*3+2=41),
+//     If we are then invoked for the line below (x=4*5, y=6*3+2=20),
 //     then the string returned would be "     " (no direction indicator since we
 //     point up).
-//     If we are then invoked for the line above (x=9*5, y=13*3+0=39),
+//     If we are then invoked for the line above (x=4*5, y=6*3+0=18),
 //     then the string returned would be "  ^  ".
 //   - TileHeight 3 (down): if we would draw an entity which is at 4.5, 6.5, and
-//     the entity points down, and we are correctly invoked with x=9*5=45 for the
-//     leftmost char, y=13*3+1=40 for the mid line, then the string returned would
+//     the entity points down, and we are correctly invoked with x=4*5=20 for the
+//     leftmost char, y=6*3+1=19 for the mid line, then the string returned would
 //     be "  V  ".
-//     If we are then invoked for the line below (x=9*5, y=13*3+2=41),
+//     If we are then invoked for the line below (x=4*5, y=6*3+2=20),
 //     then the string returned would be "     " (no direction indicator since we
 //     point down).
-//     If we are then invoked for the line above (x=9*5, y=13*3+0=39),
+//     If we are then invoked for the line above (x=4*5, y=6*3+0=18),
 //     then the string returned would be "     " (no direction indicator since we
 //     point down).
 //   - TileHeight 4: this is an unsupported configuration and we return an error.
@@ -524,21 +508,20 @@ func (r *Reader) StringAtScreenPosition(x, y int) (string, error) {
 
 // stringAtScreenPositionHeight1 deals with case of TileHeight 1.
 //
-// Accepted coordinates are factorioX*2*TileWidth, factorioY*2. Note: this
-// means the caller is expected to have corrected for TileHeight != 1, usually
-// by dividing.
+// Accepted coordinates are tileX*TileWidth, tileY. Note: this means the caller
+// is expected to have corrected for TileHeight != 1, usually by dividing.
 //
 // If there is enough space, direction is preferred to be on the left.
 // If adaptiveLeftRight, the direction is preferred to be whatever the
 // side the direction is pointing at.
 func (r *Reader) stringAtScreenPositionHeight1(x, y int, includeUpDown bool, adaptiveLeftRight bool, includePosition bool) (string, error) {
 	r.computeLegend()
-	x = x / r.TileWidth
+	x = floorDiv(x, r.TileWidth)
 	tilemap := r.AsSparseTilemap()
 	et := tilemap.EntityOrTileAtIntPosition(x, y)
 
 	if et == nil {
-		return " ", nil
+		return strings.Repeat(" ", r.TileWidth), nil
 	}
 
 	etRune := r.RuneForEntityOrTile(et)
@@ -555,7 +538,7 @@ func (r *Reader) stringAtScreenPositionHeight1(x, y int, includeUpDown bool, ada
 	// If the width is 2, we can afford to have the direction indicator.
 	directionRune := ' '
 	if includePosition {
-		directionRune = entityToDirectionRune(et.entity)
+		directionRune = r.directionRuneAt(et, x, y)
 		if !includeUpDown && (directionRune == '^' || directionRune == 'V') {
 			directionRune = ' '
 		}
@@ -640,6 +623,8 @@ func (r *Reader) stringAtScreenPositionHeight1CenterBit(includeUpDown, adaptiveL
 			} else {
 				return ">" + string(etRune), nil
 			}
+		case '/', '\\':
+			return string(directionRune) + string(etRune), nil
 		default:
 			return " " + string(etRune), nil
 		}
@@ -660,17 +645,17 @@ func (r *Reader) stringAtScreenPositionHeight1CenterBit(includeUpDown, adaptiveL
 
 // stringAtScreenPositionHeight2 deals with case of TileHeight 2.
 //
-// Accepted coordinates are factorioX*2*TileWidth, factorioY*2*TileHeight.
+// Accepted coordinates are tileX*TileWidth, tileY*TileHeight.
 func (r *Reader) stringAtScreenPositionHeight2(x, y int) (string, error) {
 	r.computeLegend()
-	if y%2 == 0 {
+	if floorMod(y, r.TileHeight) == 0 {
 		// We are on the top line.
-		y = y / r.TileHeight
+		y = floorDiv(y, r.TileHeight)
 		return r.stringAtScreenPositionHeight1(x, y, false, false, false)
 	}
 	// Next case is the bottom line which draws only the direction, centered.
 	// All directions are covered.
-	return r.centeredDirectionLine(x, y/r.TileHeight, "^V<>")
+	return r.centeredDirectionLine(x, floorDiv(y, r.TileHeight), "^V<>")
 }
 
 // centeredDirectionLine draws the direction indicator centered on the line.
@@ -682,14 +667,14 @@ func (r *Reader) stringAtScreenPositionHeight2(x, y int) (string, error) {
 //
 // A good default for permittedRunes is "^V<>".
 func (r *Reader) centeredDirectionLine(x, y int, permittedRunes string) (string, error) {
-	x = x / r.TileWidth
+	x = floorDiv(x, r.TileWidth)
 	tilemap := r.AsSparseTilemap()
 	et := tilemap.EntityOrTileAtIntPosition(x, y)
 	if et == nil {
-		return " ", nil
+		return strings.Repeat(" ", r.TileWidth), nil
 	}
 
-	directionRune := entityToDirectionRune(et.entity)
+	directionRune := r.directionRuneAt(et, x, y)
 	if !strings.ContainsRune(permittedRunes, directionRune) {
 		// substitute with space
 		directionRune = ' '
@@ -704,7 +689,7 @@ func (r *Reader) centeredDirectionLine(x, y int, permittedRunes string) (string,
 
 // stringAtScreenPositionHeight3 deals with case of TileHeight 3.
 //
-// Accepted coordinates are factorioX*2*TileWidth, factorioY*2*TileHeight.
+// Accepted coordinates are tileX*TileWidth, tileY*TileHeight.
 //
 // The difference from height 2 is that the top line gets the indicator for
 // up, the bottom line gets the indicator for down, and the middle line gets
@@ -718,47 +703,77 @@ func (r *Reader) stringAtScreenPositionHeight3(x, y int) (string, error) {
 	}
 
 	// Determine: is it top or bottom line?
-	switch y % 3 {
+	switch floorMod(y, r.TileHeight) {
 	case 0:
 		// Top line.
 		//
 		// As long as the direction rune is ^, treat like height 2's bottom
 		// line.
-		y /= r.TileHeight
+		y = floorDiv(y, r.TileHeight)
 		return r.centeredDirectionLine(x, y, "^")
 	case 1:
 		// Middle line.
 		//
 		// Reuse 'single line' code, but only support left and right.
-		y /= r.TileHeight
+		y = floorDiv(y, r.TileHeight)
 		return r.stringAtScreenPositionHeight1(x, y, false, true, true)
 	case 2:
 		// Bottom line.
 		//
 		// As long as the direction rune is V, treat like height 2's top line.
-		y /= r.TileHeight
+		y = floorDiv(y, r.TileHeight)
 		return r.centeredDirectionLine(x, y, "V")
 	default:
 		return "", fmt.Errorf("TODO: not fully implemented")
 	}
 }
 
-// entityToDirection returns a string with a direction indicator for the entity.
-// The direction indicator is a character from the set ^V<> for up, down, left,
-// right. If the entity has no direction, it returns a space.
-func entityToDirectionRune(entity *blueprint_schema.Entity) rune {
-	if entity.Direction == nil {
+// directionRuneAt returns the direction indicator of et drawn on the tile at
+// x, y. Entities covering more than one tile show it only on the tile their
+// center is in.
+func (r *Reader) directionRuneAt(et *entityOrTile, x, y int) rune {
+	if et.entity == nil || et.entity.Position.Fixed().Tile() != (blueprint_schema.TilePosition{X: x, Y: y}) {
 		return ' '
 	}
-	switch *entity.Direction {
-	case 0:
+	return entityToDirectionRune(r.Blueprint, et.entity)
+}
+
+// floorDiv divides a by b rounding towards negative infinity, so that screen
+// positions left of and above the origin land in the right tile.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// floorMod returns the remainder of floorDiv, which has the sign of b.
+func floorMod(a, b int) int {
+	return a - floorDiv(a, b)*b
+}
+
+// entityToDirectionRune returns a direction indicator for the entity, which
+// is in bp. The direction indicator is a character from the set ^V<> for up,
+// down, left, right, or / and \ for the diagonals, such as of rails. If the
+// entity has no direction, or one in between those, it returns a space.
+func entityToDirectionRune(bp *blueprint_schema.Blueprint, entity *blueprint_schema.Entity) rune {
+	if entity == nil || entity.Direction == nil {
+		return ' '
+	}
+	switch bp.EntityDirection(entity) {
+	case blueprint_schema.DirectionNorth:
 		return '^'
-	case 2:
+	case blueprint_schema.DirectionEast:
 		return '>'
-	case 4:
+	case blueprint_schema.DirectionSouth:
 		return 'V'
-	case 6:
+	case blueprint_schema.DirectionWest:
 		return '<'
+	case blueprint_schema.DirectionNorthEast, blueprint_schema.DirectionSouthWest:
+		return '/'
+	case blueprint_schema.DirectionNorthWest, blueprint_schema.DirectionSouthEast:
+		return '\\'
 	default:
 		return ' '
 	}
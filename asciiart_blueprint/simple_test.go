@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
 	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
 )
 
@@ -101,23 +102,14 @@ func ExampleReader_has1x1() {
 	out := strings.Replace(buf.String(), " ", ".", -1)
 	fmt.Print(out)
 
-	// BUG: there is extra spacing in the sample output, due to misunderstanding
-	// about the appearance of 0.5 indicating that tiles are 0.5x0.5. Uses of
-	// 0.5 should be removed.
-
-	// TODO: the 2x2 furnace is actually in top right, not in top left, of
-	// its expected position. Does Factorio actually store center of the object
-	// as its position? Maybe instead of *2 we need to +=0.5 to everything?
-
-	// See also comments in ExampleReader_2x2.
+	// The furnace is 2x2, so its center at 0, -1 is the corner between its
+	// four tiles.
 
 	// Output:
-	// ...k
-	// ....
-	// ....
-	// ..I.
-	// ....
-	// v.v.
+	// .kk
+	// .kk
+	// .I.
+	// vv.
 	// ---
 	// [I]:.inserter
 	// [k]:.stone-furnace
@@ -148,54 +140,18 @@ func ExampleReader_has2x2() {
 	out := strings.Replace(buf.String(), " ", ".", -1)
 	fmt.Print(out)
 
-	// BUG: same bugs and notes apply as with ExampleReader (extra whitespace).
-	//
-	// BUG: the furnace is drawn twice, once clipping outside the tilemap.
-	//
 	// BUG: the directions are centered differently than the entities themselves
 	// which is unintended.
-	//
-	// Note that the blueprint is actually something like this:
-	// .kk
-	// .kk
-	// .I.
-	// vv.
-	//
-	// Or on this 2x2:
-	// ..kkkk
-	// ..kkkk
-	// ..kkkk
-	// ..kkkk
-	// ..II..
-	// ..II..
-	// vvvv..
-	// ......
-	//
-	// then given how we actually do 2x2 to draw directions, we should expect
-	// something resembling this (especially since we don't have knowledge that
-	// the furnace is 2x2):
-	// .....k
-	// ...... (no direction)
+
+	// Output:
+	// ...k.k
 	// ......
+	// ...k.k
 	// ......
 	// ...I..
-	// ...V..
+	// ..V...
 	// .v.v..
-	// .<.<..
-
-	// Output:
-	// ......k.k
-	// ........
-	// ........
-	// ........
-	// ........
-	// ........
-	// ....I...
-	// ...V....
-	// ........
-	// ........
-	// .v..v...
-	// <..<....
+	// <.<...
 	// ---
 	// [I]:.inserter
 	// [k]:.stone-furnace
@@ -246,13 +202,19 @@ func TestReader_Size(t *testing.T) {
 	tcs := []struct {
 		name         string
 		blueprint    *blueprint_schema.BlueprintSchemaJSON
-		wantW, wantH float64 // in factorio coordinates (so precision of 0.5)
+		wantW, wantH float64 // in tiles
 	}{
 		{
 			name:      "SimpleBlueprint",
 			blueprint: setupSimpleBlueprint(),
-			wantW:     2.5, // we don't know that the furnace is 2x2 otherwise we'd expect 3.5
-			wantH:     3.5,
+			wantW:     3,
+			wantH:     4, // the furnace is 2x2
+		},
+		{
+			name:      "Empty",
+			blueprint: &blueprint_schema.BlueprintSchemaJSON{Blueprint: &blueprint_schema.Blueprint{}},
+			wantW:     0,
+			wantH:     0,
 		},
 	}
 
@@ -274,15 +236,15 @@ func TestReader_box(t *testing.T) {
 		name      string
 		blueprint *blueprint_schema.BlueprintSchemaJSON
 
-		wantMinX, wantMinY, wantMaxX, wantMaxY float64 // in factorio coordinates (so precision of 0.5)
+		wantMinX, wantMinY, wantMaxX, wantMaxY float64 // corners of tiles
 	}{
 		{
 			name:      "SimpleBlueprint",
 			blueprint: setupSimpleBlueprint(),
-			wantMinX:  -1.5,
-			wantMinY:  -1,
-			wantMaxX:  1,   // max is 0, we add 1; furnace is 2x2 so if we supported other data, we'd have 2
-			wantMaxY:  2.5, // max is 1.5, we add 1
+			wantMinX:  -2, // left belt at -1.5 covers -2 to -1
+			wantMinY:  -2, // furnace at -1 covers -2 to 0
+			wantMaxX:  1,  // furnace at 0 covers -1 to 1
+			wantMaxY:  2,  // belts at 1.5 cover 1 to 2
 		},
 	}
 
@@ -296,3 +258,89 @@ func TestReader_box(t *testing.T) {
 		})
 	}
 }
+
+// TestReader_quality tests that entities of different qualities are drawn
+// with their own characters.
+func TestReader_quality(t *testing.T) {
+	m := setupSimpleBlueprint()
+	legendary := blueprint_schema.QualityLegendary
+	m.Blueprint.Entities[3].Quality = &legendary
+
+	r := NewReader(m.Blueprint, 1, 1)
+	legend, err := r.generateLegend()
+	if err != nil {
+		t.Fatalf("generateLegend() failed: %v", err)
+	}
+	if !strings.Contains(legend, "transport-belt (legendary)") {
+		t.Errorf("expected legendary transport-belt in legend, got %q", legend)
+	}
+	normal, leg := r.EntityChar(&m.Blueprint.Entities[0]), r.EntityChar(&m.Blueprint.Entities[3])
+	if normal == leg {
+		t.Errorf("expected different characters for qualities, got %c for both", normal)
+	}
+}
+
+// TestEntityToDirectionRune tests that directions are read with the numbering
+// of the version of the blueprint.
+func TestEntityToDirectionRune(t *testing.T) {
+	ptrInt := func(i int) *int { return &i }
+	tcs := []struct {
+		version   blueprint_schema.GameVersion
+		direction *int
+		want      rune
+	}{
+		{blueprint_schema.GameVersion1_1, nil, ' '},
+		{blueprint_schema.GameVersion1_1, ptrInt(0), '^'},
+		{blueprint_schema.GameVersion1_1, ptrInt(2), '>'},
+		{blueprint_schema.GameVersion1_1, ptrInt(4), 'V'},
+		{blueprint_schema.GameVersion1_1, ptrInt(6), '<'},
+		{blueprint_schema.GameVersion1_1, ptrInt(1), '/'},
+		{blueprint_schema.GameVersion2_0, ptrInt(4), '>'},
+		{blueprint_schema.GameVersion2_0, ptrInt(8), 'V'},
+		{blueprint_schema.GameVersion2_0, ptrInt(12), '<'},
+		{blueprint_schema.GameVersion2_0, ptrInt(6), '\\'},
+		{blueprint_schema.GameVersion2_0, ptrInt(1), ' '},
+	}
+	for _, tc := range tcs {
+		bp := &blueprint_schema.Blueprint{Version: tc.version}
+		entity := &blueprint_schema.Entity{Name: "straight-rail", Direction: tc.direction}
+		if got := entityToDirectionRune(bp, entity); got != tc.want {
+			t.Errorf("expected %q for direction %v in %v, got %q", tc.want, tc.direction, tc.version, got)
+		}
+	}
+	if got := entityToDirectionRune(&blueprint_schema.Blueprint{}, nil); got != ' ' {
+		t.Errorf("expected ' ' for a tile, got %q", got)
+	}
+}
+
+// TestReader_prototypes tests that entities are drawn at their size from the
+// prototypes of the reader.
+func TestReader_prototypes(t *testing.T) {
+	bp := &blueprint_schema.Blueprint{
+		Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "assembling-machine-2", Position: blueprint_schema.Position{X: 0.5, Y: 0.5}},
+			{EntityNumber: 2, Name: "modded-machine", Position: blueprint_schema.Position{X: 3, Y: 1}},
+		},
+	}
+
+	r := NewReader(bp, 1, 1)
+	if w, h := r.Size(); w != 4 || h != 3 {
+		t.Fatalf("Bad data: want = '4x3' got '%gx%g'", w, h)
+	}
+
+	modded := prototype_blueprint.Vanilla()
+	modded.Add(prototype_blueprint.Prototype{Name: "modded-machine", Width: 2, Height: 2, CollisionBox: prototype_blueprint.NewBox(-0.9, -0.9, 0.9, 0.9), Directions: 1})
+	r = NewReader(bp, 1, 1)
+	r.Prototypes = modded
+	if w, h := r.Size(); w != 5 || h != 3 {
+		t.Fatalf("Bad data: want = '5x3' got '%gx%g'", w, h)
+	}
+	for _, pos := range [][2]float64{{-1, -1}, {1, 1}, {0, 1}} {
+		if et := r.AsSparseTilemap().EntityOrTileAtPosition(pos[0], pos[1]); et == nil || et.entity.EntityNumber != 1 {
+			t.Errorf("Bad data: want = 'assembling-machine-2 at %v' got '%v'", pos, et)
+		}
+	}
+	if et := r.AsSparseTilemap().EntityOrTileAtPosition(2, 0); et == nil || et.entity.EntityNumber != 2 {
+		t.Errorf("Bad data: want = 'modded-machine at 2, 0' got '%v'", et)
+	}
+}
@@ -0,0 +1,152 @@
+// Package batch_blueprint decodes and encodes many blueprint strings at once,
+// such as a whole archive of stored strings after the schema changed, with a
+// bounded pool of workers.
+//
+// Results are returned in the order of the inputs, each with its own error,
+// so that one broken string does not stop the batch:
+//
+//	results, err := batch_blueprint.DecodeStrings(ctx, strings, batch_blueprint.Options{})
+//	if err != nil {
+//		return err // canceled
+//	}
+//	for _, r := range results {
+//		if r.Err != nil {
+//			log.Printf("string %d: %v", r.Index, r.Err)
+//		}
+//	}
+//
+// The public interface is unstable.
+package batch_blueprint // badc0de.net/pkg/factorioblueprint/batch_blueprint
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+
+	"badc0de.net/pkg/factorioblueprint/read_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/write_blueprint"
+)
+
+// Options control a batch.
+type Options struct {
+	// Workers is the number of inputs processed at once. Zero means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+
+	// Limits are used to decode each string, which counts on its own against
+	// them. The zero value means no limits.
+	Limits read_blueprint.Limits
+
+	// Lossless keeps the fields unknown to the schema, as AsStructLossless and
+	// FromStructLossless do.
+	Lossless bool
+}
+
+// Decoded is the result of decoding one string.
+type Decoded struct {
+	// Index is the index of the string in the batch.
+	Index int
+
+	Blueprint blueprint_schema.BlueprintSchemaJSON
+	Err       error
+}
+
+// Encoded is the result of encoding one blueprint.
+type Encoded struct {
+	// Index is the index of the blueprint in the batch.
+	Index int
+
+	String string
+	Err    error
+}
+
+// DecodeStrings decodes every string in strs, with the error types of
+// read_blueprint for broken ones, and returns the results in the same order.
+//
+// If ctx is done before all strings are decoded, the strings not yet started
+// get ctx.Err() as their error, and so does DecodeStrings.
+func DecodeStrings(ctx context.Context, strs []string, opts Options) ([]Decoded, error) {
+	results := make([]Decoded, len(strs))
+	err := run(ctx, len(strs), opts.Workers, func(i int, err error) {
+		results[i].Index = i
+		if err == nil {
+			results[i].Blueprint, err = decode(strs[i], opts)
+		}
+		results[i].Err = err
+	})
+	return results, err
+}
+
+func decode(s string, opts Options) (blueprint_schema.BlueprintSchemaJSON, error) {
+	decompressed, err := read_blueprint.AsJSONReaderWithLimits(strings.NewReader(s), opts.Limits)
+	if err != nil {
+		return blueprint_schema.BlueprintSchemaJSON{}, err
+	}
+	if opts.Lossless {
+		return read_blueprint.AsStructLosslessWithLimits(decompressed, opts.Limits)
+	}
+	return read_blueprint.AsStructWithLimits(decompressed, opts.Limits)
+}
+
+// EncodeStructs encodes every blueprint in ms into a string, and returns the
+// results in the same order. Cancellation works as for DecodeStrings.
+func EncodeStructs(ctx context.Context, ms []blueprint_schema.BlueprintSchemaJSON, opts Options) ([]Encoded, error) {
+	results := make([]Encoded, len(ms))
+	err := run(ctx, len(ms), opts.Workers, func(i int, err error) {
+		results[i].Index = i
+		if err == nil {
+			var buf bytes.Buffer
+			if opts.Lossless {
+				err = write_blueprint.FromStructLossless(&buf, ms[i])
+			} else {
+				err = write_blueprint.FromStruct(&buf, ms[i])
+			}
+			results[i].String = buf.String()
+		}
+		results[i].Err = err
+	})
+	return results, err
+}
+
+// run calls fn for each index from 0 to n with up to workers calls at once.
+// Once ctx is done, fn is called with ctx.Err() for the indexes not yet
+// started, and run returns it if there were any.
+func run(ctx context.Context, n, workers int, fn func(i int, err error)) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		canceled error
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				err := ctx.Err()
+				if err != nil {
+					mu.Lock()
+					canceled = err
+					mu.Unlock()
+				}
+				fn(i, err)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return canceled
+}
@@ -0,0 +1,113 @@
+package batch_blueprint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/read_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Example of decoding a few strings, one of them broken.
+func ExampleDecodeStrings() {
+	strs := []string{
+		"0eNqrVkrKKU0tKMrMK1GyqlbKTM7PK1ayio7VUcosSc1VskKS1lEqSy0qzszPU7IyqK0FAEVdE+g=",
+		"0eNqrVkrKKU0tKMrMK1GyqlbKTM7PK",
+		"1abc",
+	}
+	results, err := DecodeStrings(context.Background(), strs, Options{Workers: 2})
+	if err != nil {
+		panic(err)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Printf("%d: %v\n", r.Index, r.Err)
+			continue
+		}
+		fmt.Printf("%d: blueprint %v\n", r.Index, r.Blueprint.Blueprint != nil)
+	}
+
+	// Output:
+	// 0: blueprint true
+	// 1: string truncated after 30 chars
+	// 2: version byte: got = '1', want = '{' or '0'
+}
+
+// corpus returns the strings of the corpus of read_blueprint, each repeated n
+// times.
+func corpus(t *testing.T, n int) []string {
+	data, err := ioutil.ReadFile("../read_blueprint/simple.txt")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	var strs []string
+	for i := 0; i < n; i++ {
+		strs = append(strs, strings.TrimSpace(string(data)))
+	}
+	return strs
+}
+
+// TestEncodeStructs checks that a batch decoded and encoded again decodes to
+// the same blueprints.
+func TestEncodeStructs(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{Workers: 4, Lossless: true, Limits: read_blueprint.DefaultLimits}
+
+	decoded, err := DecodeStrings(ctx, corpus(t, 50), opts)
+	if err != nil {
+		t.Fatalf("DecodeStrings() failed: %v", err)
+	}
+	var ms []blueprint_schema.BlueprintSchemaJSON
+	for i, r := range decoded {
+		if r.Err != nil || r.Index != i {
+			t.Fatalf("Bad data: want string %d decoded got '%v' for %d", i, r.Err, r.Index)
+		}
+		ms = append(ms, r.Blueprint)
+	}
+
+	encoded, err := EncodeStructs(ctx, ms, opts)
+	if err != nil {
+		t.Fatalf("EncodeStructs() failed: %v", err)
+	}
+	var strs []string
+	for i, r := range encoded {
+		if r.Err != nil || r.Index != i {
+			t.Fatalf("Bad data: want blueprint %d encoded got '%v' for %d", i, r.Err, r.Index)
+		}
+		strs = append(strs, r.String)
+	}
+
+	again, err := DecodeStrings(ctx, strs, opts)
+	if err != nil {
+		t.Fatalf("DecodeStrings() failed: %v", err)
+	}
+	for i, r := range again {
+		if r.Err != nil || !reflect.DeepEqual(r.Blueprint, ms[i]) {
+			t.Fatalf("Bad data: blueprint %d changed after round trip: %v", i, r.Err)
+		}
+	}
+}
+
+func TestDecodeStrings_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := DecodeStrings(ctx, corpus(t, 10), Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Bad data: want = '%v' got '%v'", context.Canceled, err)
+	}
+	for i, r := range results {
+		if !errors.Is(r.Err, context.Canceled) || r.Index != i {
+			t.Fatalf("Bad data: want string %d canceled got '%v' for %d", i, r.Err, r.Index)
+		}
+	}
+
+	if results, err := DecodeStrings(ctx, nil, Options{}); err != nil || len(results) != 0 {
+		t.Fatalf("Bad data: want no results and no error got '%v' '%v'", results, err)
+	}
+}
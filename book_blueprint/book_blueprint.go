@@ -0,0 +1,132 @@
+// Package book_blueprint walks through blueprint books, including books nested
+// in other books, and visits every blueprint and planner in them.
+//
+// The public interface is unstable.
+package book_blueprint // badc0de.net/pkg/factorioblueprint/book_blueprint
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Step is one level of a Path: the index of an entry in its book, and the
+// label of that entry.
+type Step struct {
+	Index int
+	Label string
+}
+
+// Path leads from the outermost book to an entry. It has one Step for each
+// book entry on the way, the last one being the entry itself. The outermost
+// book is not an entry of any book, so it is not part of the path.
+type Path []Step
+
+// String returns the path in a form such as "0:Smelting/3:Iron", with the
+// label left out for entries without one.
+func (p Path) String() string {
+	parts := make([]string, len(p))
+	for i, s := range p {
+		if s.Label == "" {
+			parts[i] = fmt.Sprint(s.Index)
+		} else {
+			parts[i] = fmt.Sprintf("%d:%s", s.Index, s.Label)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Leaf is a blueprint or planner found while walking. Exactly one of
+// Blueprint, UpgradePlanner and DeconstructionPlanner is set, unless Kind is
+// BookEntryUnknown.
+type Leaf struct {
+	Path Path
+	Kind blueprint_schema.BookEntryKind
+
+	Blueprint             *blueprint_schema.Blueprint
+	UpgradePlanner        *blueprint_schema.UpgradePlanner
+	DeconstructionPlanner *blueprint_schema.DeconstructionPlanner
+
+	// Entry is the book entry holding the leaf, or nil if the leaf is not in
+	// a book.
+	Entry *blueprint_schema.BlueprintBookBlueprintsElem
+}
+
+// WalkFunc is called for every leaf. Returning SkipBook skips the rest of
+// the book the leaf is in; returning any other error stops the walk, and Walk
+// returns that error.
+type WalkFunc func(leaf Leaf) error
+
+// SkipBook can be returned by a WalkFunc to skip the remaining entries of the
+// book holding the current leaf.
+var SkipBook = errors.New("skip this book")
+
+// Walk calls fn for the blueprint or planner at the root of m, or for every
+// leaf of the book at the root of m, in the order they are stored. Leaves
+// which are modified by fn are modified in m.
+func Walk(m *blueprint_schema.BlueprintSchemaJSON, fn WalkFunc) error {
+	var err error
+	switch {
+	case m.BlueprintBook != nil:
+		return WalkBook(m.BlueprintBook, fn)
+	case m.Blueprint != nil:
+		err = fn(Leaf{Kind: blueprint_schema.BookEntryBlueprint, Blueprint: m.Blueprint})
+	case m.UpgradePlanner != nil:
+		err = fn(Leaf{Kind: blueprint_schema.BookEntryUpgradePlanner, UpgradePlanner: m.UpgradePlanner})
+	case m.DeconstructionPlanner != nil:
+		err = fn(Leaf{Kind: blueprint_schema.BookEntryDeconstructionPlanner, DeconstructionPlanner: m.DeconstructionPlanner})
+	}
+	if err == SkipBook {
+		return nil
+	}
+	return err
+}
+
+// WalkBook calls fn for every leaf of the book, descending into nested books.
+func WalkBook(book *blueprint_schema.BlueprintBook, fn WalkFunc) error {
+	err := walkBook(book, nil, fn)
+	if err == SkipBook {
+		return nil
+	}
+	return err
+}
+
+func walkBook(book *blueprint_schema.BlueprintBook, path Path, fn WalkFunc) error {
+	for i := range book.Blueprints {
+		entry := &book.Blueprints[i]
+		kind, err := entry.Kind()
+		if err != nil {
+			if len(path) > 0 {
+				err = fmt.Errorf("%v: %w", path, err)
+			}
+			return err
+		}
+
+		// Copy the path, so that leaves can keep theirs.
+		p := make(Path, len(path), len(path)+1)
+		copy(p, path)
+		p = append(p, Step{Index: entry.Index, Label: entry.Label()})
+
+		if kind == blueprint_schema.BookEntryBlueprintBook {
+			if err := walkBook(entry.BlueprintBook, p, fn); err != nil && err != SkipBook {
+				return err
+			}
+			continue
+		}
+
+		err = fn(Leaf{
+			Path:                  p,
+			Kind:                  kind,
+			Blueprint:             entry.Blueprint,
+			UpgradePlanner:        entry.UpgradePlanner,
+			DeconstructionPlanner: entry.DeconstructionPlanner,
+			Entry:                 entry,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package book_blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// libraryJSON is a book containing a blueprint, a book with a blueprint and
+// an upgrade planner, and a deconstruction planner.
+const libraryJSON = `{
+  "blueprint_book": {
+    "item": "blueprint-book",
+    "label": "Library",
+    "version": 281479273986304,
+    "blueprints": [
+      {"index": 0, "blueprint": {"item": "blueprint", "label": "Belts", "entities": [], "icons": [], "version": 281479273986304}},
+      {"index": 1, "blueprint_book": {
+        "item": "blueprint-book",
+        "label": "Smelting",
+        "version": 281479273986304,
+        "blueprints": [
+          {"index": 0, "blueprint": {"item": "blueprint", "entities": [], "icons": [], "version": 281479273986304}},
+          {"index": 3, "upgrade_planner": {"item": "upgrade-planner", "label": "Furnaces", "version": 281479273986304}}
+        ]
+      }},
+      {"index": 2, "deconstruction_planner": {"item": "deconstruction-planner", "label": "Trees", "version": 281479273986304}}
+    ]
+  }
+}`
+
+func setupLibrary(t testing.TB) *blueprint_schema.BlueprintSchemaJSON {
+	var m blueprint_schema.BlueprintSchemaJSON
+	if err := json.Unmarshal([]byte(libraryJSON), &m); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	return &m
+}
+
+// Example of listing everything in a book of books.
+func ExampleWalk() {
+	var m blueprint_schema.BlueprintSchemaJSON
+	if err := json.Unmarshal([]byte(libraryJSON), &m); err != nil {
+		panic(err)
+	}
+
+	err := Walk(&m, func(leaf Leaf) error {
+		fmt.Printf("%v %v\n", leaf.Path, leaf.Kind)
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// 0:Belts blueprint
+	// 1:Smelting/0 blueprint
+	// 1:Smelting/3:Furnaces upgrade_planner
+	// 2:Trees deconstruction_planner
+}
+
+func TestWalk_skipBook(t *testing.T) {
+	m := setupLibrary(t)
+
+	var got []string
+	err := Walk(m, func(leaf Leaf) error {
+		got = append(got, leaf.Path.String())
+		if len(leaf.Path) > 1 {
+			return SkipBook
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+	want := []string{"0:Belts", "1:Smelting/0", "2:Trees"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Bad data: want = '%v' got '%v'", want, got)
+	}
+}
+
+func TestWalk_modify(t *testing.T) {
+	m := setupLibrary(t)
+
+	label := "Renamed"
+	err := Walk(m, func(leaf Leaf) error {
+		if leaf.Blueprint != nil {
+			leaf.Blueprint.Label = &label
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+	if l := m.BlueprintBook.Blueprints[1].BlueprintBook.Blueprints[0].Blueprint.Label; l == nil || *l != label {
+		t.Fatalf("Bad data: want = '%v' got '%v'", label, l)
+	}
+}
+
+func TestWalk_error(t *testing.T) {
+	m := setupLibrary(t)
+
+	// An entry with two objects is malformed.
+	inner := m.BlueprintBook.Blueprints[1].BlueprintBook
+	inner.Blueprints[1].Blueprint = inner.Blueprints[0].Blueprint
+	if err := Walk(m, func(Leaf) error { return nil }); err == nil {
+		t.Fatalf("Walk() with a malformed entry: got no error")
+	}
+
+	// Errors from the callback stop the walk.
+	m = setupLibrary(t)
+	stop := fmt.Errorf("stop")
+	calls := 0
+	err := Walk(m, func(Leaf) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Fatalf("Bad data: want = 'stop' after 1 call got '%v' after %d", err, calls)
+	}
+}
@@ -4,22 +4,36 @@
 package main // badc0de.net/pkg/factorioblueprint/cmd/blueprintread
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"badc0de.net/pkg/factorioblueprint/asciiart_blueprint"
+	"badc0de.net/pkg/factorioblueprint/batch_blueprint"
+	"badc0de.net/pkg/factorioblueprint/book_blueprint"
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
 	"badc0de.net/pkg/factorioblueprint/read_blueprint"
+	"badc0de.net/pkg/factorioblueprint/scan_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/validate_blueprint"
 
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	file   = flag.String("file", "", "The file to read the blueprint from. If empty, uses stdin.")
-	format = flag.String("fmt", "json", "Format. raw_json (no processing after decompression), json (default, pretty print JSON), yaml, asciiart (experimental and halfbroken).")
+	file       = flag.String("file", "", "The file to read the blueprint from. If empty, uses stdin.")
+	format     = flag.String("fmt", "json", "Format. raw_json (no processing after decompression), json (default, pretty print JSON), yaml, version (game version of the blueprint, or of everything in the book), ndjson (read one string per line, decode them in parallel, and write one JSON object per line with the line number and either the JSON or the error), validate (check against blueprint.schema.json, print violations and exit with status 1 if there are any), asciiart (experimental and halfbroken).")
+	lossless   = flag.Bool("lossless", true, "Keep fields which are not known to the schema in json and yaml output.")
+	workers    = flag.Int("workers", 0, "Number of strings decoded at once with -fmt=ndjson. If zero, uses the number of CPUs.")
+	prototypes = flag.String("prototypes", "", "A data-raw-dump.json written by the game with --dump-data, for the sizes of modded entities with -fmt=asciiart. If empty, only vanilla and Space Age entities are known.")
+	extract    = flag.Bool("extract", false, "Find every blueprint string in arbitrary text, such as a chat log or a forum post, and print each with its byte offset in the text, separated by a tab, instead of decoding the input. Exits with status 1 if none are found.")
 )
 
 func init() {
@@ -38,6 +52,32 @@ func main() {
 	}
 	defer r.Close()
 
+	// Find blueprint strings in text instead of decoding one.
+	if *extract {
+		matches, err := scan_blueprint.Scan(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to scan input: %v\n", err)
+			os.Exit(1)
+		}
+		for _, m := range matches {
+			fmt.Printf("%d\t%s\n", m.Offset, m.String)
+		}
+		if len(matches) == 0 {
+			fmt.Fprintf(os.Stderr, "No blueprint strings found\n")
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Decode a file of strings, one per line.
+	if *format == "ndjson" {
+		if err := printNDJSON(r); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to decode lines: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	decompressed, err := read_blueprint.AsJSONReader(r)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to decompress JSON: %v\n", err)
@@ -55,25 +95,56 @@ func main() {
 		return
 	}
 
+	// Check the JSON against the schema, before decoding can fail on it.
+	if *format == "validate" {
+		rawJSON, err := ioutil.ReadAll(decompressed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read raw JSON: %v\n", err)
+			os.Exit(1)
+		}
+		violations, err := validate_blueprint.Validate(rawJSON)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to validate JSON: %v\n", err)
+			os.Exit(1)
+		}
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+		if len(violations) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Decode JSON.
 	//var m = make(map[string]interface{})
-	m, err := read_blueprint.AsStruct(decompressed)
+	var m blueprint_schema.BlueprintSchemaJSON
+	if *lossless {
+		m, err = read_blueprint.AsStructLossless(decompressed)
+	} else {
+		m, err = read_blueprint.AsStruct(decompressed)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to decode JSON: %v\n", err)
 		os.Exit(1)
 	}
 
 	switch *format {
-	case "raw_json":
+	case "raw_json", "validate", "ndjson":
 		panic("unreachable")
 	case "json":
 		// Print out marshalled prettified JSON.
-		if b, err := json.MarshalIndent(m, "", "  "); err != nil {
+		b, err := blueprint_schema.MarshalLossless(m)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to marshal JSON: %v\n", err)
 			os.Exit(1)
-		} else {
-			fmt.Printf("%s\n", b)
 		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, b, "", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to indent JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s\n", out.Bytes())
 	case "yaml":
 		// Print out YAML.
 		if b, err := yaml.Marshal(m); err != nil {
@@ -82,9 +153,27 @@ func main() {
 		} else {
 			fmt.Printf("%s\n", b)
 		}
+	case "version":
+		// Print out the game versions in human readable form.
+		if err := printVersions(&m); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to walk book: %v\n", err)
+			os.Exit(1)
+		}
 	case "asciiart":
+		if m.Blueprint == nil {
+			fmt.Fprintf(os.Stderr, "Failed to generate ASCII art: not a blueprint\n")
+			os.Exit(1)
+		}
 		// Print out ASCII art of the tilemap. Just use 1x1 for now.
 		r := asciiart_blueprint.NewReader(m.Blueprint, 1, 1)
+		if *prototypes != "" {
+			registry, err := loadPrototypes(*prototypes)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to load prototypes: %v\n", err)
+				os.Exit(1)
+			}
+			r.Prototypes = registry
+		}
 		// Copy to stdout.
 		if _, err := io.Copy(os.Stdout, r); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to generate ASCII art: %v\n", err)
@@ -97,3 +186,127 @@ func main() {
 	}
 
 }
+
+// ndjsonLine is a line of -fmt=ndjson output.
+type ndjsonLine struct {
+	Line  int             `json:"line"`
+	JSON  json.RawMessage `json:"json,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// printNDJSON decodes the string on each line of r, skipping empty lines, and
+// prints the results as NDJSON. It returns an error if any line failed.
+func printNDJSON(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var strs []string
+	var lines []int
+	for i, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			strs = append(strs, line)
+			lines = append(lines, i+1)
+		}
+	}
+
+	opts := batch_blueprint.Options{
+		Workers:  *workers,
+		Limits:   read_blueprint.DefaultLimits,
+		Lossless: *lossless,
+	}
+	results, err := batch_blueprint.DecodeStrings(context.Background(), strs, opts)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	for _, result := range results {
+		out := ndjsonLine{Line: lines[result.Index]}
+		err := result.Err
+		if err == nil {
+			out.JSON, err = blueprint_schema.MarshalLossless(result.Blueprint)
+		}
+		if err != nil {
+			out.Error = err.Error()
+			failed++
+		}
+		if err := e.Encode(out); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d lines failed", failed, len(results))
+	}
+	return nil
+}
+
+// loadPrototypes returns the vanilla prototypes along with those of the
+// data-raw-dump.json at path.
+// printVersions prints the game version of the blueprint or planner at the
+// root of m, or of the book at the root of m and of everything in it, nested
+// books included. Nested books are printed before their first entry.
+func printVersions(m *blueprint_schema.BlueprintSchemaJSON) error {
+	if m.BlueprintBook != nil {
+		fmt.Printf("book %v\n", m.BlueprintBook.Version)
+	}
+	printed := make(map[string]bool)
+	return book_blueprint.Walk(m, func(leaf book_blueprint.Leaf) error {
+		book := m.BlueprintBook
+		for i := 0; i < len(leaf.Path)-1; i++ {
+			book = nestedBook(book, leaf.Path[i].Index)
+			if book == nil {
+				return fmt.Errorf("%v: no book with index %d", leaf.Path, leaf.Path[i].Index)
+			}
+			if p := leaf.Path[:i+1].String(); !printed[p] {
+				printed[p] = true
+				fmt.Printf("%v book %v\n", p, book.Version)
+			}
+		}
+
+		var version blueprint_schema.GameVersion
+		switch {
+		case leaf.Blueprint != nil:
+			version = leaf.Blueprint.Version
+		case leaf.UpgradePlanner != nil:
+			version = leaf.UpgradePlanner.Version
+		case leaf.DeconstructionPlanner != nil:
+			version = leaf.DeconstructionPlanner.Version
+		default:
+			return nil
+		}
+		if len(leaf.Path) > 0 {
+			fmt.Printf("%v ", leaf.Path)
+		}
+		fmt.Printf("%v %v\n", leaf.Kind, version)
+		return nil
+	})
+}
+
+// nestedBook returns the book in the entry of book with the given index, or
+// nil if there is none.
+func nestedBook(book *blueprint_schema.BlueprintBook, index int) *blueprint_schema.BlueprintBook {
+	for i := range book.Blueprints {
+		if e := &book.Blueprints[i]; e.Index == index && e.BlueprintBook != nil {
+			return e.BlueprintBook
+		}
+	}
+	return nil
+}
+
+func loadPrototypes(path string) (*prototype_blueprint.Registry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	registry := prototype_blueprint.Vanilla()
+	if err := registry.LoadDataRawDump(f); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
@@ -0,0 +1,184 @@
+// blueprintwrite reads a blueprint in JSON or YAML format from a file, such as
+// the output of blueprintread, tries to read it into a schema, and prints it out
+// as a b64-encoded zlib-compressed blueprint string which can be pasted into
+// the game.
+package main // badc0de.net/pkg/factorioblueprint/cmd/blueprintwrite
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"badc0de.net/pkg/factorioblueprint/compose_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/wire_blueprint"
+	"badc0de.net/pkg/factorioblueprint/write_blueprint"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	file      = flag.String("file", "", "The file to read the blueprint from. If empty, uses stdin.")
+	format    = flag.String("fmt", "auto", "Input format. auto (default, json if the input starts with '{', otherwise yaml), json (raw or pretty printed JSON), yaml.")
+	canonical = flag.Bool("canonical", false, "Write the canonical string, with sorted and renumbered entities, which is the same for the same design.")
+	shortest  = flag.Bool("shortest", false, "Write the shortest string, without fields equal to the game defaults, and print the size saved to stderr.")
+	repeat    = flag.String("repeat", "", "Repeat the blueprint in columns and rows, such as 4x2, and print overlapping entities to stderr.")
+	pitch     = flag.String("pitch", "", "Distance from one repeated copy to the next in tiles, such as 3,6. If empty or 0, the width or height of the blueprint.")
+	stitch    = flag.String("stitch", "", "Wires to connect repeated electric poles to their copies with, comma separated: copper, red, green.")
+)
+
+func init() {
+	flag.Parse()
+}
+
+func main() {
+	r := os.Stdin
+	if *file != "" {
+		var err error
+		r, err = os.Open(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read input: %v\n", err)
+		os.Exit(1)
+	}
+
+	inputFormat := *format
+	if inputFormat == "auto" {
+		// JSON is technically also YAML, but the JSON decoder gives better
+		// error messages for JSON input.
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			inputFormat = "json"
+		} else {
+			inputFormat = "yaml"
+		}
+	}
+	if inputFormat != "json" && inputFormat != "yaml" {
+		fmt.Fprintf(os.Stderr, "Unknown format: %v\n", *format)
+		os.Exit(1)
+	}
+
+	if *canonical && *shortest {
+		fmt.Fprintf(os.Stderr, "Only one of -canonical and -shortest can be used\n")
+		os.Exit(1)
+	}
+
+	var out bytes.Buffer
+	if !*canonical && !*shortest && *repeat == "" {
+		// Nothing to change, so the input is encoded as it is.
+		if inputFormat == "json" {
+			err = write_blueprint.FromJSON(&out, bytes.NewReader(data))
+		} else {
+			err = write_blueprint.FromYAML(&out, bytes.NewReader(data))
+		}
+	} else {
+		var m blueprint_schema.BlueprintSchemaJSON
+		if inputFormat == "json" {
+			err = blueprint_schema.UnmarshalLossless(data, &m)
+		} else {
+			err = yaml.Unmarshal(data, &m)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to decode blueprint: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *repeat != "" {
+			if err := repeatBlueprint(&m); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to repeat blueprint: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		switch {
+		case *canonical:
+			err = write_blueprint.FromStructCanonical(&out, m)
+		case *shortest:
+			var report write_blueprint.SizeReport
+			if report, err = write_blueprint.FromStructShortest(&out, m); err == nil {
+				fmt.Fprintf(os.Stderr, "Saved %d of %d chars\n", report.Saved(), report.DefaultLength)
+			}
+		default:
+			err = write_blueprint.FromStructLossless(&out, m)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode blueprint: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s\n", out.Bytes())
+}
+
+// repeatBlueprint replaces the blueprint in m with copies of it, as set by the
+// -repeat, -pitch and -stitch flags.
+func repeatBlueprint(m *blueprint_schema.BlueprintSchemaJSON) error {
+	if m.Blueprint == nil {
+		return fmt.Errorf("only a single blueprint can be repeated")
+	}
+
+	var opts compose_blueprint.RepeatOptions
+	var err error
+	if opts.Columns, opts.Rows, err = parsePair(*repeat, "x"); err != nil {
+		return fmt.Errorf("bad -repeat: %w", err)
+	}
+	if opts.Columns < 1 || opts.Rows < 1 {
+		return fmt.Errorf("bad -repeat: want at least one column and one row, got %q", *repeat)
+	}
+	if *pitch != "" {
+		if opts.Pitch.X, opts.Pitch.Y, err = parsePair(*pitch, ","); err != nil {
+			return fmt.Errorf("bad -pitch: %w", err)
+		}
+	}
+	if *stitch != "" {
+		for _, name := range strings.Split(*stitch, ",") {
+			switch strings.TrimSpace(name) {
+			case "copper":
+				opts.Stitch.Connectors = append(opts.Stitch.Connectors, wire_blueprint.ConnectorPoleCopper)
+			case "red":
+				opts.Stitch.Connectors = append(opts.Stitch.Connectors, wire_blueprint.ConnectorCircuitRed)
+			case "green":
+				opts.Stitch.Connectors = append(opts.Stitch.Connectors, wire_blueprint.ConnectorCircuitGreen)
+			default:
+				return fmt.Errorf("bad -stitch: unknown wire %q", name)
+			}
+		}
+	}
+
+	bp, overlaps, err := compose_blueprint.Repeat(m.Blueprint, opts)
+	if err != nil {
+		return err
+	}
+	for _, o := range overlaps {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", o)
+	}
+	m.Blueprint = bp
+	return nil
+}
+
+// parsePair parses two integers separated by sep, such as "4x2".
+func parsePair(s, sep string) (int, int, error) {
+	parts := strings.Split(s, sep)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("want two numbers separated by %q, got %q", sep, s)
+	}
+	a, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
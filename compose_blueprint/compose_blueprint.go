@@ -0,0 +1,252 @@
+// Package compose_blueprint puts larger blueprints together from smaller
+// ones, each placed at an offset and turned as needed.
+//
+// For example, a column of smelters, a row of beacons and a strip of power
+// poles:
+//
+//	bp, overlaps, err := compose_blueprint.Merge([]compose_blueprint.Part{
+//		{Blueprint: smelters},
+//		{Blueprint: beacons, Offset: blueprint_schema.TilePosition{X: 3}},
+//		{Blueprint: poles, Offset: blueprint_schema.TilePosition{X: 6}, Transform: transform_blueprint.Transform{QuarterTurns: 1}},
+//	}, compose_blueprint.Options{})
+//
+// Entities are numbered anew, and wires, neighbours, connections and the
+// locomotives of schedules follow. The result can be written out with
+// write_blueprint.FromBlueprint.
+//
+// Repeat puts copies of one blueprint in columns and rows, such as a smelter
+// column, a solar field or a lamp display, and can wire the copies together.
+//
+// The public interface is unstable.
+package compose_blueprint // badc0de.net/pkg/factorioblueprint/compose_blueprint
+
+import (
+	"fmt"
+	"sort"
+
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/transform_blueprint"
+)
+
+// Part is a blueprint placed into the merged one.
+type Part struct {
+	Blueprint *blueprint_schema.Blueprint
+
+	// Transform turns the part around its origin before it is moved.
+	Transform transform_blueprint.Transform
+
+	// Offset moves the part, in whole tiles so that it stays on the tile
+	// grid. Parts with rails should be moved by an even number of tiles to
+	// stay on the rail grid.
+	Offset blueprint_schema.TilePosition
+}
+
+// TileConflict says what Merge does with two different tiles on the same
+// position.
+type TileConflict int
+
+const (
+	// TileConflictKeepFirst keeps the tile of the part which comes first.
+	TileConflictKeepFirst TileConflict = iota
+	// TileConflictKeepLast keeps the tile of the part which comes last, as
+	// if the parts were built one after the other.
+	TileConflictKeepLast
+	// TileConflictError makes Merge fail.
+	TileConflictError
+)
+
+// Options control a merge.
+type Options struct {
+	// Tiles says what to do with different tiles on the same position.
+	// The same tile on the same position is merged into one in any case.
+	Tiles TileConflict
+
+	// Prototypes gives the sizes and collision boxes of entities. If nil,
+	// prototype_blueprint.Vanilla is used.
+	Prototypes *prototype_blueprint.Registry
+}
+
+// Overlap is a pair of entities of different parts whose collision boxes
+// overlap in the merged blueprint, so that the game would not build both.
+type Overlap struct {
+	// A and B are the entity numbers in the merged blueprint, A before B.
+	A, B int
+}
+
+func (o Overlap) String() string {
+	return fmt.Sprintf("entity %d overlaps entity %d", o.A, o.B)
+}
+
+// Merge returns a blueprint with the entities, tiles, wires and schedules of
+// all parts, in order, along with the entities of different parts which
+// overlap. Parts are not changed.
+//
+// All parts must number directions the same way, either as Factorio 2.0 or
+// as the versions before; migrate_blueprint can bring them to one version.
+// The merged blueprint has the newest version of the parts, and the icons of
+// the first part which has some.
+func Merge(parts []Part, opts Options) (*blueprint_schema.Blueprint, []Overlap, error) {
+	prototypes := opts.Prototypes
+	if prototypes == nil {
+		prototypes = prototype_blueprint.Vanilla()
+	}
+
+	out := &blueprint_schema.Blueprint{Item: "blueprint"}
+	tiles := newTileMerger(opts.Tiles)
+	var partOf []int // part index of each merged entity
+	for i, part := range parts {
+		if part.Blueprint == nil {
+			return nil, nil, fmt.Errorf("part %d: no blueprint", i)
+		}
+		if i > 0 && part.Blueprint.Version.AtLeast(blueprint_schema.GameVersion2_0) != out.Version.AtLeast(blueprint_schema.GameVersion2_0) {
+			return nil, nil, fmt.Errorf("part %d: version %v numbers directions unlike version %v of the parts before", i, part.Blueprint.Version, out.Version)
+		}
+		if part.Blueprint.Version > out.Version {
+			out.Version = part.Blueprint.Version
+		}
+
+		bp, err := place(part, prototypes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("part %d: %w", i, err)
+		}
+		if err := Renumber(bp, len(out.Entities)+1); err != nil {
+			return nil, nil, fmt.Errorf("part %d: %w", i, err)
+		}
+
+		if out.Icons == nil && len(bp.Icons) > 0 {
+			out.Icons = bp.Icons
+		}
+		out.Entities = append(out.Entities, bp.Entities...)
+		out.Wires = append(out.Wires, bp.Wires...)
+		out.Schedules = append(out.Schedules, bp.Schedules...)
+		for _, t := range bp.Tiles {
+			if err := tiles.add(t, i); err != nil {
+				return nil, nil, err
+			}
+		}
+		for range bp.Entities {
+			partOf = append(partOf, i)
+		}
+	}
+	out.Tiles = tiles.tiles
+	if out.Icons == nil {
+		out.Icons = []blueprint_schema.Icon{}
+	}
+
+	return out, Overlaps(out, prototypes, func(a, b int) bool { return partOf[a] != partOf[b] }), nil
+}
+
+// place returns a copy of the blueprint of part, transformed and moved.
+func place(part Part, prototypes *prototype_blueprint.Registry) (*blueprint_schema.Blueprint, error) {
+	bp, err := part.Blueprint.Clone()
+	if err != nil {
+		return nil, err
+	}
+	// The grid of a part means nothing in the merged blueprint, and would
+	// keep the part in its grid cell when transformed.
+	bp.SnapToGrid = nil
+	bp.PositionRelativeToGrid = nil
+	if err := transform_blueprint.Apply(bp, part.Transform, prototypes); err != nil {
+		return nil, err
+	}
+	transform_blueprint.Translate(bp, part.Offset.Fixed())
+	return bp, nil
+}
+
+// Renumber numbers the entities of bp in order, starting with first, and
+// changes everything referring to them to the new numbers.
+func Renumber(bp *blueprint_schema.Blueprint, first int) error {
+	numbers := make(map[int]int, len(bp.Entities))
+	for i, e := range bp.Entities {
+		if _, ok := numbers[e.EntityNumber]; ok {
+			return fmt.Errorf("entity %d: number used twice", e.EntityNumber)
+		}
+		numbers[e.EntityNumber] = first + i
+	}
+	return bp.RenumberEntities(numbers)
+}
+
+// tileMerger merges tiles of parts, keeping one tile per position.
+type tileMerger struct {
+	conflict TileConflict
+	tiles    []blueprint_schema.Tile
+	at       map[blueprint_schema.TilePosition]int // index into tiles
+	partOf   []int
+}
+
+func newTileMerger(conflict TileConflict) *tileMerger {
+	return &tileMerger{conflict: conflict, at: make(map[blueprint_schema.TilePosition]int)}
+}
+
+func (m *tileMerger) add(t blueprint_schema.Tile, part int) error {
+	pos := t.Position.Fixed().Tile()
+	i, ok := m.at[pos]
+	if !ok {
+		m.at[pos] = len(m.tiles)
+		m.tiles = append(m.tiles, t)
+		m.partOf = append(m.partOf, part)
+		return nil
+	}
+	if m.tiles[i].Name == t.Name {
+		return nil
+	}
+	switch m.conflict {
+	case TileConflictKeepLast:
+		m.tiles[i] = t
+		m.partOf[i] = part
+	case TileConflictError:
+		return fmt.Errorf("tile at %v: %q of part %d conflicts with %q of part %d", pos.Fixed(), t.Name, part, m.tiles[i].Name, m.partOf[i])
+	}
+	return nil
+}
+
+// Overlaps returns the pairs of entities of bp whose collision boxes overlap,
+// for which check returns true, ordered by entity numbers. check is called
+// with the indexes of the entities in bp.Entities, and may be nil to report
+// all pairs.
+func Overlaps(bp *blueprint_schema.Blueprint, prototypes *prototype_blueprint.Registry, check func(a, b int) bool) []Overlap {
+	if prototypes == nil {
+		prototypes = prototype_blueprint.Vanilla()
+	}
+
+	// Only compare entities on the same tiles.
+	boxes := make([]prototype_blueprint.Box, len(bp.Entities))
+	byTile := make(map[blueprint_schema.TilePosition][]int)
+	for i := range bp.Entities {
+		boxes[i] = prototypes.EntityBox(bp, &bp.Entities[i])
+		first := boxes[i].LeftTop.Tile()
+		last := boxes[i].RightBottom.Sub(blueprint_schema.FixedPosition{X: 1, Y: 1}).Tile()
+		for y := first.Y; y <= last.Y; y++ {
+			for x := first.X; x <= last.X; x++ {
+				t := blueprint_schema.TilePosition{X: x, Y: y}
+				byTile[t] = append(byTile[t], i)
+			}
+		}
+	}
+
+	seen := make(map[[2]int]bool)
+	var overlaps []Overlap
+	for _, entities := range byTile {
+		for j, a := range entities {
+			for _, b := range entities[j+1:] {
+				if seen[[2]int{a, b}] || (check != nil && !check(a, b)) || !boxes[a].Overlaps(boxes[b]) {
+					continue
+				}
+				seen[[2]int{a, b}] = true
+				o := Overlap{A: bp.Entities[a].EntityNumber, B: bp.Entities[b].EntityNumber}
+				if o.B < o.A {
+					o.A, o.B = o.B, o.A
+				}
+				overlaps = append(overlaps, o)
+			}
+		}
+	}
+	sort.Slice(overlaps, func(i, j int) bool {
+		if overlaps[i].A != overlaps[j].A {
+			return overlaps[i].A < overlaps[j].A
+		}
+		return overlaps[i].B < overlaps[j].B
+	})
+	return overlaps
+}
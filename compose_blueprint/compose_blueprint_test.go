@@ -0,0 +1,292 @@
+package compose_blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/read_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/transform_blueprint"
+	"badc0de.net/pkg/factorioblueprint/wire_blueprint"
+	"badc0de.net/pkg/factorioblueprint/write_blueprint"
+)
+
+// setupComposeBlueprint creates a Factorio 2.0 blueprint of two power poles
+// wired together, a locomotive with a schedule, and a tile of concrete.
+func setupComposeBlueprint() *blueprint_schema.Blueprint {
+	var bp blueprint_schema.Blueprint
+	data := `{"item": "blueprint", "version": 562949954076673, "icons": [{"index": 1, "signal": {"name": "small-electric-pole"}}], "entities": [
+		{"entity_number": 7, "name": "small-electric-pole", "position": {"x": 0.5, "y": 0.5}},
+		{"entity_number": 3, "name": "small-electric-pole", "position": {"x": 4.5, "y": 0.5}},
+		{"entity_number": 5, "name": "locomotive", "position": {"x": 2, "y": 5}}
+	], "tiles": [{"name": "concrete", "position": {"x": 0, "y": 0}}],
+	"wires": [[7, 5, 3, 5], [7, 1, 3, 1]], "schedules": [{"locomotives": [5]}]}`
+	if err := json.Unmarshal([]byte(data), &bp); err != nil {
+		panic(err)
+	}
+	return &bp
+}
+
+// Example of putting two copies of a blueprint side by side, the second
+// turned around.
+func ExampleMerge() {
+	bp := setupComposeBlueprint()
+	merged, overlaps, err := Merge([]Part{
+		{Blueprint: bp},
+		{Blueprint: bp, Offset: blueprint_schema.TilePosition{X: 12}, Transform: transform_blueprint.Transform{QuarterTurns: 2}},
+	}, Options{})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, e := range merged.Entities {
+		fmt.Println(e.EntityNumber, e.Name, e.Position.X, e.Position.Y)
+	}
+	fmt.Println(merged.Wires)
+	fmt.Println(merged.Schedules[0].Locomotives, merged.Schedules[1].Locomotives)
+	fmt.Println(len(merged.Tiles), overlaps)
+
+	// Output:
+	// 1 small-electric-pole 0.5 0.5
+	// 2 small-electric-pole 4.5 0.5
+	// 3 locomotive 2 5
+	// 4 small-electric-pole 11.5 -0.5
+	// 5 small-electric-pole 7.5 -0.5
+	// 6 locomotive 10 -5
+	// [[1 5 2 5] [1 1 2 1] [4 5 5 5] [4 1 5 1]]
+	// [3] [6]
+	// 2 []
+}
+
+// TestMerge_tiles tests the policies for different tiles on the same
+// position.
+func TestMerge_tiles(t *testing.T) {
+	concrete := setupComposeBlueprint()
+	stone := setupComposeBlueprint()
+	stone.Tiles[0].Name = "stone-path"
+	parts := []Part{
+		{Blueprint: concrete, Offset: blueprint_schema.TilePosition{Y: 10}},
+		{Blueprint: stone, Offset: blueprint_schema.TilePosition{X: 10, Y: 10}},
+		{Blueprint: concrete, Offset: blueprint_schema.TilePosition{X: 10, Y: 10}},
+		{Blueprint: concrete, Offset: blueprint_schema.TilePosition{Y: 10}},
+	}
+
+	tcs := []struct {
+		conflict TileConflict
+		want     string
+	}{
+		{TileConflictKeepFirst, "[concrete stone-path]"},
+		{TileConflictKeepLast, "[concrete concrete]"},
+	}
+	for _, tc := range tcs {
+		bp, _, err := Merge(parts, Options{Tiles: tc.conflict})
+		if err != nil {
+			t.Fatalf("Merge() failed: %v", err)
+		}
+		var names []string
+		for _, tile := range bp.Tiles {
+			names = append(names, tile.Name)
+		}
+		if got := fmt.Sprint(names); got != tc.want {
+			t.Errorf("Bad data for %d: want = '%s' got '%s'", tc.conflict, tc.want, got)
+		}
+	}
+
+	if _, _, err := Merge(parts, Options{Tiles: TileConflictError}); err == nil {
+		t.Errorf("Bad data: want = 'error for conflicting tiles' got 'nil'")
+	}
+	// The same tile twice is no conflict.
+	if _, _, err := Merge(parts[2:], Options{Tiles: TileConflictError}); err != nil {
+		t.Errorf("Bad data: want = 'nil' got '%v'", err)
+	}
+}
+
+// TestMerge_overlaps tests that overlapping entities of different parts are
+// reported.
+func TestMerge_overlaps(t *testing.T) {
+	bp := setupComposeBlueprint()
+	_, overlaps, err := Merge([]Part{
+		{Blueprint: bp},
+		{Blueprint: bp, Offset: blueprint_schema.TilePosition{X: 4}},
+	}, Options{})
+	if err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+	// The second pole of the first part is where the first pole of the second
+	// part is; the locomotives are side by side.
+	if got, want := fmt.Sprint(overlaps), "[entity 2 overlaps entity 4]"; got != want {
+		t.Errorf("Bad data: want = '%s' got '%s'", want, got)
+	}
+}
+
+// TestMerge_errors tests parts which cannot be merged, and that parts are
+// not changed.
+func TestMerge_errors(t *testing.T) {
+	bp := setupComposeBlueprint()
+	old := setupComposeBlueprint()
+	old.Version = blueprint_schema.GameVersion1_1
+	if _, _, err := Merge([]Part{{Blueprint: bp}, {Blueprint: old}}, Options{}); err == nil {
+		t.Errorf("Bad data: want = 'error for mixed versions' got 'nil'")
+	}
+	if _, _, err := Merge([]Part{{Blueprint: bp}, {}}, Options{}); err == nil {
+		t.Errorf("Bad data: want = 'error for missing blueprint' got 'nil'")
+	}
+
+	got, _ := json.Marshal(bp)
+	want, _ := json.Marshal(setupComposeBlueprint())
+	if string(got) != string(want) {
+		t.Errorf("Bad data: want = '%s' got '%s'", want, got)
+	}
+}
+
+// TestMerge_write tests that a merged blueprint can be written out and read
+// back.
+func TestMerge_write(t *testing.T) {
+	bp := setupComposeBlueprint()
+	merged, _, err := Merge([]Part{{Blueprint: bp}, {Blueprint: bp, Offset: blueprint_schema.TilePosition{Y: 8}}}, Options{})
+	if err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := write_blueprint.FromBlueprint(&buf, merged); err != nil {
+		t.Fatalf("FromBlueprint() failed: %v", err)
+	}
+	r, err := read_blueprint.AsJSONReader(&buf)
+	if err != nil {
+		t.Fatalf("AsJSONReader() failed: %v", err)
+	}
+	m, err := read_blueprint.AsStructLossless(r)
+	if err != nil {
+		t.Fatalf("AsStructLossless() failed: %v", err)
+	}
+	if m.Blueprint == nil || len(m.Blueprint.Entities) != 6 || len(m.Blueprint.Wires) != 4 {
+		t.Errorf("Bad data: want = '6 entities, 4 wires' got '%+v'", m.Blueprint)
+	}
+}
+
+// Example of a row of power poles, chained with copper wire.
+func ExampleRepeat() {
+	bp := &blueprint_schema.Blueprint{
+		Version: blueprint_schema.GameVersion2_0,
+		Icons:   []blueprint_schema.Icon{},
+		Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "small-electric-pole", Position: blueprint_schema.Position{X: 0.5, Y: 0.5}},
+			{EntityNumber: 2, Name: "small-lamp", Position: blueprint_schema.Position{X: 1.5, Y: 0.5}},
+		},
+	}
+	row, _, err := Repeat(bp, RepeatOptions{
+		Columns: 3,
+		Pitch:   blueprint_schema.TilePosition{X: 7},
+		Stitch:  Stitch{Connectors: []wire_blueprint.Connector{wire_blueprint.ConnectorPoleCopper}},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, e := range row.Entities {
+		fmt.Println(e.EntityNumber, e.Name, e.Position.X, e.Position.Y)
+	}
+	fmt.Println(row.Wires)
+
+	// Output:
+	// 1 small-electric-pole 0.5 0.5
+	// 2 small-lamp 1.5 0.5
+	// 3 small-electric-pole 7.5 0.5
+	// 4 small-lamp 8.5 0.5
+	// 5 small-electric-pole 14.5 0.5
+	// 6 small-lamp 15.5 0.5
+	// [[1 5 3 5] [3 5 5 5]]
+}
+
+// TestRepeat tests repeating in both directions with the pitch of the size of
+// the blueprint, and wiring in Factorio 1.1.
+func TestRepeat(t *testing.T) {
+	bp := &blueprint_schema.Blueprint{
+		Version: blueprint_schema.GameVersion1_1,
+		Icons:   []blueprint_schema.Icon{},
+		Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "small-lamp", Position: blueprint_schema.Position{X: 0.5, Y: 0.5}},
+			{EntityNumber: 2, Name: "stone-furnace", Position: blueprint_schema.Position{X: 2, Y: 1}},
+		},
+	}
+	grid, overlaps, err := Repeat(bp, RepeatOptions{
+		Columns: 2,
+		Rows:    2,
+		Stitch: Stitch{
+			Connectors: []wire_blueprint.Connector{wire_blueprint.ConnectorCircuitRed},
+			Entity:     func(e *blueprint_schema.Entity) bool { return e.Name == "small-lamp" },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Repeat() failed: %v", err)
+	}
+	if len(overlaps) != 0 {
+		t.Errorf("Bad data: want = 'no overlaps' got '%v'", overlaps)
+	}
+
+	var got []string
+	for _, e := range grid.Entities {
+		got = append(got, fmt.Sprintf("%d %v,%v", e.EntityNumber, e.Position.X, e.Position.Y))
+	}
+	if want := "[1 0.5,0.5 2 2,1 3 3.5,0.5 4 5,1 5 0.5,2.5 6 2,3 7 3.5,2.5 8 5,3]"; fmt.Sprint(got) != want {
+		t.Errorf("Bad data: want = '%s' got '%s'", want, got)
+	}
+
+	wires, err := wire_blueprint.FromBlueprint(grid)
+	if err != nil {
+		t.Fatalf("FromBlueprint() failed: %v", err)
+	}
+	if grid.Wires != nil || len(wires) != 4 {
+		t.Errorf("Bad data: want = '4 wires in connections' got '%v' and '%v'", grid.Wires, wires)
+	}
+
+	if _, _, err := Repeat(bp, RepeatOptions{Columns: -1}); err == nil {
+		t.Errorf("Bad data: want = 'error for -1 columns' got 'nil'")
+	}
+}
+
+// TestRepeat_powerSwitches tests that stitching a Factorio 1.1 blueprint keeps
+// the sides of the copper wires of power switches.
+func TestRepeat_powerSwitches(t *testing.T) {
+	var bp blueprint_schema.Blueprint
+	data := `{"item": "blueprint", "version": ` + fmt.Sprint(uint64(blueprint_schema.GameVersion1_1)) + `, "icons": [], "entities": [
+		{"entity_number": 1, "name": "small-electric-pole", "position": {"x": 0.5, "y": 0.5}},
+		{"entity_number": 2, "name": "power-switch", "position": {"x": 3, "y": 1}, "connections": {
+			"Cu0": [{"entity_id": 1, "wire_id": 0}],
+			"Cu1": [{"entity_id": 3, "wire_id": 0}]}},
+		{"entity_number": 3, "name": "power-switch", "position": {"x": 7, "y": 1}, "connections": {
+			"Cu0": [{"entity_id": 2, "wire_id": 1}]}}
+	]}`
+	if err := json.Unmarshal([]byte(data), &bp); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	row, _, err := Repeat(&bp, RepeatOptions{
+		Columns: 2,
+		Pitch:   blueprint_schema.TilePosition{X: 10},
+		Stitch:  Stitch{Connectors: []wire_blueprint.Connector{wire_blueprint.ConnectorPoleCopper}},
+	})
+	if err != nil {
+		t.Fatalf("Repeat() failed: %v", err)
+	}
+
+	var got []string
+	for _, e := range row.Entities {
+		c, _ := json.Marshal(e.Connections)
+		got = append(got, fmt.Sprintf("%d %v %s", e.EntityNumber, e.Neighbours, c))
+	}
+	want := []string{
+		`1 [4] null`,
+		`2 [] {"Cu0":[{"entity_id":1,"wire_id":0}],"Cu1":[{"entity_id":3,"wire_id":0}]}`,
+		`3 [] {"Cu0":[{"entity_id":2,"wire_id":1}]}`,
+		`4 [1] null`,
+		`5 [] {"Cu0":[{"entity_id":4,"wire_id":0}],"Cu1":[{"entity_id":6,"wire_id":0}]}`,
+		`6 [] {"Cu0":[{"entity_id":5,"wire_id":1}]}`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Bad data: want = '%v' got '%v'", want, got)
+	}
+}
@@ -0,0 +1,127 @@
+package compose_blueprint
+
+import (
+	"fmt"
+
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/wire_blueprint"
+)
+
+// RepeatOptions control how a blueprint is repeated.
+type RepeatOptions struct {
+	Options
+
+	// Columns and Rows are the number of copies along X and along Y. Zero
+	// means one.
+	Columns, Rows int
+
+	// Pitch is the distance from one copy to the next, in tiles. A zero X or
+	// Y is the width or height of the blueprint, so that copies touch.
+	Pitch blueprint_schema.TilePosition
+
+	// Stitch wires copies to their neighbours.
+	Stitch Stitch
+}
+
+// Stitch wires each picked entity to its copies in the next column and in
+// the next row, such as to chain power poles or lamps. Whether the wires
+// reach is not checked.
+type Stitch struct {
+	// Connectors are the connectors wired together on both copies, such as
+	// wire_blueprint.ConnectorPoleCopper. If empty, nothing is wired.
+	Connectors []wire_blueprint.Connector
+
+	// Entity picks the entities to wire, out of the blueprint repeated. If
+	// nil, electric poles are picked.
+	Entity func(e *blueprint_schema.Entity) bool
+}
+
+// Repeat returns a blueprint with copies of bp in columns and rows, along
+// with the entities of different copies which overlap, as Merge does. The
+// entities of each copy are numbered after those of the copy before, going
+// along each row first. The label and description of bp are kept.
+func Repeat(bp *blueprint_schema.Blueprint, opts RepeatOptions) (*blueprint_schema.Blueprint, []Overlap, error) {
+	columns, rows := opts.Columns, opts.Rows
+	if columns == 0 {
+		columns = 1
+	}
+	if rows == 0 {
+		rows = 1
+	}
+	if columns < 0 || rows < 0 {
+		return nil, nil, fmt.Errorf("cannot repeat %dx%d times", columns, rows)
+	}
+
+	if opts.Prototypes == nil {
+		opts.Prototypes = prototype_blueprint.Vanilla()
+	}
+
+	pitch := opts.Pitch
+	if pitch.X == 0 || pitch.Y == 0 {
+		if bounds, ok := opts.Prototypes.Bounds(bp); ok {
+			w, h := bounds.Size()
+			if pitch.X == 0 {
+				pitch.X = w.Floor()
+			}
+			if pitch.Y == 0 {
+				pitch.Y = h.Floor()
+			}
+		}
+	}
+
+	var parts []Part
+	for y := 0; y < rows; y++ {
+		for x := 0; x < columns; x++ {
+			parts = append(parts, Part{Blueprint: bp, Offset: blueprint_schema.TilePosition{X: x * pitch.X, Y: y * pitch.Y}})
+		}
+	}
+	out, overlaps, err := Merge(parts, opts.Options)
+	if err != nil {
+		return nil, nil, err
+	}
+	out.Label, out.LabelColor, out.Description = bp.Label, bp.LabelColor, bp.Description
+	if len(opts.Stitch.Connectors) == 0 {
+		return out, overlaps, nil
+	}
+
+	wires, err := wire_blueprint.FromBlueprint(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	pick := opts.Stitch.Entity
+	if pick == nil {
+		pick = func(e *blueprint_schema.Entity) bool {
+			return opts.Prototypes.Prototype(e.Name).Type == "electric-pole"
+		}
+	}
+	n := len(bp.Entities)
+	number := func(x, y, i int) int {
+		return (y*columns+x)*n + i + 1
+	}
+	for i := range bp.Entities {
+		if !pick(&bp.Entities[i]) {
+			continue
+		}
+		for y := 0; y < rows; y++ {
+			for x := 0; x < columns; x++ {
+				for _, c := range opts.Stitch.Connectors {
+					from := wire_blueprint.End{EntityNumber: number(x, y, i), Connector: c}
+					if x+1 < columns {
+						wires = append(wires, wire_blueprint.Wire{A: from, B: wire_blueprint.End{EntityNumber: number(x+1, y, i), Connector: c}})
+					}
+					if y+1 < rows {
+						wires = append(wires, wire_blueprint.Wire{A: from, B: wire_blueprint.End{EntityNumber: number(x, y+1, i), Connector: c}})
+					}
+				}
+			}
+		}
+	}
+
+	if out.Version.AtLeast(blueprint_schema.GameVersion2_0) {
+		wire_blueprint.SetWires(out, wires)
+	} else if err := wire_blueprint.SetConnections(out, wires); err != nil {
+		return nil, nil, err
+	}
+	return out, overlaps, nil
+}
@@ -0,0 +1,237 @@
+package control_blueprint
+
+import (
+	"fmt"
+	"sort"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// comparators are the comparators of conditions. Blueprints made by the game
+// use the unicode forms; the ascii forms are accepted as well.
+var comparators = map[string]bool{
+	"<": true, ">": true, "=": true,
+	"≤": true, "≥": true, "≠": true,
+	"<=": true, ">=": true, "!=": true,
+}
+
+// arithmeticOperations are the operations of arithmetic combinators.
+var arithmeticOperations = map[string]bool{
+	"*": true, "/": true, "+": true, "-": true, "%": true, "^": true,
+	"<<": true, ">>": true, "AND": true, "OR": true, "XOR": true,
+}
+
+// selectorOperations are the operations of selector combinators.
+var selectorOperations = map[string]bool{
+	"select": true, "count": true, "random": true, "stack-size": true,
+	"rocket-capacity": true, "quality-filter": true, "quality-transfer": true,
+}
+
+func checkComparator(comparator string) error {
+	if !comparators[comparator] {
+		return fmt.Errorf("comparator %q is not known", comparator)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in sorted order, so that errors are
+// reported in a stable order.
+func sortedKeys(m map[string]*Condition) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Networks selects the circuit networks a combinator reads a signal from
+// (new in Factorio 2.0). Both are read if not set.
+type Networks struct {
+	Red   *bool `json:"red,omitempty"`
+	Green *bool `json:"green,omitempty"`
+}
+
+// ArithmeticConditions are the settings of an arithmetic combinator. Each
+// input is either a signal or a constant.
+type ArithmeticConditions struct {
+	FirstSignal *SignalID `json:"first_signal,omitempty"`
+	// FirstConstant is new in Factorio 2.0.
+	FirstConstant *int      `json:"first_constant,omitempty"`
+	SecondSignal  *SignalID `json:"second_signal,omitempty"`
+	// SecondConstant is new in Factorio 2.0; 1.1 uses Constant.
+	SecondConstant *int      `json:"second_constant,omitempty"`
+	Constant       *int      `json:"constant,omitempty"`
+	Operation      *string   `json:"operation,omitempty"`
+	OutputSignal   *SignalID `json:"output_signal,omitempty"`
+	// FirstSignalNetworks and SecondSignalNetworks are new in Factorio 2.0.
+	FirstSignalNetworks  *Networks `json:"first_signal_networks,omitempty"`
+	SecondSignalNetworks *Networks `json:"second_signal_networks,omitempty"`
+
+	Extra blueprint_schema.ExtraFields `json:"-"`
+}
+
+// ArithmeticCombinator is the control behavior of arithmetic combinators.
+type ArithmeticCombinator struct {
+	ArithmeticConditions *ArithmeticConditions `json:"arithmetic_conditions,omitempty"`
+}
+
+// Kind implements View.
+func (*ArithmeticCombinator) Kind() Kind { return KindArithmeticCombinator }
+
+func (v *ArithmeticCombinator) validate() error {
+	c := v.ArithmeticConditions
+	if c == nil {
+		return nil
+	}
+	if c.Operation != nil && !arithmeticOperations[*c.Operation] {
+		return fmt.Errorf("arithmetic_conditions: operation %q is not known", *c.Operation)
+	}
+	if c.FirstSignal != nil && c.FirstConstant != nil {
+		return fmt.Errorf("arithmetic_conditions: both first_signal and first_constant are set")
+	}
+	if c.SecondSignal != nil && (c.SecondConstant != nil || c.Constant != nil) {
+		return fmt.Errorf("arithmetic_conditions: both second_signal and a constant are set")
+	}
+	return nil
+}
+
+// DeciderCondition is one condition of a Factorio 2.0 decider combinator.
+type DeciderCondition struct {
+	FirstSignal          *SignalID `json:"first_signal,omitempty"`
+	FirstSignalNetworks  *Networks `json:"first_signal_networks,omitempty"`
+	SecondSignal         *SignalID `json:"second_signal,omitempty"`
+	SecondSignalNetworks *Networks `json:"second_signal_networks,omitempty"`
+	Constant             *int      `json:"constant,omitempty"`
+	Comparator           *string   `json:"comparator,omitempty"`
+	// CompareType joins the condition with the ones before it: "or", or
+	// "and" if not set.
+	CompareType *string `json:"compare_type,omitempty"`
+
+	Extra blueprint_schema.ExtraFields `json:"-"`
+}
+
+// DeciderOutput is one output of a Factorio 2.0 decider combinator.
+type DeciderOutput struct {
+	Signal             *SignalID `json:"signal,omitempty"`
+	CopyCountFromInput *bool     `json:"copy_count_from_input,omitempty"`
+	Constant           *int      `json:"constant,omitempty"`
+	Networks           *Networks `json:"networks,omitempty"`
+
+	Extra blueprint_schema.ExtraFields `json:"-"`
+}
+
+// DeciderConditions are the settings of a decider combinator. Factorio 1.1
+// uses a single condition and output, in the fields of the same names as
+// Condition; Factorio 2.0 uses Conditions and Outputs.
+type DeciderConditions struct {
+	FirstSignal        *SignalID `json:"first_signal,omitempty"`
+	SecondSignal       *SignalID `json:"second_signal,omitempty"`
+	Constant           *int      `json:"constant,omitempty"`
+	Comparator         *string   `json:"comparator,omitempty"`
+	OutputSignal       *SignalID `json:"output_signal,omitempty"`
+	CopyCountFromInput *bool     `json:"copy_count_from_input,omitempty"`
+
+	Conditions []DeciderCondition `json:"conditions,omitempty"`
+	Outputs    []DeciderOutput    `json:"outputs,omitempty"`
+
+	Extra blueprint_schema.ExtraFields `json:"-"`
+}
+
+// DeciderCombinator is the control behavior of decider combinators.
+type DeciderCombinator struct {
+	DeciderConditions *DeciderConditions `json:"decider_conditions,omitempty"`
+}
+
+// Kind implements View.
+func (*DeciderCombinator) Kind() Kind { return KindDeciderCombinator }
+
+func (v *DeciderCombinator) validate() error {
+	c := v.DeciderConditions
+	if c == nil {
+		return nil
+	}
+	if c.Comparator != nil {
+		if err := checkComparator(*c.Comparator); err != nil {
+			return fmt.Errorf("decider_conditions: %w", err)
+		}
+	}
+	for i, cond := range c.Conditions {
+		if cond.Comparator != nil {
+			if err := checkComparator(*cond.Comparator); err != nil {
+				return fmt.Errorf("decider_conditions: condition %d: %w", i, err)
+			}
+		}
+		if cond.CompareType != nil && *cond.CompareType != "and" && *cond.CompareType != "or" {
+			return fmt.Errorf("decider_conditions: condition %d: compare_type %q is not and or or", i, *cond.CompareType)
+		}
+	}
+	return nil
+}
+
+// QualityFilter selects signals by quality.
+type QualityFilter struct {
+	Quality    *blueprint_schema.Quality `json:"quality,omitempty"`
+	Comparator *string                   `json:"comparator,omitempty"`
+}
+
+// SelectorCombinator is the control behavior of selector combinators (new in
+// Factorio 2.0).
+type SelectorCombinator struct {
+	Operation                *string                   `json:"operation,omitempty"`
+	SelectMax                *bool                     `json:"select_max,omitempty"`
+	IndexSignal              *SignalID                 `json:"index_signal,omitempty"`
+	IndexConstant            *int                      `json:"index_constant,omitempty"`
+	CountSignal              *SignalID                 `json:"count_signal,omitempty"`
+	RandomUpdateInterval     *int                      `json:"random_update_interval,omitempty"`
+	QualityFilter            *QualityFilter            `json:"quality_filter,omitempty"`
+	SelectQualityFromSignal  *bool                     `json:"select_quality_from_signal,omitempty"`
+	QualitySourceStatic      *blueprint_schema.Quality `json:"quality_source_static,omitempty"`
+	QualitySourceSignal      *SignalID                 `json:"quality_source_signal,omitempty"`
+	QualityDestinationSignal *SignalID                 `json:"quality_destination_signal,omitempty"`
+}
+
+// Kind implements View.
+func (*SelectorCombinator) Kind() Kind { return KindSelectorCombinator }
+
+func (v *SelectorCombinator) validate() error {
+	if v.Operation != nil && !selectorOperations[*v.Operation] {
+		return fmt.Errorf("operation %q is not known", *v.Operation)
+	}
+	if v.QualityFilter != nil && v.QualityFilter.Comparator != nil {
+		if err := checkComparator(*v.QualityFilter.Comparator); err != nil {
+			return fmt.Errorf("quality_filter: %w", err)
+		}
+	}
+	if v.RandomUpdateInterval != nil && *v.RandomUpdateInterval < 0 {
+		return fmt.Errorf("random_update_interval %d is negative", *v.RandomUpdateInterval)
+	}
+	return nil
+}
+
+// ConstantCombinator is the control behavior of constant combinators.
+// Factorio 1.1 uses Filters, Factorio 2.0 uses Sections.
+type ConstantCombinator struct {
+	IsOn     *bool                                      `json:"is_on,omitempty"`
+	Filters  []blueprint_schema.BlueprintLogisticFilter `json:"filters,omitempty"`
+	Sections *blueprint_schema.LogisticSections         `json:"sections,omitempty"`
+}
+
+// Kind implements View.
+func (*ConstantCombinator) Kind() Kind { return KindConstantCombinator }
+
+func (v *ConstantCombinator) validate() error {
+	if v.Sections == nil {
+		return nil
+	}
+	for _, s := range v.Sections.Sections {
+		for _, f := range s.Filters {
+			if f.Comparator != "" {
+				if err := checkComparator(f.Comparator); err != nil {
+					return fmt.Errorf("section %d: filter %d: %w", s.Index, f.Index, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+// Package control_blueprint gives typed views of the control behavior of
+// entities, one per kind of entity, instead of the single ControlBehavior
+// struct of the schema which mixes the fields of all of them.
+//
+// Decode checks that the control behavior of an entity only holds fields which
+// are legal for the kind of the entity, and that their values make sense.
+// Encode writes a view back into the entity.
+//
+// There are views for inserters, belts, train stops, lamps, rail signals,
+// roboports, accumulators, mining drills, walls, programmable speakers, pumps,
+// power switches and combinators. Chests, including logistic chests, and
+// storage tanks have no view: Get and Decode return an error wrapping
+// ErrUnsupportedKind for them. Other entities, such as modded ones, are of
+// unknown kind until they are registered with RegisterKind.
+//
+//	var inserter control_blueprint.Inserter
+//	if err := control_blueprint.Decode(&entity, &inserter); err != nil {
+//		return err
+//	}
+//	inserter.CircuitSetStackSize = &yes
+//	inserter.StackControlInputSignal = &blueprint_schema.SignalID{Type: &virtual, Name: "signal-S"}
+//	if err := control_blueprint.Encode(&entity, &inserter); err != nil {
+//		return err
+//	}
+//
+// The public interface is unstable.
+package control_blueprint // badc0de.net/pkg/factorioblueprint/control_blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Kind is a kind of entity with its own set of control behavior fields.
+type Kind string
+
+const (
+	KindUnknown              Kind = ""
+	KindInserter             Kind = "inserter"
+	KindTransportBelt        Kind = "transport-belt"
+	KindTrainStop            Kind = "train-stop"
+	KindLamp                 Kind = "lamp"
+	KindRailSignal           Kind = "rail-signal"
+	KindRoboport             Kind = "roboport"
+	KindAccumulator          Kind = "accumulator"
+	KindMiningDrill          Kind = "mining-drill"
+	KindWall                 Kind = "wall"
+	KindProgrammableSpeaker  Kind = "programmable-speaker"
+	KindConstantCombinator   Kind = "constant-combinator"
+	KindArithmeticCombinator Kind = "arithmetic-combinator"
+	KindDeciderCombinator    Kind = "decider-combinator"
+	KindSelectorCombinator   Kind = "selector-combinator"
+	KindPump                 Kind = "pump"
+	KindPowerSwitch          Kind = "power-switch"
+
+	// KindUnsupported is the kind of entities which have a control behavior
+	// this package has no view for.
+	KindUnsupported Kind = "unsupported"
+)
+
+// ErrUnsupportedKind is returned, wrapped, when decoding the control behavior
+// of an entity of KindUnsupported.
+var ErrUnsupportedKind = errors.New("unsupported kind")
+
+// kindsMu guards kinds, which RegisterKind may change while entities are
+// decoded.
+var kindsMu sync.RWMutex
+
+// kinds maps names of vanilla entities to their kind.
+var kinds = map[string]Kind{
+	"burner-inserter":        KindInserter,
+	"inserter":               KindInserter,
+	"long-handed-inserter":   KindInserter,
+	"fast-inserter":          KindInserter,
+	"filter-inserter":        KindInserter,
+	"stack-inserter":         KindInserter,
+	"stack-filter-inserter":  KindInserter,
+	"bulk-inserter":          KindInserter,
+	"transport-belt":         KindTransportBelt,
+	"fast-transport-belt":    KindTransportBelt,
+	"express-transport-belt": KindTransportBelt,
+	"turbo-transport-belt":   KindTransportBelt,
+	"train-stop":             KindTrainStop,
+	"small-lamp":             KindLamp,
+	"rail-signal":            KindRailSignal,
+	"rail-chain-signal":      KindRailSignal,
+	"roboport":               KindRoboport,
+	"accumulator":            KindAccumulator,
+	"burner-mining-drill":    KindMiningDrill,
+	"electric-mining-drill":  KindMiningDrill,
+	"big-mining-drill":       KindMiningDrill,
+	"pumpjack":               KindMiningDrill,
+	"stone-wall":             KindWall,
+	"programmable-speaker":   KindProgrammableSpeaker,
+	"constant-combinator":    KindConstantCombinator,
+	"arithmetic-combinator":  KindArithmeticCombinator,
+	"decider-combinator":     KindDeciderCombinator,
+	"selector-combinator":    KindSelectorCombinator,
+	"pump":                   KindPump,
+	"offshore-pump":          KindPump,
+	"power-switch":           KindPowerSwitch,
+
+	"wooden-chest":                    KindUnsupported,
+	"iron-chest":                      KindUnsupported,
+	"steel-chest":                     KindUnsupported,
+	"logistic-chest-active-provider":  KindUnsupported,
+	"logistic-chest-passive-provider": KindUnsupported,
+	"logistic-chest-storage":          KindUnsupported,
+	"logistic-chest-buffer":           KindUnsupported,
+	"logistic-chest-requester":        KindUnsupported,
+	"active-provider-chest":           KindUnsupported,
+	"passive-provider-chest":          KindUnsupported,
+	"storage-chest":                   KindUnsupported,
+	"buffer-chest":                    KindUnsupported,
+	"requester-chest":                 KindUnsupported,
+	"storage-tank":                    KindUnsupported,
+}
+
+// KindOf returns the kind of the entity with the given prototype name, or
+// KindUnknown for entities which are not known, such as modded ones.
+func KindOf(name string) Kind {
+	kindsMu.RLock()
+	defer kindsMu.RUnlock()
+	return kinds[name]
+}
+
+// RegisterKind makes entities with the given prototype name, such as modded
+// ones, use the view of kind. It is safe to call while other goroutines
+// decode entities.
+func RegisterKind(name string, kind Kind) {
+	kindsMu.Lock()
+	defer kindsMu.Unlock()
+	kinds[name] = kind
+}
+
+// View is a typed view of the control behavior of one kind of entity.
+type View interface {
+	// Kind returns the kind of entity the view is for.
+	Kind() Kind
+}
+
+// validator is implemented by views which check the values of their fields,
+// beyond what decoding checks.
+type validator interface {
+	validate() error
+}
+
+// New returns an empty view for the kind, or nil for KindUnknown and
+// KindUnsupported.
+func New(kind Kind) View {
+	switch kind {
+	case KindInserter:
+		return &Inserter{}
+	case KindTransportBelt:
+		return &TransportBelt{}
+	case KindTrainStop:
+		return &TrainStop{}
+	case KindLamp:
+		return &Lamp{}
+	case KindRailSignal:
+		return &RailSignal{}
+	case KindRoboport:
+		return &Roboport{}
+	case KindAccumulator:
+		return &Accumulator{}
+	case KindMiningDrill:
+		return &MiningDrill{}
+	case KindWall:
+		return &Wall{}
+	case KindProgrammableSpeaker:
+		return &ProgrammableSpeaker{}
+	case KindConstantCombinator:
+		return &ConstantCombinator{}
+	case KindArithmeticCombinator:
+		return &ArithmeticCombinator{}
+	case KindDeciderCombinator:
+		return &DeciderCombinator{}
+	case KindSelectorCombinator:
+		return &SelectorCombinator{}
+	case KindPump:
+		return &Pump{}
+	case KindPowerSwitch:
+		return &PowerSwitch{}
+	default:
+		return nil
+	}
+}
+
+// Get returns the view of the control behavior of the entity, of the type
+// matching its kind. See Decode for the checks done.
+func Get(e *blueprint_schema.Entity) (View, error) {
+	kind := KindOf(e.Name)
+	if kind == KindUnsupported {
+		return nil, unsupported(e)
+	}
+	v := New(kind)
+	if v == nil {
+		return nil, fmt.Errorf("entity %d: %s has no known control behavior", e.EntityNumber, e.Name)
+	}
+	if err := Decode(e, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Validate checks that the control behavior of the entity only holds fields
+// which are legal for its kind, and that their values make sense. Entities of
+// unknown and unsupported kinds are not checked.
+func Validate(e *blueprint_schema.Entity) error {
+	if kind := KindOf(e.Name); kind == KindUnknown || kind == KindUnsupported {
+		return nil
+	}
+	_, err := Get(e)
+	return err
+}
+
+// Decode reads the control behavior of the entity into v. It returns an error
+// if v is not for the kind of the entity, if the control behavior holds fields
+// which v does not have, or if v finds their values invalid.
+//
+// Entities of unknown kinds can be decoded into any view, and entities of
+// KindUnsupported into none.
+func Decode(e *blueprint_schema.Entity, v View) error {
+	kind := KindOf(e.Name)
+	if kind == KindUnsupported {
+		return unsupported(e)
+	}
+	if kind != KindUnknown && kind != v.Kind() {
+		return fmt.Errorf("entity %d: %s is of kind %s, not %s", e.EntityNumber, e.Name, kind, v.Kind())
+	}
+	if e.ControlBehavior == nil {
+		return nil
+	}
+
+	// Go through JSON, so that fields unknown to the schema, which are kept in
+	// Extra, are decoded too.
+	data, err := blueprint_schema.MarshalLossless(e.ControlBehavior)
+	if err != nil {
+		return fmt.Errorf("entity %d: %w", e.EntityNumber, err)
+	}
+	if err := checkFields(data, v); err != nil {
+		return fmt.Errorf("entity %d: control behavior of %s: %w", e.EntityNumber, v.Kind(), err)
+	}
+	if err := blueprint_schema.UnmarshalLossless(data, v); err != nil {
+		return fmt.Errorf("entity %d: control behavior of %s: %w", e.EntityNumber, v.Kind(), err)
+	}
+	if val, ok := v.(validator); ok {
+		if err := val.validate(); err != nil {
+			return fmt.Errorf("entity %d: control behavior of %s: %w", e.EntityNumber, v.Kind(), err)
+		}
+	}
+	return nil
+}
+
+// unsupported returns the error for an entity of KindUnsupported.
+func unsupported(e *blueprint_schema.Entity) error {
+	return fmt.Errorf("entity %d: control behavior of %s: %w", e.EntityNumber, e.Name, ErrUnsupportedKind)
+}
+
+// Encode replaces the control behavior of the entity with v. It returns an
+// error if v is not for the kind of the entity, or if v finds its values
+// invalid.
+func Encode(e *blueprint_schema.Entity, v View) error {
+	if kind := KindOf(e.Name); kind != KindUnknown && kind != v.Kind() {
+		return fmt.Errorf("entity %d: %s is of kind %s, not %s", e.EntityNumber, e.Name, kind, v.Kind())
+	}
+	if val, ok := v.(validator); ok {
+		if err := val.validate(); err != nil {
+			return fmt.Errorf("entity %d: control behavior of %s: %w", e.EntityNumber, v.Kind(), err)
+		}
+	}
+
+	data, err := blueprint_schema.MarshalLossless(v)
+	if err != nil {
+		return fmt.Errorf("entity %d: %w", e.EntityNumber, err)
+	}
+	var cb blueprint_schema.ControlBehavior
+	if err := blueprint_schema.UnmarshalLossless(data, &cb); err != nil {
+		return fmt.Errorf("entity %d: %w", e.EntityNumber, err)
+	}
+	if e.ControlBehavior != nil {
+		// Keep the keys in the order they were, and unchanged values as
+		// they were written.
+		cb.Layout = e.ControlBehavior.Layout
+	}
+	if bytes.Equal(data, []byte("{}")) {
+		e.ControlBehavior = nil
+	} else {
+		e.ControlBehavior = &cb
+	}
+	return nil
+}
+
+// checkFields returns an error naming the first key of the JSON object data,
+// in sorted order, which is not a field of the view v. Nested objects are
+// checked too, depth first, except that schema structs and other structs with
+// an Extra field keep unknown keys there as usual.
+func checkFields(data []byte, v View) error {
+	return checkValue(data, reflect.TypeOf(v).Elem(), "")
+}
+
+var extraFieldsType = reflect.TypeOf(blueprint_schema.ExtraFields(nil))
+
+// checkValue checks the JSON value data, named path, against the type t it is
+// decoded into, as checkFields does. Values of the wrong JSON type are left
+// for decoding to report.
+func checkValue(data json.RawMessage, t reflect.Type, path string) error {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return checkValue(data, t.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		var elems []json.RawMessage
+		if json.Unmarshal(data, &elems) != nil {
+			return nil
+		}
+		for i, elem := range elems {
+			if err := checkValue(elem, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			return nil
+		}
+		fields := make(map[string]reflect.Type)
+		open := false
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Type == extraFieldsType {
+				open = true
+				continue
+			}
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name != "" && name != "-" {
+				fields[name] = f.Type
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name := k
+			if path != "" {
+				name = path + "." + k
+			}
+			ft, ok := fields[k]
+			if !ok {
+				if open {
+					continue
+				}
+				return fmt.Errorf("field %s is not legal", name)
+			}
+			if err := checkValue(obj[k], ft, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
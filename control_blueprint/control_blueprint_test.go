@@ -0,0 +1,193 @@
+package control_blueprint
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// decodeEntity decodes a single entity from JSON.
+func decodeEntity(t testing.TB, data string) *blueprint_schema.Entity {
+	var e blueprint_schema.Entity
+	if err := blueprint_schema.UnmarshalLossless([]byte(data), &e); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	return &e
+}
+
+// Example of turning a 1.1 inserter into one which sets its stack size from
+// the circuit network.
+func ExampleEncode() {
+	var e blueprint_schema.Entity
+	data := `{"entity_number": 2, "name": "stack-inserter", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"circuit_mode_of_operation": 3}}`
+	if err := blueprint_schema.UnmarshalLossless([]byte(data), &e); err != nil {
+		panic(err)
+	}
+
+	var inserter Inserter
+	if err := Decode(&e, &inserter); err != nil {
+		panic(err)
+	}
+	mode, yes, virtual := InserterModeSetStackSize, true, blueprint_schema.SignalIDTypeVirtual
+	inserter.CircuitModeOfOperation = &mode
+	inserter.CircuitSetStackSize = &yes
+	inserter.StackControlInputSignal = &SignalID{Type: &virtual, Name: "signal-S"}
+	if err := Encode(&e, &inserter); err != nil {
+		panic(err)
+	}
+
+	out, err := blueprint_schema.MarshalLossless(e.ControlBehavior)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+
+	// Output:
+	// {"circuit_mode_of_operation":4,"circuit_set_stack_size":true,"stack_control_input_signal":{"name":"signal-S","type":"virtual"}}
+}
+
+func TestGet(t *testing.T) {
+	e := decodeEntity(t, `{"entity_number": 2, "name": "fast-inserter", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {
+		"circuit_read_hand_contents": true,
+		"circuit_hand_read_mode": 1,
+		"circuit_condition": {"first_signal": {"type": "item", "name": "iron-plate"}, "constant": 100, "comparator": "<", "fulfilled": false}
+	}}`)
+	v, err := Get(e)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	inserter, ok := v.(*Inserter)
+	if !ok {
+		t.Fatalf("Bad data: want *Inserter got '%T'", v)
+	}
+	if inserter.CircuitHandReadMode == nil || *inserter.CircuitHandReadMode != ReadModeHold {
+		t.Fatalf("Bad data: want circuit_hand_read_mode = 1 got '%v'", inserter.CircuitHandReadMode)
+	}
+	c := inserter.CircuitCondition
+	if c == nil || c.FirstSignal.Name != "iron-plate" || *c.Constant != 100 || c.Extra["fulfilled"] != false {
+		t.Fatalf("Bad data: want condition on iron-plate got '%+v'", c)
+	}
+
+	// Encoding the view again keeps everything, including the field unknown to
+	// the schema.
+	want, err := blueprint_schema.MarshalLossless(e.ControlBehavior)
+	if err != nil {
+		t.Fatalf("Failed to encode JSON: %v", err)
+	}
+	if err := Encode(e, v); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got, err := blueprint_schema.MarshalLossless(e.ControlBehavior)
+	if err != nil {
+		t.Fatalf("Failed to encode JSON: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("Bad data: want = %s got '%s'", want, got)
+	}
+}
+
+func TestGet_combinators(t *testing.T) {
+	e := decodeEntity(t, `{"entity_number": 1, "name": "decider-combinator", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"decider_conditions": {
+		"conditions": [
+			{"first_signal": {"type": "virtual", "name": "signal-A"}, "constant": 5, "comparator": "≥"},
+			{"first_signal": {"type": "virtual", "name": "signal-B"}, "first_signal_networks": {"green": false}, "comparator": "=", "compare_type": "or"}
+		],
+		"outputs": [{"signal": {"type": "virtual", "name": "signal-C"}, "copy_count_from_input": false}]
+	}}}`)
+	v, err := Get(e)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	c := v.(*DeciderCombinator).DeciderConditions
+	if len(c.Conditions) != 2 || *c.Conditions[1].CompareType != "or" || *c.Conditions[1].FirstSignalNetworks.Green {
+		t.Fatalf("Bad data: want 2 conditions got '%+v'", c.Conditions)
+	}
+	if len(c.Outputs) != 1 || c.Outputs[0].Signal.Name != "signal-C" {
+		t.Fatalf("Bad data: want output signal-C got '%+v'", c.Outputs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		entity string
+		want   string
+	}{
+		{`{"entity_number": 1, "name": "accumulator", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"output_signal": {"type": "virtual", "name": "signal-A"}}}`, ""},
+		{`{"entity_number": 1, "name": "modded-thing", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"whatever": 1}}`, ""},
+		{`{"entity_number": 1, "name": "small-lamp", "position": {"x": 0.5, "y": 0.5}}`, ""},
+		{`{"entity_number": 1, "name": "accumulator", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"circuit_condition": {}}}`,
+			"entity 1: control behavior of accumulator: field circuit_condition is not legal"},
+		{`{"entity_number": 2, "name": "inserter", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"circuit_mode_of_operation": 7}}`,
+			"entity 2: control behavior of inserter: circuit_mode_of_operation 7 is not within 0 to 4"},
+		{`{"entity_number": 3, "name": "small-lamp", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"circuit_condition": {"comparator": "~"}}}`,
+			"entity 3: control behavior of lamp: circuit_condition: comparator \"~\" is not known"},
+		{`{"entity_number": 4, "name": "arithmetic-combinator", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"arithmetic_conditions": {"operation": "NAND"}}}`,
+			"entity 4: control behavior of arithmetic-combinator: arithmetic_conditions: operation \"NAND\" is not known"},
+		{`{"entity_number": 5, "name": "selector-combinator", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"operation": "sort"}}`,
+			"entity 5: control behavior of selector-combinator: operation \"sort\" is not known"},
+		{`{"entity_number": 6, "name": "pump", "position": {"x": 0.5, "y": 1}, "control_behavior": {"circuit_condition": {"comparator": ">", "constant": 10}}}`, ""},
+		{`{"entity_number": 7, "name": "power-switch", "position": {"x": 1, "y": 1}, "control_behavior": {"use_colors": true}}`,
+			"entity 7: control behavior of power-switch: field use_colors is not legal"},
+		{`{"entity_number": 8, "name": "steel-chest", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"circuit_mode_of_operation": 1}}`, ""},
+		{`{"entity_number": 9, "name": "programmable-speaker", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"circuit_parameters": {"note_id": 1, "pitch": 2}}}`,
+			"entity 9: control behavior of programmable-speaker: field circuit_parameters.pitch is not legal"},
+		{`{"entity_number": 10, "name": "decider-combinator", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"decider_conditions": {"conditions": [{"first_signal_networks": {"red": true, "blue": true}}]}}}`,
+			"entity 10: control behavior of decider-combinator: field decider_conditions.conditions[0].first_signal_networks.blue is not legal"},
+	} {
+		err := Validate(decodeEntity(t, tc.entity))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Fatalf("Bad data: want = '%v' got '%v'", tc.want, got)
+		}
+	}
+}
+
+func TestDecode_wrongKind(t *testing.T) {
+	e := decodeEntity(t, `{"entity_number": 1, "name": "small-lamp", "position": {"x": 0.5, "y": 0.5}}`)
+	err := Decode(e, &Inserter{})
+	if err == nil || !strings.Contains(err.Error(), "small-lamp is of kind lamp, not inserter") {
+		t.Fatalf("Bad data: want kind error got '%v'", err)
+	}
+}
+
+// TestDecode_unsupported checks that chests and storage tanks, which have no
+// view, cannot be decoded into any.
+func TestDecode_unsupported(t *testing.T) {
+	e := decodeEntity(t, `{"entity_number": 1, "name": "logistic-chest-requester", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"circuit_mode_of_operation": 1}}`)
+	if _, err := Get(e); !errors.Is(err, ErrUnsupportedKind) {
+		t.Fatalf("Bad data: want unsupported kind error got '%v'", err)
+	}
+	err := Decode(e, &Inserter{})
+	if want := "entity 1: control behavior of logistic-chest-requester: unsupported kind"; err == nil || err.Error() != want {
+		t.Fatalf("Bad data: want = '%v' got '%v'", want, err)
+	}
+}
+
+// TestRegisterKind checks that modded entities can be registered while
+// other goroutines decode entities; run with -race.
+func TestRegisterKind(t *testing.T) {
+	e := decodeEntity(t, `{"entity_number": 1, "name": "small-lamp", "position": {"x": 0.5, "y": 0.5}, "control_behavior": {"use_colors": true}}`)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			RegisterKind(fmt.Sprintf("modded-lamp-%d", i), KindLamp)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := Get(e); err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+	}
+	<-done
+
+	if kind := KindOf("modded-lamp-99"); kind != KindLamp {
+		t.Fatalf("Bad data: want kind = '%v' got '%v'", KindLamp, kind)
+	}
+}
@@ -0,0 +1,349 @@
+package control_blueprint
+
+import (
+	"fmt"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Condition is a circuit or logistic condition.
+type Condition = blueprint_schema.Condition
+
+// SignalID is a signal, such as an item or a virtual signal.
+type SignalID = blueprint_schema.SignalID
+
+// Modes of operation of 1.1 inserters, as circuit_mode_of_operation.
+const (
+	InserterModeEnableDisable = 0
+	InserterModeSetFilters    = 1
+	InserterModeReadHand      = 2
+	InserterModeNone          = 3
+	InserterModeSetStackSize  = 4
+)
+
+// Modes of reading contents, as circuit_hand_read_mode of inserters and
+// circuit_contents_read_mode of belts.
+const (
+	ReadModePulse = 0
+	ReadModeHold  = 1
+	// ReadModeEntireBelt is new in Factorio 2.0, and only for belts.
+	ReadModeEntireBelt = 2
+)
+
+// Color modes of lamps, as color_mode.
+const (
+	LampColorModeColorMapping = 0
+	LampColorModeComponents   = 1
+	LampColorModePacked       = 2
+)
+
+// Modes of reading resources of mining drills, as circuit_resource_read_mode.
+const (
+	ResourceReadModeThisMiner   = 0
+	ResourceReadModeEntirePatch = 1
+)
+
+// Modes of reading items of roboports, as read_items_mode.
+const (
+	RoboportReadItemsNone       = 0
+	RoboportReadItemsLogistics  = 1
+	RoboportReadItemsMissingReq = 2
+)
+
+// Inserter is the control behavior of inserters.
+type Inserter struct {
+	// CircuitModeOfOperation is one of the InserterMode constants (Factorio
+	// 1.1). Factorio 2.0 uses the boolean fields instead.
+	CircuitModeOfOperation  *int       `json:"circuit_mode_of_operation,omitempty"`
+	CircuitEnabled          *bool      `json:"circuit_enabled,omitempty"`
+	CircuitCondition        *Condition `json:"circuit_condition,omitempty"`
+	CircuitSetFilters       *bool      `json:"circuit_set_filters,omitempty"`
+	CircuitReadHandContents *bool      `json:"circuit_read_hand_contents,omitempty"`
+	// CircuitHandReadMode is ReadModePulse or ReadModeHold.
+	CircuitHandReadMode      *int       `json:"circuit_hand_read_mode,omitempty"`
+	CircuitSetStackSize      *bool      `json:"circuit_set_stack_size,omitempty"`
+	StackControlInputSignal  *SignalID  `json:"stack_control_input_signal,omitempty"`
+	ConnectToLogisticNetwork *bool      `json:"connect_to_logistic_network,omitempty"`
+	LogisticCondition        *Condition `json:"logistic_condition,omitempty"`
+}
+
+// Kind implements View.
+func (*Inserter) Kind() Kind { return KindInserter }
+
+func (v *Inserter) validate() error {
+	if err := checkRange("circuit_mode_of_operation", v.CircuitModeOfOperation, InserterModeEnableDisable, InserterModeSetStackSize); err != nil {
+		return err
+	}
+	if err := checkRange("circuit_hand_read_mode", v.CircuitHandReadMode, ReadModePulse, ReadModeHold); err != nil {
+		return err
+	}
+	return checkConditions(map[string]*Condition{
+		"circuit_condition":  v.CircuitCondition,
+		"logistic_condition": v.LogisticCondition,
+	})
+}
+
+// TransportBelt is the control behavior of transport belts.
+type TransportBelt struct {
+	CircuitEnableDisable    *bool      `json:"circuit_enable_disable,omitempty"`
+	CircuitCondition        *Condition `json:"circuit_condition,omitempty"`
+	CircuitReadHandContents *bool      `json:"circuit_read_hand_contents,omitempty"`
+	// CircuitContentsReadMode is one of the ReadMode constants.
+	CircuitContentsReadMode  *int       `json:"circuit_contents_read_mode,omitempty"`
+	ConnectToLogisticNetwork *bool      `json:"connect_to_logistic_network,omitempty"`
+	LogisticCondition        *Condition `json:"logistic_condition,omitempty"`
+}
+
+// Kind implements View.
+func (*TransportBelt) Kind() Kind { return KindTransportBelt }
+
+func (v *TransportBelt) validate() error {
+	if err := checkRange("circuit_contents_read_mode", v.CircuitContentsReadMode, ReadModePulse, ReadModeEntireBelt); err != nil {
+		return err
+	}
+	return checkConditions(map[string]*Condition{
+		"circuit_condition":  v.CircuitCondition,
+		"logistic_condition": v.LogisticCondition,
+	})
+}
+
+// TrainStop is the control behavior of train stops.
+type TrainStop struct {
+	CircuitEnableDisable     *bool      `json:"circuit_enable_disable,omitempty"`
+	CircuitCondition         *Condition `json:"circuit_condition,omitempty"`
+	ConnectToLogisticNetwork *bool      `json:"connect_to_logistic_network,omitempty"`
+	LogisticCondition        *Condition `json:"logistic_condition,omitempty"`
+	SendToTrain              *bool      `json:"send_to_train,omitempty"`
+	ReadFromTrain            *bool      `json:"read_from_train,omitempty"`
+	ReadStoppedTrain         *bool      `json:"read_stopped_train,omitempty"`
+	TrainStoppedSignal       *SignalID  `json:"train_stopped_signal,omitempty"`
+	SetTrainsLimit           *bool      `json:"set_trains_limit,omitempty"`
+	TrainsLimitSignal        *SignalID  `json:"trains_limit_signal,omitempty"`
+	ReadTrainsCount          *bool      `json:"read_trains_count,omitempty"`
+	TrainsCountSignal        *SignalID  `json:"trains_count_signal,omitempty"`
+	// SetPriority and PrioritySignal are new in Factorio 2.0.
+	SetPriority    *bool     `json:"set_priority,omitempty"`
+	PrioritySignal *SignalID `json:"priority_signal,omitempty"`
+}
+
+// Kind implements View.
+func (*TrainStop) Kind() Kind { return KindTrainStop }
+
+func (v *TrainStop) validate() error {
+	return checkConditions(map[string]*Condition{
+		"circuit_condition":  v.CircuitCondition,
+		"logistic_condition": v.LogisticCondition,
+	})
+}
+
+// Lamp is the control behavior of lamps.
+type Lamp struct {
+	CircuitCondition         *Condition `json:"circuit_condition,omitempty"`
+	ConnectToLogisticNetwork *bool      `json:"connect_to_logistic_network,omitempty"`
+	LogisticCondition        *Condition `json:"logistic_condition,omitempty"`
+	UseColors                *bool      `json:"use_colors,omitempty"`
+	// ColorMode is one of the LampColorMode constants (new in Factorio 2.0).
+	ColorMode   *int      `json:"color_mode,omitempty"`
+	RedSignal   *SignalID `json:"red_signal,omitempty"`
+	GreenSignal *SignalID `json:"green_signal,omitempty"`
+	BlueSignal  *SignalID `json:"blue_signal,omitempty"`
+	RGBSignal   *SignalID `json:"rgb_signal,omitempty"`
+}
+
+// Kind implements View.
+func (*Lamp) Kind() Kind { return KindLamp }
+
+func (v *Lamp) validate() error {
+	if err := checkRange("color_mode", v.ColorMode, LampColorModeColorMapping, LampColorModePacked); err != nil {
+		return err
+	}
+	return checkConditions(map[string]*Condition{
+		"circuit_condition":  v.CircuitCondition,
+		"logistic_condition": v.LogisticCondition,
+	})
+}
+
+// RailSignal is the control behavior of rail signals and rail chain signals.
+type RailSignal struct {
+	CircuitCloseSignal *bool      `json:"circuit_close_signal,omitempty"`
+	CircuitReadSignal  *bool      `json:"circuit_read_signal,omitempty"`
+	CircuitCondition   *Condition `json:"circuit_condition,omitempty"`
+	RedOutputSignal    *SignalID  `json:"red_output_signal,omitempty"`
+	OrangeOutputSignal *SignalID  `json:"orange_output_signal,omitempty"`
+	GreenOutputSignal  *SignalID  `json:"green_output_signal,omitempty"`
+	// BlueOutputSignal is only for rail chain signals.
+	BlueOutputSignal *SignalID `json:"blue_output_signal,omitempty"`
+}
+
+// Kind implements View.
+func (*RailSignal) Kind() Kind { return KindRailSignal }
+
+func (v *RailSignal) validate() error {
+	return checkConditions(map[string]*Condition{"circuit_condition": v.CircuitCondition})
+}
+
+// Roboport is the control behavior of roboports.
+type Roboport struct {
+	ReadLogistics  *bool `json:"read_logistics,omitempty"`
+	ReadRobotStats *bool `json:"read_robot_stats,omitempty"`
+	// ReadItemsMode is one of the RoboportReadItems constants (new in
+	// Factorio 2.0).
+	ReadItemsMode                     *int      `json:"read_items_mode,omitempty"`
+	AvailableLogisticOutputSignal     *SignalID `json:"available_logistic_output_signal,omitempty"`
+	TotalLogisticOutputSignal         *SignalID `json:"total_logistic_output_signal,omitempty"`
+	AvailableConstructionOutputSignal *SignalID `json:"available_construction_output_signal,omitempty"`
+	TotalConstructionOutputSignal     *SignalID `json:"total_construction_output_signal,omitempty"`
+	RoboportCountOutputSignal         *SignalID `json:"roboport_count_output_signal,omitempty"`
+}
+
+// Kind implements View.
+func (*Roboport) Kind() Kind { return KindRoboport }
+
+func (v *Roboport) validate() error {
+	return checkRange("read_items_mode", v.ReadItemsMode, RoboportReadItemsNone, RoboportReadItemsMissingReq)
+}
+
+// Accumulator is the control behavior of accumulators.
+type Accumulator struct {
+	OutputSignal *SignalID `json:"output_signal,omitempty"`
+}
+
+// Kind implements View.
+func (*Accumulator) Kind() Kind { return KindAccumulator }
+
+// MiningDrill is the control behavior of mining drills and pumpjacks.
+type MiningDrill struct {
+	CircuitEnableDisable     *bool      `json:"circuit_enable_disable,omitempty"`
+	CircuitCondition         *Condition `json:"circuit_condition,omitempty"`
+	ConnectToLogisticNetwork *bool      `json:"connect_to_logistic_network,omitempty"`
+	LogisticCondition        *Condition `json:"logistic_condition,omitempty"`
+	CircuitReadResources     *bool      `json:"circuit_read_resources,omitempty"`
+	// CircuitResourceReadMode is one of the ResourceReadMode constants.
+	CircuitResourceReadMode *int `json:"circuit_resource_read_mode,omitempty"`
+}
+
+// Kind implements View.
+func (*MiningDrill) Kind() Kind { return KindMiningDrill }
+
+func (v *MiningDrill) validate() error {
+	if err := checkRange("circuit_resource_read_mode", v.CircuitResourceReadMode, ResourceReadModeThisMiner, ResourceReadModeEntirePatch); err != nil {
+		return err
+	}
+	return checkConditions(map[string]*Condition{
+		"circuit_condition":  v.CircuitCondition,
+		"logistic_condition": v.LogisticCondition,
+	})
+}
+
+// Wall is the control behavior of walls, which read and control the gates
+// next to them.
+type Wall struct {
+	CircuitOpenGate   *bool      `json:"circuit_open_gate,omitempty"`
+	CircuitReadSensor *bool      `json:"circuit_read_sensor,omitempty"`
+	CircuitCondition  *Condition `json:"circuit_condition,omitempty"`
+	OutputSignal      *SignalID  `json:"output_signal,omitempty"`
+}
+
+// Kind implements View.
+func (*Wall) Kind() Kind { return KindWall }
+
+func (v *Wall) validate() error {
+	return checkConditions(map[string]*Condition{"circuit_condition": v.CircuitCondition})
+}
+
+// SpeakerParameters are the circuit parameters of a programmable speaker.
+type SpeakerParameters struct {
+	SignalValueIsPitch *bool `json:"signal_value_is_pitch,omitempty"`
+	InstrumentID       *int  `json:"instrument_id,omitempty"`
+	NoteID             *int  `json:"note_id,omitempty"`
+	// StopPlayingSounds is new in Factorio 2.0.
+	StopPlayingSounds *bool `json:"stop_playing_sounds,omitempty"`
+}
+
+// ProgrammableSpeaker is the control behavior of programmable speakers.
+type ProgrammableSpeaker struct {
+	CircuitEnableDisable     *bool              `json:"circuit_enable_disable,omitempty"`
+	CircuitCondition         *Condition         `json:"circuit_condition,omitempty"`
+	ConnectToLogisticNetwork *bool              `json:"connect_to_logistic_network,omitempty"`
+	LogisticCondition        *Condition         `json:"logistic_condition,omitempty"`
+	CircuitParameters        *SpeakerParameters `json:"circuit_parameters,omitempty"`
+}
+
+// Kind implements View.
+func (*ProgrammableSpeaker) Kind() Kind { return KindProgrammableSpeaker }
+
+func (v *ProgrammableSpeaker) validate() error {
+	if p := v.CircuitParameters; p != nil {
+		if p.InstrumentID != nil && *p.InstrumentID < 0 {
+			return fmt.Errorf("circuit_parameters: instrument_id %d is negative", *p.InstrumentID)
+		}
+		if p.NoteID != nil && *p.NoteID < 0 {
+			return fmt.Errorf("circuit_parameters: note_id %d is negative", *p.NoteID)
+		}
+	}
+	return checkConditions(map[string]*Condition{
+		"circuit_condition":  v.CircuitCondition,
+		"logistic_condition": v.LogisticCondition,
+	})
+}
+
+// Pump is the control behavior of pumps and offshore pumps.
+type Pump struct {
+	// CircuitEnableDisable is new in Factorio 2.0.
+	CircuitEnableDisable     *bool      `json:"circuit_enable_disable,omitempty"`
+	CircuitCondition         *Condition `json:"circuit_condition,omitempty"`
+	ConnectToLogisticNetwork *bool      `json:"connect_to_logistic_network,omitempty"`
+	LogisticCondition        *Condition `json:"logistic_condition,omitempty"`
+	// SetFilter is new in Factorio 2.0, and only for pumps.
+	SetFilter *bool `json:"set_filter,omitempty"`
+}
+
+// Kind implements View.
+func (*Pump) Kind() Kind { return KindPump }
+
+func (v *Pump) validate() error {
+	return checkConditions(map[string]*Condition{
+		"circuit_condition":  v.CircuitCondition,
+		"logistic_condition": v.LogisticCondition,
+	})
+}
+
+// PowerSwitch is the control behavior of power switches.
+type PowerSwitch struct {
+	CircuitCondition         *Condition `json:"circuit_condition,omitempty"`
+	ConnectToLogisticNetwork *bool      `json:"connect_to_logistic_network,omitempty"`
+	LogisticCondition        *Condition `json:"logistic_condition,omitempty"`
+}
+
+// Kind implements View.
+func (*PowerSwitch) Kind() Kind { return KindPowerSwitch }
+
+func (v *PowerSwitch) validate() error {
+	return checkConditions(map[string]*Condition{
+		"circuit_condition":  v.CircuitCondition,
+		"logistic_condition": v.LogisticCondition,
+	})
+}
+
+// checkRange returns an error if the mode is set and not within [lo, hi].
+func checkRange(field string, mode *int, lo, hi int) error {
+	if mode != nil && (*mode < lo || *mode > hi) {
+		return fmt.Errorf("%s %d is not within %d to %d", field, *mode, lo, hi)
+	}
+	return nil
+}
+
+// checkConditions returns an error if any of the conditions, keyed by field
+// name, has an unknown comparator.
+func checkConditions(conditions map[string]*Condition) error {
+	for _, field := range sortedKeys(conditions) {
+		c := conditions[field]
+		if c == nil || c.Comparator == nil {
+			continue
+		}
+		if err := checkComparator(*c.Comparator); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return nil
+}
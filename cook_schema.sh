@@ -11,3 +11,7 @@ if [[ ! -e "${GOPATH}"/bin/go-jsonschema ]] ; then
 fi
 
 go-jsonschema --capitalization ID,JSON -e -p badc0de.net/pkg/factorioblueprint/schema/blueprint_schema blueprint.schema.json -o ${GOPATH}/src/badc0de.net/pkg/factorioblueprint/schema/blueprint_schema/blueprint.schema.json.go
+
+# Add the Extra field to every generated struct, which keeps fields unknown to
+# the schema.
+(cd "${GOPATH}"/src/badc0de.net/pkg/factorioblueprint && go run schema/add_extra_fields.go -file schema/blueprint_schema/blueprint.schema.json.go)
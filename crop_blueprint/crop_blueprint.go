@@ -0,0 +1,166 @@
+// Package crop_blueprint extracts part of a blueprint, picked by area, by
+// name or by any other test, as a blueprint of its own.
+//
+// This splits a large blueprint into pieces which can be reused on their own:
+//
+//	area := prototype_blueprint.NewBox(0, 0, 32, 32)
+//	piece, dropped, err := crop_blueprint.Extract(bp, crop_blueprint.Selection{Area: &area}, nil)
+//
+// Wires between the extracted entities are kept, and the wires to the rest of
+// the blueprint are returned. Schedules keep only the extracted locomotives.
+//
+// The public interface is unstable.
+package crop_blueprint // badc0de.net/pkg/factorioblueprint/crop_blueprint
+
+import (
+	"badc0de.net/pkg/factorioblueprint/compose_blueprint"
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/transform_blueprint"
+	"badc0de.net/pkg/factorioblueprint/wire_blueprint"
+)
+
+// Selection picks entities and tiles. Only those matching all fields which
+// are set are picked; the zero value picks everything.
+type Selection struct {
+	// Area picks the entities whose collision boxes overlap it, as the game
+	// does when selecting an area for a blueprint, and the tiles in it.
+	Area *prototype_blueprint.Box
+
+	// EntityNames picks the entities with one of these names, and TileNames
+	// the tiles. A nil list picks any name, an empty one none.
+	EntityNames, TileNames []string
+
+	// Entity picks the entities for which it returns true.
+	Entity func(e *blueprint_schema.Entity) bool
+
+	// Tile picks the tiles for which it returns true.
+	Tile func(t *blueprint_schema.Tile) bool
+}
+
+// Extract returns a new blueprint with the entities and tiles of bp picked by
+// sel, numbered from 1 and moved so that they are around the origin. bp is
+// not changed. Entities are looked up in prototypes for their collision
+// boxes; if prototypes is nil, prototype_blueprint.Vanilla is used.
+//
+// Wires which go from a picked entity to one which is not, or to one which
+// does not exist, are left out and returned, with the entity numbers of bp;
+// wires between entities which are not picked are just left out.
+// Wires are stored the way the version of bp stores them. Schedules without
+// any picked locomotive are left out.
+//
+// The blueprint is moved by an even number of tiles, keeping rails on the
+// rail grid. As its position in a grid cell is no longer known, its position
+// relative to the grid is removed.
+func Extract(bp *blueprint_schema.Blueprint, sel Selection, prototypes *prototype_blueprint.Registry) (*blueprint_schema.Blueprint, []wire_blueprint.Wire, error) {
+	if prototypes == nil {
+		prototypes = prototype_blueprint.Vanilla()
+	}
+	out, err := bp.Clone()
+	if err != nil {
+		return nil, nil, err
+	}
+	wires, err := wire_blueprint.FromBlueprint(out)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entityNames, tileNames := nameSet(sel.EntityNames), nameSet(sel.TileNames)
+	picked := make(map[int]bool)
+	entities := out.Entities[:0]
+	for i := range out.Entities {
+		e := &out.Entities[i]
+		if sel.Area != nil && !sel.Area.Overlaps(prototypes.EntityBox(out, e)) ||
+			sel.EntityNames != nil && !entityNames[e.Name] ||
+			sel.Entity != nil && !sel.Entity(e) {
+			continue
+		}
+		picked[e.EntityNumber] = true
+		entities = append(entities, *e)
+	}
+	out.Entities = entities
+
+	tiles := out.Tiles[:0]
+	for i := range out.Tiles {
+		t := &out.Tiles[i]
+		if sel.Area != nil && !sel.Area.Overlaps(tileBox(t)) ||
+			sel.TileNames != nil && !tileNames[t.Name] ||
+			sel.Tile != nil && !sel.Tile(t) {
+			continue
+		}
+		tiles = append(tiles, *t)
+	}
+	out.Tiles = tiles
+
+	var kept, dropped []wire_blueprint.Wire
+	for _, w := range wires {
+		switch a, b := picked[w.A.EntityNumber], picked[w.B.EntityNumber]; {
+		case a && b:
+			kept = append(kept, w)
+		case a || b:
+			dropped = append(dropped, w)
+		}
+	}
+	if out.Version.AtLeast(blueprint_schema.GameVersion2_0) {
+		wire_blueprint.SetWires(out, kept)
+	} else if err := wire_blueprint.SetConnections(out, kept); err != nil {
+		return nil, nil, err
+	}
+
+	schedules := out.Schedules[:0]
+	for _, s := range out.Schedules {
+		locomotives := s.Locomotives[:0]
+		for _, n := range s.Locomotives {
+			if picked[n] {
+				locomotives = append(locomotives, n)
+			}
+		}
+		if len(locomotives) > 0 {
+			s.Locomotives = locomotives
+			schedules = append(schedules, s)
+		}
+	}
+	out.Schedules = schedules
+
+	if err := compose_blueprint.Renumber(out, 1); err != nil {
+		return nil, nil, err
+	}
+	Recenter(out, prototypes)
+	out.PositionRelativeToGrid = nil
+	return out, dropped, nil
+}
+
+// Recenter moves bp so that the middle of the area it covers is near the
+// origin, by an even number of tiles to keep rails on the rail grid. If
+// prototypes is nil, prototype_blueprint.Vanilla is used.
+func Recenter(bp *blueprint_schema.Blueprint, prototypes *prototype_blueprint.Registry) {
+	if prototypes == nil {
+		prototypes = prototype_blueprint.Vanilla()
+	}
+	bounds, ok := prototypes.Bounds(bp)
+	if !ok {
+		return
+	}
+	center := blueprint_schema.FixedPosition{
+		X: (bounds.LeftTop.X + bounds.RightBottom.X) / 2,
+		Y: (bounds.LeftTop.Y + bounds.RightBottom.Y) / 2,
+	}.Tile()
+	center.X -= center.X & 1
+	center.Y -= center.Y & 1
+	transform_blueprint.Translate(bp, blueprint_schema.FixedPosition{}.Sub(center.Fixed()))
+}
+
+// nameSet returns the set of names.
+func nameSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// tileBox returns the area covered by a tile.
+func tileBox(t *blueprint_schema.Tile) prototype_blueprint.Box {
+	lt := t.Position.Fixed().Tile()
+	return prototype_blueprint.Box{LeftTop: lt.Fixed(), RightBottom: lt.Add(blueprint_schema.TilePosition{X: 1, Y: 1}).Fixed()}
+}
@@ -0,0 +1,225 @@
+package crop_blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// setupCropBlueprint creates a blueprint with a row of three power poles
+// wired together, a combinator wired to the last pole, two locomotives
+// sharing a schedule, and a tile under each pole.
+func setupCropBlueprint(version blueprint_schema.GameVersion) *blueprint_schema.Blueprint {
+	var bp blueprint_schema.Blueprint
+	data := `{"item": "blueprint", "version": ` + fmt.Sprint(uint64(version)) + `, "icons": [], "entities": [
+		{"entity_number": 1, "name": "small-electric-pole", "position": {"x": 10.5, "y": 10.5}},
+		{"entity_number": 2, "name": "small-electric-pole", "position": {"x": 14.5, "y": 10.5}},
+		{"entity_number": 3, "name": "small-electric-pole", "position": {"x": 18.5, "y": 10.5}},
+		{"entity_number": 4, "name": "constant-combinator", "position": {"x": 19.5, "y": 10.5}},
+		{"entity_number": 5, "name": "locomotive", "position": {"x": 12, "y": 16}},
+		{"entity_number": 6, "name": "locomotive", "position": {"x": 20, "y": 16}}
+	], "tiles": [
+		{"name": "concrete", "position": {"x": 10, "y": 10}},
+		{"name": "concrete", "position": {"x": 14, "y": 10}},
+		{"name": "stone-path", "position": {"x": 18, "y": 10}}
+	], "wires": [[1, 5, 2, 5], [2, 5, 3, 5], [3, 1, 4, 1], [1, 2, 2, 2]],
+	"schedules": [{"locomotives": [5, 6]}]}`
+	if err := json.Unmarshal([]byte(data), &bp); err != nil {
+		panic(err)
+	}
+	return &bp
+}
+
+// Example of cutting out the left part of a blueprint.
+func ExampleExtract() {
+	bp := setupCropBlueprint(blueprint_schema.GameVersion2_0)
+	area := prototype_blueprint.NewBox(8, 8, 16, 20)
+	piece, dropped, err := Extract(bp, Selection{Area: &area}, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, e := range piece.Entities {
+		fmt.Println(e.EntityNumber, e.Name, e.Position.X, e.Position.Y)
+	}
+	for _, t := range piece.Tiles {
+		fmt.Println(t.Name, t.Position.X, t.Position.Y)
+	}
+	fmt.Println(piece.Wires)
+	fmt.Println(piece.Schedules[0].Locomotives)
+	fmt.Println(dropped)
+
+	// Output:
+	// 1 small-electric-pole -1.5 -3.5
+	// 2 small-electric-pole 2.5 -3.5
+	// 3 locomotive 0 2
+	// concrete -2 -4
+	// concrete 2 -4
+	// [[1 5 2 5] [1 2 2 2]]
+	// [3]
+	// [[2, 5, 3, 5]]
+}
+
+// TestExtract_filters tests picking by names and by tests.
+func TestExtract_filters(t *testing.T) {
+	tcs := []struct {
+		name      string
+		sel       Selection
+		wantNames string
+		wantTiles int
+		wantWires string
+		wantDrop  int
+		wantSched int
+	}{
+		{
+			name:      "everything",
+			wantNames: "[small-electric-pole small-electric-pole small-electric-pole constant-combinator locomotive locomotive]",
+			wantTiles: 3,
+			wantWires: "[[1 5 2 5] [2 5 3 5] [3 1 4 1] [1 2 2 2]]",
+			wantSched: 1,
+		},
+		{
+			name:      "names",
+			sel:       Selection{EntityNames: []string{"small-electric-pole"}, TileNames: []string{"concrete"}},
+			wantNames: "[small-electric-pole small-electric-pole small-electric-pole]",
+			wantTiles: 2,
+			wantWires: "[[1 5 2 5] [2 5 3 5] [1 2 2 2]]",
+			wantDrop:  1,
+		},
+		{
+			name:      "entity names",
+			sel:       Selection{EntityNames: []string{"small-electric-pole"}},
+			wantNames: "[small-electric-pole small-electric-pole small-electric-pole]",
+			wantTiles: 3,
+			wantWires: "[[1 5 2 5] [2 5 3 5] [1 2 2 2]]",
+			wantDrop:  1,
+		},
+		{
+			name: "tests",
+			sel: Selection{
+				Entity: func(e *blueprint_schema.Entity) bool { return e.Position.X > 15 },
+				Tile:   func(t *blueprint_schema.Tile) bool { return false },
+			},
+			wantNames: "[small-electric-pole constant-combinator locomotive]",
+			wantWires: "[[1 1 2 1]]",
+			wantDrop:  1,
+			wantSched: 1,
+		},
+		{
+			name:      "nothing",
+			sel:       Selection{EntityNames: []string{}, TileNames: []string{}},
+			wantNames: "[]",
+			wantWires: "[]",
+		},
+	}
+	for _, tc := range tcs {
+		bp, dropped, err := Extract(setupCropBlueprint(blueprint_schema.GameVersion2_0), tc.sel, nil)
+		if err != nil {
+			t.Fatalf("Extract(%s) failed: %v", tc.name, err)
+		}
+		names := []string{}
+		for _, e := range bp.Entities {
+			names = append(names, e.Name)
+		}
+		if got := fmt.Sprint(names); got != tc.wantNames {
+			t.Errorf("Bad data for %s: want = '%s' got '%s'", tc.name, tc.wantNames, got)
+		}
+		if got := len(bp.Tiles); got != tc.wantTiles {
+			t.Errorf("Bad data for %s: want = '%d tiles' got '%d'", tc.name, tc.wantTiles, got)
+		}
+		if got := fmt.Sprint(bp.Wires); got != tc.wantWires {
+			t.Errorf("Bad data for %s: want = '%s' got '%s'", tc.name, tc.wantWires, got)
+		}
+		if got := len(dropped); got != tc.wantDrop {
+			t.Errorf("Bad data for %s: want = '%d dropped wires' got '%d'", tc.name, tc.wantDrop, got)
+		}
+		if got := len(bp.Schedules); got != tc.wantSched {
+			t.Errorf("Bad data for %s: want = '%d schedules' got '%d'", tc.name, tc.wantSched, got)
+		}
+	}
+}
+
+// TestExtract_connections tests that wires of Factorio 1.1 stay in the
+// connections and neighbours of entities, and that the blueprint given is
+// not changed.
+func TestExtract_connections(t *testing.T) {
+	bp := setupCropBlueprint(blueprint_schema.GameVersion1_1)
+	bp.Wires = nil
+	bp.Entities[0].Neighbours = []int{2}
+	bp.Entities[1].Neighbours = []int{1, 3}
+	bp.Entities[2].Neighbours = []int{2}
+	want, _ := json.Marshal(bp)
+
+	piece, dropped, err := Extract(bp, Selection{EntityNames: []string{"small-electric-pole"}, Entity: func(e *blueprint_schema.Entity) bool { return e.EntityNumber > 1 }}, nil)
+	if err != nil {
+		t.Fatalf("Extract() failed: %v", err)
+	}
+	got := fmt.Sprint(piece.Wires, piece.Entities[0].Neighbours, piece.Entities[1].Neighbours, dropped)
+	if want := "[] [2] [1] [[1, 5, 2, 5]]"; got != want {
+		t.Errorf("Bad data: want = '%s' got '%s'", want, got)
+	}
+
+	if got, _ := json.Marshal(bp); string(got) != string(want) {
+		t.Errorf("Bad data: want = '%s' got '%s'", want, got)
+	}
+}
+
+// TestExtract_powerSwitches tests that the copper wires of Factorio 1.1 power
+// switches keep their sides when everything is extracted.
+func TestExtract_powerSwitches(t *testing.T) {
+	var bp blueprint_schema.Blueprint
+	data := `{"item": "blueprint", "version": ` + fmt.Sprint(uint64(blueprint_schema.GameVersion1_1)) + `, "icons": [], "entities": [
+		{"entity_number": 1, "name": "small-electric-pole", "position": {"x": 0.5, "y": 0.5}},
+		{"entity_number": 2, "name": "power-switch", "position": {"x": 3, "y": 1}, "connections": {
+			"Cu0": [{"entity_id": 1, "wire_id": 0}, {"entity_id": 3, "wire_id": 1}],
+			"Cu1": [{"entity_id": 3, "wire_id": 0}]}},
+		{"entity_number": 3, "name": "power-switch", "position": {"x": 7, "y": 1}, "connections": {
+			"Cu0": [{"entity_id": 2, "wire_id": 1}],
+			"Cu1": [{"entity_id": 2, "wire_id": 0}, {"entity_id": 4, "wire_id": 0}]}},
+		{"entity_number": 4, "name": "small-electric-pole", "position": {"x": 9.5, "y": 0.5}}
+	]}`
+	if err := json.Unmarshal([]byte(data), &bp); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	piece, dropped, err := Extract(&bp, Selection{}, nil)
+	if err != nil {
+		t.Fatalf("Extract() failed: %v", err)
+	}
+	if len(dropped) != 0 {
+		t.Errorf("Bad data: want = 'no dropped wires' got '%v'", dropped)
+	}
+	for i := range bp.Entities {
+		want, _ := json.Marshal(bp.Entities[i].Connections)
+		got, _ := json.Marshal(piece.Entities[i].Connections)
+		if string(got) != string(want) {
+			t.Errorf("Bad data for entity %d: want = '%s' got '%s'", i+1, want, got)
+		}
+	}
+}
+
+// TestRecenter tests that blueprints are moved by an even number of tiles.
+func TestRecenter(t *testing.T) {
+	tcs := []struct {
+		x, y         float64
+		wantX, wantY float64
+	}{
+		{0.5, 0.5, 0.5, 0.5},
+		{1.5, 1.5, 1.5, 1.5},
+		{2.5, 3.5, 0.5, 1.5},
+		{-3.5, -2.5, 0.5, 1.5},
+		{101, 7, 1, 1},
+	}
+	for _, tc := range tcs {
+		bp := &blueprint_schema.Blueprint{Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "wooden-chest", Position: blueprint_schema.Position{X: tc.x, Y: tc.y}},
+		}}
+		Recenter(bp, nil)
+		if got := bp.Entities[0].Position; got.X != tc.wantX || got.Y != tc.wantY {
+			t.Errorf("Bad data for %v, %v: want = '%v, %v' got '%v, %v'", tc.x, tc.y, tc.wantX, tc.wantY, got.X, got.Y)
+		}
+	}
+}
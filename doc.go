@@ -21,4 +21,45 @@
 //     $ blueprintread -fmt=raw_json -file read_blueprint/simple.txt
 //     $ blueprintread -fmt=yaml -file read_blueprint/simple.txt
 //
+// The game version the blueprint, or everything in a book, was created with
+// is printed with:
+//
+//     $ blueprintread -fmt=version -file read_blueprint/simple.txt
+//
+// A blueprint string, or a JSON document such as one in a blueprint repository,
+// is checked against the schema with the following, which prints every
+// violation as a JSON pointer and the rule which failed, and exits with status 1
+// if there are any:
+//
+//     $ blueprintread -fmt=validate -file read_blueprint/simple.txt
+//
+// Blueprint strings mixed into other text, such as a chat log, a markdown file
+// or a forum post, are found with -extract, which prints the byte offset and
+// the string of each, separated by a tab:
+//
+//     $ blueprintread -extract -file chat.log | cut -f2
+//
+// A file with one string per line, such as an archive of stored strings, is
+// decoded in parallel with -fmt=ndjson, which prints one JSON object per line
+// with the line number and either the JSON or the error:
+//
+//     $ blueprintread -fmt=ndjson -workers 8 -file strings.txt > blueprints.ndjson
+//
+// The companion writer turns JSON or YAML back into a blueprint string:
+//
+//     $ go install badc0de.net/pkg/factorioblueprint/cmd/blueprintwrite@latest
+//     $ blueprintread -fmt=yaml -file read_blueprint/simple.txt > simple.yaml
+//     $ blueprintwrite -file simple.yaml
+//
+// With -canonical, the same design always yields the same string, so strings
+// can be committed and compared by hash:
+//
+//     $ blueprintwrite -canonical -file simple.yaml
+//
+// With -shortest, fields equal to the game defaults are dropped and every
+// compression level is tried, to write the shortest string; the size saved is
+// printed to stderr:
+//
+//     $ blueprintwrite -shortest -file simple.yaml
+//
 package factorioblueprint // badc0de.net/pkg/factorioblueprint
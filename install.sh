@@ -1,3 +1,4 @@
 #!/bin/bash
 go install badc0de.net/pkg/factorioblueprint/cmd/blueprintread
+go install badc0de.net/pkg/factorioblueprint/cmd/blueprintwrite
 
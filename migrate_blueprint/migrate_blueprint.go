@@ -0,0 +1,315 @@
+// Package migrate_blueprint rewrites blueprints, books and planners created
+// with one version of the game for another version, such as a Factorio 1.1
+// library for Factorio 2.0.
+//
+// When going from 1.1 to 2.0:
+//
+//   - directions are doubled, as 2.0 has 16 directions instead of 8,
+//   - connections and neighbours of entities become the wires array,
+//   - request filters of logistic containers and filters of constant
+//     combinators become logistic sections,
+//   - vanilla entities which were renamed, such as logistic chests and filter
+//     inserters, get their new names,
+//   - and the version is updated.
+//
+// Going from 2.0 to 1.1 does the reverse, and also turns insert plans back
+// into item requests and drops qualities. Anything which cannot be translated
+// is left as it is, or dropped, and reported as an Issue.
+//
+// The public interface is unstable.
+package migrate_blueprint // badc0de.net/pkg/factorioblueprint/migrate_blueprint
+
+import (
+	"fmt"
+	"sort"
+
+	"badc0de.net/pkg/factorioblueprint/book_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/wire_blueprint"
+)
+
+// Issue is something which could not be translated to the target version.
+type Issue struct {
+	// Path of the blueprint in the book, empty if it is not in a book.
+	Path book_blueprint.Path
+	// EntityNumber of the entity, or 0 if the issue is not about an entity.
+	EntityNumber int
+	Message      string
+}
+
+// String returns the issue in the form "1:Smelting/0: entity 3: message".
+func (i Issue) String() string {
+	s := i.Message
+	if i.EntityNumber != 0 {
+		s = fmt.Sprintf("entity %d: %s", i.EntityNumber, s)
+	}
+	if len(i.Path) > 0 {
+		s = fmt.Sprintf("%v: %s", i.Path, s)
+	}
+	return s
+}
+
+// Migrate rewrites the blueprint, book or planner in m for the game version
+// to, including everything nested in books. It returns what could not be
+// translated. An error is returned only if m is malformed, in which case m may
+// have been partially migrated.
+func Migrate(m *blueprint_schema.BlueprintSchemaJSON, to blueprint_schema.GameVersion) ([]Issue, error) {
+	if m.BlueprintBook != nil {
+		stampBooks(m.BlueprintBook, to)
+	}
+
+	var issues []Issue
+	err := book_blueprint.Walk(m, func(leaf book_blueprint.Leaf) error {
+		switch {
+		case leaf.Blueprint != nil:
+			leafIssues, err := MigrateBlueprint(leaf.Blueprint, to)
+			if err != nil {
+				if len(leaf.Path) > 0 {
+					err = fmt.Errorf("%v: %w", leaf.Path, err)
+				}
+				return err
+			}
+			for _, issue := range leafIssues {
+				issue.Path = leaf.Path
+				issues = append(issues, issue)
+			}
+		case leaf.UpgradePlanner != nil:
+			leaf.UpgradePlanner.Version = to
+		case leaf.DeconstructionPlanner != nil:
+			leaf.DeconstructionPlanner.Version = to
+		default:
+			issues = append(issues, Issue{Path: leaf.Path, Message: "unknown book entry left as it is"})
+		}
+		return nil
+	})
+	return issues, err
+}
+
+// stampBooks sets the version of the book and all books nested in it.
+func stampBooks(book *blueprint_schema.BlueprintBook, to blueprint_schema.GameVersion) {
+	book.Version = to
+	for _, entry := range book.Blueprints {
+		if entry.BlueprintBook != nil {
+			stampBooks(entry.BlueprintBook, to)
+		}
+	}
+}
+
+// MigrateBlueprint rewrites a single blueprint for the game version to. It
+// returns what could not be translated. The Path of the issues is empty.
+func MigrateBlueprint(bp *blueprint_schema.Blueprint, to blueprint_schema.GameVersion) ([]Issue, error) {
+	m := &migration{}
+
+	from := bp.Version
+	if from == 0 {
+		m.report(0, "no version, assuming %v", blueprint_schema.GameVersion1_1)
+		from = blueprint_schema.GameVersion1_1
+	}
+
+	switch {
+	case !from.AtLeast(blueprint_schema.GameVersion2_0) && to.AtLeast(blueprint_schema.GameVersion2_0):
+		if err := m.upgrade2_0(bp); err != nil {
+			return nil, err
+		}
+	case from.AtLeast(blueprint_schema.GameVersion2_0) && !to.AtLeast(blueprint_schema.GameVersion2_0):
+		if err := m.downgrade2_0(bp); err != nil {
+			return nil, err
+		}
+	}
+
+	bp.Version = to
+	return m.issues, nil
+}
+
+// migration collects the issues of a single blueprint.
+type migration struct {
+	issues []Issue
+}
+
+func (m *migration) report(entityNumber int, format string, args ...interface{}) {
+	m.issues = append(m.issues, Issue{EntityNumber: entityNumber, Message: fmt.Sprintf(format, args...)})
+}
+
+// renamed2_0 are vanilla entities and items which were renamed in 2.0.
+var renamed2_0 = map[string]string{
+	"logistic-chest-active-provider":  "active-provider-chest",
+	"logistic-chest-passive-provider": "passive-provider-chest",
+	"logistic-chest-storage":          "storage-chest",
+	"logistic-chest-buffer":           "buffer-chest",
+	"logistic-chest-requester":        "requester-chest",
+	"stack-inserter":                  "bulk-inserter",
+	// Filter inserters became regular inserters with use_filters set.
+	"filter-inserter":       "fast-inserter",
+	"stack-filter-inserter": "bulk-inserter",
+}
+
+// renamed1_1 are the 1.1 names of entities and items renamed in 2.0, except
+// for filter inserters, which depend on use_filters.
+var renamed1_1 = map[string]string{}
+
+func init() {
+	for old, name := range renamed2_0 {
+		if old != "filter-inserter" && old != "stack-filter-inserter" {
+			renamed1_1[name] = old
+		}
+	}
+}
+
+// upgrade2_0 rewrites a 1.1 blueprint for 2.0.
+func (m *migration) upgrade2_0(bp *blueprint_schema.Blueprint) error {
+	wires, err := wire_blueprint.FromBlueprint(bp)
+	if err != nil {
+		return err
+	}
+
+	for i := range bp.Entities {
+		e := &bp.Entities[i]
+
+		direction := blueprint_schema.DirectionNorth
+		if e.Direction != nil {
+			direction = blueprint_schema.DirectionFromRaw(*e.Direction, blueprint_schema.GameVersion1_1)
+		}
+		isDiagonal := direction.IsDiagonal()
+		switch {
+		case e.Name == "curved-rail" || (e.Name == "straight-rail" && isDiagonal):
+			m.report(e.EntityNumber, "%s has no 2.0 equivalent, left as it is", e.Name)
+		case e.Name == "filter-inserter" || e.Name == "stack-filter-inserter":
+			useFilters := true
+			e.UseFilters = &useFilters
+		}
+		if name, ok := renamed2_0[e.Name]; ok {
+			e.Name = name
+		}
+		if e.Direction != nil {
+			d, _ := direction.Raw(blueprint_schema.GameVersion2_0)
+			e.Direction = &d
+		}
+
+		if e.RequestFilters != nil && e.RequestFilters.Filters != nil {
+			e.RequestFilters = &blueprint_schema.RequestFilters{Sections: m.requestSections(e)}
+		}
+		if cb := e.ControlBehavior; cb != nil && cb.Filters != nil && cb.Sections == nil {
+			cb.Sections = m.constantSections(e)
+			cb.Filters = nil
+		}
+		if e.Items != nil && len(e.Items.Requests) > 0 {
+			m.report(e.EntityNumber, "item requests left in the 1.1 form")
+		}
+	}
+
+	renameIcons(bp.Icons, renamed2_0)
+	if len(bp.Schedules) > 0 {
+		m.report(0, "train schedules left in the 1.1 form")
+	}
+
+	wire_blueprint.SetWires(bp, wires)
+	return nil
+}
+
+// downgrade2_0 rewrites a 2.0 blueprint for 1.1.
+func (m *migration) downgrade2_0(bp *blueprint_schema.Blueprint) error {
+	wires, err := wire_blueprint.FromBlueprint(bp)
+	if err != nil {
+		return err
+	}
+
+	for i := range bp.Entities {
+		e := &bp.Entities[i]
+
+		useFilters := e.UseFilters != nil && *e.UseFilters
+		switch {
+		case e.Name == "fast-inserter" && useFilters:
+			e.Name = "filter-inserter"
+		case e.Name == "bulk-inserter" && useFilters:
+			e.Name = "stack-filter-inserter"
+		default:
+			if name, ok := renamed1_1[e.Name]; ok {
+				e.Name = name
+			}
+		}
+		e.UseFilters = nil
+
+		if e.Direction != nil {
+			d, ok := blueprint_schema.DirectionFromRaw(*e.Direction, blueprint_schema.GameVersion2_0).Raw(blueprint_schema.GameVersion1_1)
+			if !ok {
+				m.report(e.EntityNumber, "direction %d has no 1.1 equivalent, rounded down", *e.Direction)
+			}
+			e.Direction = &d
+		}
+
+		m.dropQuality(e)
+		if e.Items != nil && e.Items.InsertPlans != nil {
+			e.Items = &blueprint_schema.EntityItems{Requests: m.itemRequests(e)}
+		}
+		if e.RequestFilters != nil && e.RequestFilters.Sections != nil {
+			e.RequestFilters = &blueprint_schema.RequestFilters{Filters: m.requestFilters(e)}
+		}
+		if cb := e.ControlBehavior; cb != nil && cb.Sections != nil && cb.Filters == nil {
+			cb.Filters = m.constantFilters(e)
+			cb.Sections = nil
+		}
+	}
+
+	renameIcons(bp.Icons, renamed1_1)
+
+	if err := wire_blueprint.SetConnections(bp, wires); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dropQuality removes the qualities of the entity, its recipe and its
+// filters, which 1.1 has no equivalent for.
+func (m *migration) dropQuality(e *blueprint_schema.Entity) {
+	if q := blueprint_schema.QualityOf(e.Quality); !q.IsNormal() {
+		m.report(e.EntityNumber, "quality %s of the entity dropped", q)
+	}
+	e.Quality = nil
+	if q := blueprint_schema.QualityOf(e.RecipeQuality); !q.IsNormal() {
+		m.report(e.EntityNumber, "quality %s of the recipe dropped", q)
+	}
+	e.RecipeQuality = nil
+	for i := range e.Filters {
+		f := &e.Filters[i]
+		if q := blueprint_schema.QualityOf(f.Quality); !q.IsNormal() {
+			m.report(e.EntityNumber, "quality %s of filter %s dropped", q, f.Name)
+		}
+		f.Quality = nil
+		f.Comparator = nil
+	}
+}
+
+// itemRequests turns the 2.0 insert plans of an entity into 1.1 item
+// requests. Where the items go is up to the game in 1.1.
+func (m *migration) itemRequests(e *blueprint_schema.Entity) blueprint_schema.ItemRequest {
+	counts := e.Items.Counts()
+	items := make([]blueprint_schema.ItemWithQuality, 0, len(counts))
+	for item := range counts {
+		items = append(items, item)
+	}
+	// Report in a stable order.
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].Quality < items[j].Quality
+	})
+
+	requests := blueprint_schema.ItemRequest{}
+	for _, item := range items {
+		if !item.Quality.IsNormal() {
+			m.report(e.EntityNumber, "quality %s of requested %s dropped", item.Quality, item.Name)
+		}
+		requests[item.Name] += counts[item]
+	}
+	return requests
+}
+
+// renameIcons renames the items shown as icons.
+func renameIcons(icons []blueprint_schema.Icon, renamed map[string]string) {
+	for i := range icons {
+		if name, ok := renamed[icons[i].Signal.Name]; ok {
+			icons[i].Signal.Name = name
+		}
+	}
+}
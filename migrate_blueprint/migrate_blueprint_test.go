@@ -0,0 +1,237 @@
+package migrate_blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+var version2_0 = blueprint_schema.NewGameVersion(2, 0, 28, 0)
+
+func setupLibrary(t testing.TB) *blueprint_schema.BlueprintSchemaJSON {
+	data, err := ioutil.ReadFile("testdata/library_1_1.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	var m blueprint_schema.BlueprintSchemaJSON
+	if err := blueprint_schema.UnmarshalLossless(data, &m); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	return &m
+}
+
+// Example of upgrading a 1.1 library for 2.0.
+func ExampleMigrate() {
+	data, err := ioutil.ReadFile("testdata/library_1_1.json")
+	if err != nil {
+		panic(err)
+	}
+	var m blueprint_schema.BlueprintSchemaJSON
+	if err := blueprint_schema.UnmarshalLossless(data, &m); err != nil {
+		panic(err)
+	}
+
+	issues, err := Migrate(&m, blueprint_schema.NewGameVersion(2, 0, 28, 0))
+	if err != nil {
+		panic(err)
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	fmt.Println(m.BlueprintBook.Version)
+	fmt.Println(m.BlueprintBook.Blueprints[0].Blueprint.Wires)
+
+	// Output:
+	// 0:Mall: entity 5: curved-rail has no 2.0 equivalent, left as it is
+	// 2.0.28.0
+	// [[3 1 4 1]]
+}
+
+func TestMigrate_upgrade(t *testing.T) {
+	m := setupLibrary(t)
+	if _, err := Migrate(m, version2_0); err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+
+	bp := m.BlueprintBook.Blueprints[0].Blueprint
+	if bp.Version != version2_0 || m.BlueprintBook.Blueprints[1].UpgradePlanner.Version != version2_0 {
+		t.Fatalf("Bad data: want version = %v got '%v'", version2_0, bp.Version)
+	}
+	if name := bp.Icons[0].Signal.Name; name != "requester-chest" {
+		t.Fatalf("Bad data: want icon = 'requester-chest' got '%v'", name)
+	}
+
+	chest := bp.Entities[0]
+	if chest.Name != "requester-chest" {
+		t.Fatalf("Bad data: want name = 'requester-chest' got '%v'", chest.Name)
+	}
+	if chest.RequestFilters == nil || chest.RequestFilters.Sections == nil || len(chest.RequestFilters.Sections.Sections) != 1 {
+		t.Fatalf("Bad data: want 1 logistic section got '%+v'", chest.RequestFilters)
+	}
+	if f := chest.RequestFilters.Sections.Sections[0].Filters[1]; f.Name != "copper-plate" || f.Count != 50 || f.Index != 2 || *f.Quality != "normal" || f.Comparator != "=" {
+		t.Fatalf("Bad data: want copper-plate request got '%+v'", f)
+	}
+
+	inserter := bp.Entities[1]
+	if inserter.Name != "fast-inserter" || inserter.UseFilters == nil || !*inserter.UseFilters {
+		t.Fatalf("Bad data: want fast-inserter using filters got '%v' '%v'", inserter.Name, inserter.UseFilters)
+	}
+	if *inserter.Direction != 8 {
+		t.Fatalf("Bad data: want direction = 8 got '%v'", *inserter.Direction)
+	}
+
+	combinator := bp.Entities[3]
+	if combinator.Connections != nil {
+		t.Fatalf("Bad data: want no connections got '%+v'", combinator.Connections)
+	}
+	cb := combinator.ControlBehavior
+	if cb.Filters != nil || cb.Sections == nil {
+		t.Fatalf("Bad data: want sections instead of filters got '%+v'", cb)
+	}
+	if f := cb.Sections.Sections[0].Filters[0]; f.Name != "signal-A" || f.Type == nil || *f.Type != "virtual" || f.Count != 5 {
+		t.Fatalf("Bad data: want signal-A filter got '%+v'", f)
+	}
+}
+
+// TestMigrate_notLossless checks that blueprints decoded with encoding/json,
+// which drops fields unknown to the schema, are migrated alike.
+func TestMigrate_notLossless(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/library_1_1.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	var m blueprint_schema.BlueprintSchemaJSON
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	if _, err := Migrate(&m, version2_0); err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+
+	cb := m.BlueprintBook.Blueprints[0].Blueprint.Entities[3].ControlBehavior
+	if cb.Sections == nil || len(cb.Sections.Sections[0].Filters) != 1 {
+		t.Fatalf("Bad data: want 1 filter got '%+v'", cb.Sections)
+	}
+	if f := cb.Sections.Sections[0].Filters[0]; f.Name != "signal-A" || f.Type == nil || *f.Type != "virtual" || f.Count != 5 {
+		t.Fatalf("Bad data: want signal-A filter got '%+v'", f)
+	}
+
+	if _, err := Migrate(&m, blueprint_schema.GameVersion1_1); err != nil {
+		t.Fatalf("Migrate() to 1.1 failed: %v", err)
+	}
+	f := m.BlueprintBook.Blueprints[0].Blueprint.Entities[3].ControlBehavior.Filters
+	if len(f) != 1 || f[0].Signal == nil || f[0].Signal.Name != "signal-A" || *f[0].Signal.Type != "virtual" {
+		t.Fatalf("Bad data: want signal-A filter got '%+v'", f)
+	}
+}
+
+// sortedKeys returns the JSON with the keys of every object sorted, keeping
+// the text of numbers.
+func sortedKeys(t *testing.T, data []byte) []byte {
+	t.Helper()
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to encode JSON: %v", err)
+	}
+	return b
+}
+
+// TestMigrate_roundTrip checks that upgrading and downgrading again restores
+// everything which can be translated.
+func TestMigrate_roundTrip(t *testing.T) {
+	want := setupLibrary(t)
+	m := setupLibrary(t)
+	if _, err := Migrate(m, version2_0); err != nil {
+		t.Fatalf("Migrate() to 2.0 failed: %v", err)
+	}
+	issues, err := Migrate(m, want.BlueprintBook.Version)
+	if err != nil {
+		t.Fatalf("Migrate() to 1.1 failed: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("Bad data: want no issues got '%v'", issues)
+	}
+
+	wantBP, gotBP := want.BlueprintBook.Blueprints[0].Blueprint, m.BlueprintBook.Blueprints[0].Blueprint
+	wantJSON, err := blueprint_schema.MarshalLossless(wantBP)
+	if err != nil {
+		t.Fatalf("Failed to encode JSON: %v", err)
+	}
+	gotJSON, err := blueprint_schema.MarshalLossless(gotBP)
+	if err != nil {
+		t.Fatalf("Failed to encode JSON: %v", err)
+	}
+	// Filters rebuilt by the migration have their keys in the order of the
+	// schema rather than that of the file, so compare with sorted keys.
+	gotJSON, wantJSON = sortedKeys(t, gotJSON), sortedKeys(t, wantJSON)
+	if string(gotJSON) != string(wantJSON) {
+		t.Fatalf("Bad data: blueprint changed after round trip:\ngot:  %s\nwant: %s", gotJSON, wantJSON)
+	}
+}
+
+func TestMigrate_downgradeIssues(t *testing.T) {
+	quality, legendary, yes := blueprint_schema.QualityRare, blueprint_schema.QualityLegendary, true
+	dir := 3
+	bp := &blueprint_schema.Blueprint{
+		Item:    "blueprint",
+		Version: version2_0,
+		Entities: []blueprint_schema.Entity{{
+			EntityNumber: 1,
+			Name:         "requester-chest",
+			Direction:    &dir,
+			RequestFilters: &blueprint_schema.RequestFilters{Sections: &blueprint_schema.LogisticSections{
+				Sections: []blueprint_schema.Section{
+					{Index: 1, Filters: []blueprint_schema.Filter{{Index: 1, Name: "iron-plate", Quality: &quality, Comparator: "=", Count: 10}}},
+					{Index: 2, Filters: []blueprint_schema.Filter{{Index: 1, Name: "copper-plate", Comparator: "=", Count: 10}}},
+				},
+				TrashNotRequested: &yes,
+			}},
+		}, {
+			EntityNumber: 2,
+			Name:         "assembling-machine-3",
+			Quality:      &legendary,
+			Items: &blueprint_schema.EntityItems{InsertPlans: []blueprint_schema.InsertPlan{{
+				ID: blueprint_schema.ItemIDAndQuality{Name: "speed-module", Quality: &quality},
+				Items: blueprint_schema.ItemInventoryPositions{InInventory: []blueprint_schema.InventoryPosition{
+					{Inventory: 4, Stack: 0},
+					{Inventory: 4, Stack: 1},
+				}},
+			}}},
+		}},
+	}
+
+	issues, err := MigrateBlueprint(bp, blueprint_schema.GameVersion1_1)
+	if err != nil {
+		t.Fatalf("MigrateBlueprint() failed: %v", err)
+	}
+	want := []string{
+		"entity 1: direction 3 has no 1.1 equivalent, rounded down",
+		"entity 1: 2 logistic sections merged into one",
+		"entity 1: quality rare of iron-plate dropped",
+		"entity 1: trash_not_requested dropped",
+		"entity 2: quality legendary of the entity dropped",
+		"entity 2: quality rare of requested speed-module dropped",
+	}
+	if fmt.Sprint(issues) != fmt.Sprint(want) {
+		t.Fatalf("Bad data: want = '%v' got '%v'", want, issues)
+	}
+	if f := bp.Entities[0].RequestFilters.Filters; len(f) != 2 || *f[1].Name != "copper-plate" || *f[1].Index != 2 {
+		t.Fatalf("Bad data: want 2 request filters got '%+v'", f)
+	}
+	if r := bp.Entities[1].Items.Requests; len(r) != 1 || r["speed-module"] != 2 {
+		t.Fatalf("Bad data: want 2 speed-module got '%v'", r)
+	}
+	if bp.Entities[0].Name != "logistic-chest-requester" {
+		t.Fatalf("Bad data: want name = 'logistic-chest-requester' got '%v'", bp.Entities[0].Name)
+	}
+}
@@ -0,0 +1,119 @@
+package migrate_blueprint
+
+import (
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// equalComparator is the comparator 2.0 filters of a single quality use.
+const equalComparator = "="
+
+// requestSections turns the 1.1 request filters of a logistic container into
+// a single 2.0 logistic section.
+func (m *migration) requestSections(e *blueprint_schema.Entity) *blueprint_schema.LogisticSections {
+	section := blueprint_schema.Section{Index: 1, Filters: []blueprint_schema.Filter{}}
+	for i, f := range e.RequestFilters.Filters {
+		if f.Name == nil {
+			m.report(e.EntityNumber, "request filter %d has no name, dropped", i)
+			continue
+		}
+		section.Filters = append(section.Filters, newFilter(f.Index, len(section.Filters), "", *f.Name, f.Count))
+	}
+	return &blueprint_schema.LogisticSections{Sections: []blueprint_schema.Section{section}}
+}
+
+// constantSections turns the 1.1 filters of a constant combinator, which hold
+// a signal, into a single 2.0 logistic section.
+func (m *migration) constantSections(e *blueprint_schema.Entity) *blueprint_schema.LogisticSections {
+	section := blueprint_schema.Section{Index: 1, Filters: []blueprint_schema.Filter{}}
+	for i, f := range e.ControlBehavior.Filters {
+		if f.Signal == nil || f.Signal.Name == "" {
+			m.report(e.EntityNumber, "constant combinator filter %d has no signal, dropped", i)
+			continue
+		}
+		var signalType string
+		if f.Signal.Type != nil {
+			signalType = string(*f.Signal.Type)
+		}
+		section.Filters = append(section.Filters, newFilter(f.Index, len(section.Filters), signalType, f.Signal.Name, f.Count))
+	}
+	return &blueprint_schema.LogisticSections{Sections: []blueprint_schema.Section{section}}
+}
+
+// newFilter returns a 2.0 filter for an item or signal of normal quality. If
+// index is not set, the filter is placed after the n filters before it.
+func newFilter(index *int, n int, signalType, name string, count *int) blueprint_schema.Filter {
+	quality := blueprint_schema.QualityNormal
+	f := blueprint_schema.Filter{
+		Index:      n + 1,
+		Name:       name,
+		Quality:    &quality,
+		Comparator: equalComparator,
+	}
+	if index != nil {
+		f.Index = *index
+	}
+	if signalType != "" && signalType != "item" {
+		f.Type = &signalType
+	}
+	if count != nil {
+		f.Count = *count
+	}
+	return f
+}
+
+// requestFilters turns the 2.0 logistic sections of a logistic container
+// into 1.1 request filters. All sections are merged, and the filters are
+// numbered again.
+func (m *migration) requestFilters(e *blueprint_schema.Entity) []blueprint_schema.LogisticFilter {
+	filters := []blueprint_schema.LogisticFilter{}
+	for _, f := range m.flattenSections(e, e.RequestFilters.Sections) {
+		if f.Type != nil && *f.Type != "item" {
+			m.report(e.EntityNumber, "request of %s %s dropped", *f.Type, f.Name)
+			continue
+		}
+		index, name, count := len(filters)+1, f.Name, f.Count
+		filters = append(filters, blueprint_schema.LogisticFilter{Index: &index, Name: &name, Count: &count})
+	}
+	if s := e.RequestFilters.Sections.TrashNotRequested; s != nil && *s {
+		m.report(e.EntityNumber, "trash_not_requested dropped")
+	}
+	return filters
+}
+
+// constantFilters turns the 2.0 logistic sections of a constant combinator
+// into 1.1 filters. All sections are merged, and the filters are numbered
+// again.
+func (m *migration) constantFilters(e *blueprint_schema.Entity) []blueprint_schema.BlueprintLogisticFilter {
+	filters := []blueprint_schema.BlueprintLogisticFilter{}
+	for _, f := range m.flattenSections(e, e.ControlBehavior.Sections) {
+		signalType := blueprint_schema.SignalIDTypeItem
+		if f.Type != nil {
+			signalType = blueprint_schema.SignalIDType(*f.Type)
+		}
+		index, count := len(filters)+1, f.Count
+		filters = append(filters, blueprint_schema.BlueprintLogisticFilter{
+			Index:  &index,
+			Count:  &count,
+			Signal: &blueprint_schema.SignalID{Type: &signalType, Name: f.Name},
+		})
+	}
+	return filters
+}
+
+// flattenSections returns the filters of all sections, reporting what 1.1
+// has no equivalent for.
+func (m *migration) flattenSections(e *blueprint_schema.Entity, sections *blueprint_schema.LogisticSections) []blueprint_schema.Filter {
+	if len(sections.Sections) > 1 {
+		m.report(e.EntityNumber, "%d logistic sections merged into one", len(sections.Sections))
+	}
+	var filters []blueprint_schema.Filter
+	for _, s := range sections.Sections {
+		for _, f := range s.Filters {
+			if !blueprint_schema.QualityOf(f.Quality).IsNormal() {
+				m.report(e.EntityNumber, "quality %s of %s dropped", *f.Quality, f.Name)
+			}
+			filters = append(filters, f)
+		}
+	}
+	return filters
+}
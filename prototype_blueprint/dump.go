@@ -0,0 +1,194 @@
+package prototype_blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// typeDirections is the number of directions entities of a prototype type can
+// face. Types not listed cannot be rotated.
+var typeDirections = map[string]int{
+	"transport-belt":              4,
+	"underground-belt":            4,
+	"splitter":                    4,
+	"lane-splitter":               4,
+	"loader":                      4,
+	"loader-1x1":                  4,
+	"linked-belt":                 4,
+	"inserter":                    4,
+	"storage-tank":                4,
+	"pipe-to-ground":              4,
+	"pump":                        4,
+	"offshore-pump":               4,
+	"valve":                       4,
+	"boiler":                      4,
+	"generator":                   4,
+	"burner-generator":            4,
+	"mining-drill":                4,
+	"assembling-machine":          4,
+	"furnace":                     4,
+	"arithmetic-combinator":       4,
+	"decider-combinator":          4,
+	"selector-combinator":         4,
+	"constant-combinator":         4,
+	"display-panel":               4,
+	"simple-entity-with-owner":    4,
+	"straight-rail":               8,
+	"half-diagonal-rail":          8,
+	"curved-rail":                 8,
+	"curved-rail-a":               16,
+	"curved-rail-b":               16,
+	"rail-ramp":                   4,
+	"rail-support":                8,
+	"elevated-straight-rail":      8,
+	"elevated-half-diagonal-rail": 8,
+	"elevated-curved-rail-a":      16,
+	"elevated-curved-rail-b":      16,
+	"train-stop":                  4,
+	"rail-signal":                 16,
+	"rail-chain-signal":           16,
+	"gate":                        4,
+	"fluid-turret":                4,
+	"artillery-turret":            4,
+	"fusion-reactor":              4,
+	"fusion-generator":            4,
+	"asteroid-collector":          4,
+}
+
+// dumpPrototype are the fields of a prototype in data-raw-dump.json which the
+// registry needs.
+type dumpPrototype struct {
+	CollisionBox json.RawMessage `json:"collision_box"`
+	TileWidth    *int            `json:"tile_width"`
+	TileHeight   *int            `json:"tile_height"`
+	Flags        []string        `json:"flags"`
+}
+
+// LoadDataRawDump adds the entity prototypes of a data-raw-dump.json to the
+// registry, replacing those of the same name. The game writes the file to its
+// script-output directory when started with --dump-data, with the prototypes
+// of the mods enabled at the time.
+//
+// Prototypes with a collision box are taken to be entities. Their size is
+// tile_width and tile_height if given, otherwise the collision box rounded up
+// to whole tiles, as the game does.
+func (r *Registry) LoadDataRawDump(rd io.Reader) error {
+	var dump map[string]map[string]json.RawMessage
+	if err := json.NewDecoder(rd).Decode(&dump); err != nil {
+		return fmt.Errorf("reading data-raw-dump: %w", err)
+	}
+
+	// Go through the types in order, so an entity named the same in two
+	// types always ends up the same.
+	types := make([]string, 0, len(dump))
+	for typ := range dump {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	for _, typ := range types {
+		for name, raw := range dump[typ] {
+			var dp dumpPrototype
+			if err := json.Unmarshal(raw, &dp); err != nil {
+				// Not every prototype is an object, such as in
+				// "utility-constants"; those are no entities.
+				continue
+			}
+			if dp.CollisionBox == nil {
+				continue
+			}
+			box, err := parseDumpBox(dp.CollisionBox)
+			if err != nil {
+				return fmt.Errorf("%s %q: collision_box: %w", typ, name, err)
+			}
+			p := Prototype{
+				Name:         name,
+				Type:         typ,
+				CollisionBox: box,
+				Directions:   typeDirections[typ],
+			}
+			w, h := box.Size()
+			p.Width = int(math.Ceil(w.Float64()))
+			p.Height = int(math.Ceil(h.Float64()))
+			if dp.TileWidth != nil {
+				p.Width = *dp.TileWidth
+			}
+			if dp.TileHeight != nil {
+				p.Height = *dp.TileHeight
+			}
+			if p.Width < 1 {
+				p.Width = 1
+			}
+			if p.Height < 1 {
+				p.Height = 1
+			}
+			if p.Directions == 0 || hasFlag(dp.Flags, "not-rotatable") {
+				p.Directions = 1
+			}
+			r.Add(p)
+		}
+	}
+	return nil
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDumpBox parses a bounding box of the game, which is either
+// [left_top, right_bottom] or {"left_top": ..., "right_bottom": ...}.
+func parseDumpBox(raw json.RawMessage) (Box, error) {
+	var corners []json.RawMessage
+	if err := json.Unmarshal(raw, &corners); err != nil {
+		var named struct {
+			LeftTop     json.RawMessage `json:"left_top"`
+			RightBottom json.RawMessage `json:"right_bottom"`
+		}
+		if err := json.Unmarshal(raw, &named); err != nil {
+			return Box{}, err
+		}
+		corners = []json.RawMessage{named.LeftTop, named.RightBottom}
+	}
+	if len(corners) < 2 {
+		return Box{}, fmt.Errorf("want 2 corners, got %d", len(corners))
+	}
+	var xy [2][2]float64
+	for i := range xy {
+		var err error
+		if xy[i], err = parseDumpPosition(corners[i]); err != nil {
+			return Box{}, err
+		}
+	}
+	return NewBox(xy[0][0], xy[0][1], xy[1][0], xy[1][1]), nil
+}
+
+// parseDumpPosition parses a map position of the game, which is either
+// [x, y] or {"x": x, "y": y}.
+func parseDumpPosition(raw json.RawMessage) ([2]float64, error) {
+	var xy []float64
+	if err := json.Unmarshal(raw, &xy); err == nil {
+		if len(xy) != 2 {
+			return [2]float64{}, fmt.Errorf("want 2 coordinates, got %d", len(xy))
+		}
+		return [2]float64{xy[0], xy[1]}, nil
+	}
+	var named struct {
+		X *float64 `json:"x"`
+		Y *float64 `json:"y"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return [2]float64{}, err
+	}
+	if named.X == nil || named.Y == nil {
+		return [2]float64{}, fmt.Errorf("position %s: want x and y", raw)
+	}
+	return [2]float64{*named.X, *named.Y}, nil
+}
@@ -0,0 +1,219 @@
+// Package prototype_blueprint knows the sizes, collision boxes and directions
+// of entity prototypes, which blueprints leave out: a blueprint only has the
+// name and the center of each entity.
+//
+// Vanilla returns a registry of the entities of vanilla Factorio and Space
+// Age. Entities of mods are added from the data-raw-dump.json the game writes
+// when started with --dump-data:
+//
+//	r := prototype_blueprint.Vanilla()
+//	if err := r.LoadDataRawDump(f); err != nil {
+//		return err
+//	}
+//	bounds, ok := r.Bounds(bp)
+//
+// The public interface is unstable.
+package prototype_blueprint // badc0de.net/pkg/factorioblueprint/prototype_blueprint
+
+import (
+	"fmt"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Prototype is what the registry knows of an entity prototype.
+type Prototype struct {
+	// Name is the name of the prototype, as in Entity.Name.
+	Name string
+
+	// Type is the type of the prototype, its category, such as
+	// "assembling-machine".
+	Type string
+
+	// Width and Height are the size of the entity in tiles, facing north.
+	Width, Height int
+
+	// CollisionBox is the collision box of the entity facing north, relative
+	// to its center.
+	CollisionBox Box
+
+	// Directions is the number of directions the entity can face, in the
+	// 16-way numbering of blueprint_schema.Direction: 1 if it cannot be
+	// rotated, 4 for the cardinal directions, and 8 or 16 for rails and
+	// signals.
+	Directions int
+}
+
+// Rotatable returns whether the entity can face more than one direction.
+func (p Prototype) Rotatable() bool {
+	return p.Directions > 1
+}
+
+// quarterTurns returns the number of quarter turns clockwise from north for
+// the footprint of an entity facing d. Directions between the cardinal ones
+// count as the one counterclockwise of them.
+func quarterTurns(d blueprint_schema.Direction) int {
+	return int(d.Rotate(0)) / int(blueprint_schema.DirectionEast)
+}
+
+// Size returns the size of the entity in tiles when facing d.
+func (p Prototype) Size(d blueprint_schema.Direction) (width, height int) {
+	if quarterTurns(d)%2 == 1 {
+		return p.Height, p.Width
+	}
+	return p.Width, p.Height
+}
+
+// CollisionBoxFacing returns the collision box of the entity facing d,
+// relative to its center.
+func (p Prototype) CollisionBoxFacing(d blueprint_schema.Direction) Box {
+	return p.CollisionBox.Rotate(quarterTurns(d))
+}
+
+// Box is an axis aligned rectangle, such as a collision box.
+type Box struct {
+	LeftTop, RightBottom blueprint_schema.FixedPosition
+}
+
+// NewBox returns the box between two corners, given in tiles.
+func NewBox(left, top, right, bottom float64) Box {
+	return Box{
+		LeftTop:     blueprint_schema.FixedPosition{X: blueprint_schema.FixedFromFloat(left), Y: blueprint_schema.FixedFromFloat(top)},
+		RightBottom: blueprint_schema.FixedPosition{X: blueprint_schema.FixedFromFloat(right), Y: blueprint_schema.FixedFromFloat(bottom)},
+	}
+}
+
+// Add returns b moved by p.
+func (b Box) Add(p blueprint_schema.FixedPosition) Box {
+	return Box{LeftTop: b.LeftTop.Add(p), RightBottom: b.RightBottom.Add(p)}
+}
+
+// Rotate returns b turned around the origin clockwise by the number of
+// quarter turns.
+func (b Box) Rotate(quarterTurns int) Box {
+	return boxOf(b.LeftTop.Rotate(quarterTurns), b.RightBottom.Rotate(quarterTurns))
+}
+
+// Union returns the smallest box containing both b and o.
+func (b Box) Union(o Box) Box {
+	return boxOf(b.LeftTop, b.RightBottom, o.LeftTop, o.RightBottom)
+}
+
+// Overlaps returns whether b and o overlap. Boxes which only touch do not.
+func (b Box) Overlaps(o Box) bool {
+	return b.LeftTop.X < o.RightBottom.X && o.LeftTop.X < b.RightBottom.X &&
+		b.LeftTop.Y < o.RightBottom.Y && o.LeftTop.Y < b.RightBottom.Y
+}
+
+// Size returns the width and height of b.
+func (b Box) Size() (width, height blueprint_schema.Fixed) {
+	return b.RightBottom.X - b.LeftTop.X, b.RightBottom.Y - b.LeftTop.Y
+}
+
+func (b Box) String() string {
+	return fmt.Sprintf("[%v, %v]", b.LeftTop, b.RightBottom)
+}
+
+// boxOf returns the smallest box containing all points.
+func boxOf(points ...blueprint_schema.FixedPosition) Box {
+	b := Box{LeftTop: points[0], RightBottom: points[0]}
+	for _, p := range points[1:] {
+		if p.X < b.LeftTop.X {
+			b.LeftTop.X = p.X
+		}
+		if p.Y < b.LeftTop.Y {
+			b.LeftTop.Y = p.Y
+		}
+		if p.X > b.RightBottom.X {
+			b.RightBottom.X = p.X
+		}
+		if p.Y > b.RightBottom.Y {
+			b.RightBottom.Y = p.Y
+		}
+	}
+	return b
+}
+
+// Registry maps entity names to prototypes. It is not safe to add prototypes
+// while looking up others at the same time.
+type Registry struct {
+	prototypes map[string]Prototype
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{prototypes: make(map[string]Prototype)}
+}
+
+// Vanilla returns a new registry with the entities of vanilla Factorio 2.0
+// and Space Age, along with the names of Factorio 1.1 which were renamed.
+func Vanilla() *Registry {
+	r := NewRegistry()
+	for _, p := range vanilla {
+		r.Add(p)
+	}
+	return r
+}
+
+// Add adds p to the registry, replacing a prototype of the same name.
+func (r *Registry) Add(p Prototype) {
+	r.prototypes[p.Name] = p
+}
+
+// Lookup returns the prototype of an entity name.
+func (r *Registry) Lookup(name string) (Prototype, bool) {
+	p, ok := r.prototypes[name]
+	return p, ok
+}
+
+// Len returns the number of prototypes in the registry.
+func (r *Registry) Len() int {
+	return len(r.prototypes)
+}
+
+// Prototype returns the prototype of an entity name. Entities unknown to the
+// registry are taken to fill one tile, and not to be rotatable.
+func (r *Registry) Prototype(name string) Prototype {
+	if p, ok := r.Lookup(name); ok {
+		return p
+	}
+	return Prototype{Name: name, Width: 1, Height: 1, CollisionBox: NewBox(-0.5, -0.5, 0.5, 0.5), Directions: 1}
+}
+
+// EntityBox returns the collision box of e, an entity of bp, on the map.
+func (r *Registry) EntityBox(bp *blueprint_schema.Blueprint, e *blueprint_schema.Entity) Box {
+	return r.Prototype(e.Name).CollisionBoxFacing(bp.EntityDirection(e)).Add(e.Position.Fixed())
+}
+
+// EntityTiles returns the top left tile covered by e, an entity of bp, and
+// the number of tiles it covers to the right and downwards.
+func (r *Registry) EntityTiles(bp *blueprint_schema.Blueprint, e *blueprint_schema.Entity) (topLeft blueprint_schema.TilePosition, width, height int) {
+	width, height = r.Prototype(e.Name).Size(bp.EntityDirection(e))
+	return e.Position.Fixed().TopLeft(width, height), width, height
+}
+
+// Bounds returns the area covered by the tiles of all entities and tiles of
+// bp, so its corners are tile corners. It returns false for a blueprint
+// without entities and tiles.
+func (r *Registry) Bounds(bp *blueprint_schema.Blueprint) (Box, bool) {
+	var bounds Box
+	ok := false
+	add := func(topLeft blueprint_schema.TilePosition, width, height int) {
+		b := Box{
+			LeftTop:     topLeft.Fixed(),
+			RightBottom: topLeft.Add(blueprint_schema.TilePosition{X: width, Y: height}).Fixed(),
+		}
+		if ok {
+			bounds = bounds.Union(b)
+		} else {
+			bounds, ok = b, true
+		}
+	}
+	for i := range bp.Entities {
+		add(r.EntityTiles(bp, &bp.Entities[i]))
+	}
+	for _, t := range bp.Tiles {
+		add(t.Position.Fixed().Tile(), 1, 1)
+	}
+	return bounds, ok
+}
@@ -0,0 +1,178 @@
+package prototype_blueprint
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Example of finding the area a blueprint covers.
+func ExampleRegistry_Bounds() {
+	bp := &blueprint_schema.Blueprint{
+		Version: blueprint_schema.GameVersion2_0,
+		Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "assembling-machine-2", Position: blueprint_schema.Position{X: 0.5, Y: 0.5}},
+			{EntityNumber: 2, Name: "inserter", Position: blueprint_schema.Position{X: 2.5, Y: 0.5}},
+			{EntityNumber: 3, Name: "rocket-silo", Position: blueprint_schema.Position{X: 7.5, Y: 0.5}},
+		},
+	}
+
+	r := Vanilla()
+	bounds, _ := r.Bounds(bp)
+	fmt.Println(bounds)
+	w, h := bounds.Size()
+	fmt.Println(w, h)
+
+	// Output:
+	// [(-1, -4), (12, 5)]
+	// 13 9
+}
+
+// TestPrototype_Size tests the sizes of entities facing each way.
+func TestPrototype_Size(t *testing.T) {
+	r := Vanilla()
+	tcs := []struct {
+		name         string
+		direction    blueprint_schema.Direction
+		wantW, wantH int
+	}{
+		{"assembling-machine-3", blueprint_schema.DirectionEast, 3, 3},
+		{"rocket-silo", blueprint_schema.DirectionNorth, 9, 9},
+		{"stone-furnace", blueprint_schema.DirectionNorth, 2, 2},
+		{"splitter", blueprint_schema.DirectionNorth, 2, 1},
+		{"splitter", blueprint_schema.DirectionEast, 1, 2},
+		{"splitter", blueprint_schema.DirectionSouth, 2, 1},
+		{"splitter", blueprint_schema.DirectionWest, 1, 2},
+		{"steam-engine", blueprint_schema.DirectionEast, 5, 3},
+		{"straight-rail", blueprint_schema.DirectionNorthEast, 2, 2},
+		{"no-such-entity", blueprint_schema.DirectionEast, 1, 1},
+	}
+	for _, tc := range tcs {
+		gotW, gotH := r.Prototype(tc.name).Size(tc.direction)
+		if gotW != tc.wantW || gotH != tc.wantH {
+			t.Errorf("Bad data for %s facing %v: want = '%dx%d' got '%dx%d'", tc.name, tc.direction, tc.wantW, tc.wantH, gotW, gotH)
+		}
+	}
+
+	if _, ok := r.Lookup("no-such-entity"); ok {
+		t.Errorf("Bad data: want = 'unknown' got 'known'")
+	}
+	if p := r.Prototype("stone-furnace"); p.Rotatable() || p.Type != "furnace" {
+		t.Errorf("Bad data: want = 'furnace, not rotatable' got '%+v'", p)
+	}
+	if p := r.Prototype("inserter"); !p.Rotatable() {
+		t.Errorf("Bad data: want = 'rotatable' got '%+v'", p)
+	}
+}
+
+// TestVanilla tests that the vanilla prototypes are consistent: names are
+// unique, and collision boxes fit the size of the entity.
+func TestVanilla(t *testing.T) {
+	if got := Vanilla().Len(); got != len(vanilla) {
+		t.Errorf("Bad data: want = '%d prototypes' got '%d', a name is listed twice", len(vanilla), got)
+	}
+	for _, p := range vanilla {
+		w, h := p.CollisionBox.Size()
+		if w <= 0 || h <= 0 || w > blueprint_schema.FixedFromTiles(p.Width) || h > blueprint_schema.FixedFromTiles(p.Height) {
+			t.Errorf("Bad data for %s: want = 'collision box within %dx%d' got '%v'", p.Name, p.Width, p.Height, p.CollisionBox)
+		}
+		if p.Directions < 1 || 16%p.Directions != 0 {
+			t.Errorf("Bad data for %s: want = 'directions dividing 16' got '%d'", p.Name, p.Directions)
+		}
+	}
+}
+
+// TestRegistry_EntityBox tests collision boxes on the map.
+func TestRegistry_EntityBox(t *testing.T) {
+	ptrInt := func(i int) *int { return &i }
+	r := Vanilla()
+	bp := &blueprint_schema.Blueprint{Version: blueprint_schema.GameVersion2_0}
+	splitter := &blueprint_schema.Entity{EntityNumber: 1, Name: "splitter", Position: blueprint_schema.Position{X: 0.5, Y: 1}, Direction: ptrInt(4)}
+	belt := &blueprint_schema.Entity{EntityNumber: 2, Name: "transport-belt", Position: blueprint_schema.Position{X: 0.5, Y: 2.5}}
+	below := &blueprint_schema.Entity{EntityNumber: 3, Name: "transport-belt", Position: blueprint_schema.Position{X: 0.5, Y: 1.5}}
+
+	if got, want := r.EntityBox(bp, splitter), NewBox(0.1, 0.1, 0.9, 1.9); got != want {
+		t.Errorf("Bad data: want = '%v' got '%v'", want, got)
+	}
+	if topLeft, w, h := r.EntityTiles(bp, splitter); topLeft != (blueprint_schema.TilePosition{X: 0, Y: 0}) || w != 1 || h != 2 {
+		t.Errorf("Bad data: want = '(0, 0) 1x2' got '%v %dx%d'", topLeft, w, h)
+	}
+	if r.EntityBox(bp, splitter).Overlaps(r.EntityBox(bp, belt)) {
+		t.Errorf("Bad data: want = 'no collision with belt after splitter' got 'collision'")
+	}
+	if !r.EntityBox(bp, splitter).Overlaps(r.EntityBox(bp, below)) {
+		t.Errorf("Bad data: want = 'collision with belt under splitter' got 'none'")
+	}
+
+	// Boxes which touch do not collide.
+	if NewBox(0, 0, 1, 1).Overlaps(NewBox(1, 0, 2, 1)) {
+		t.Errorf("Bad data: want = 'no collision of touching boxes' got 'collision'")
+	}
+}
+
+// TestRegistry_Bounds tests the area of blueprints with tiles, and of an
+// empty one.
+func TestRegistry_Bounds(t *testing.T) {
+	r := Vanilla()
+	if _, ok := r.Bounds(&blueprint_schema.Blueprint{}); ok {
+		t.Errorf("Bad data: want = 'no bounds' got 'bounds'")
+	}
+
+	bp := &blueprint_schema.Blueprint{
+		Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "stone-furnace", Position: blueprint_schema.Position{X: 0, Y: 0}},
+		},
+		Tiles: []blueprint_schema.Tile{
+			{Name: "stone-path", Position: blueprint_schema.Position{X: 3, Y: -4}},
+		},
+	}
+	got, ok := r.Bounds(bp)
+	if want := NewBox(-1, -4, 4, 1); !ok || got != want {
+		t.Errorf("Bad data: want = '%v' got '%v'", want, got)
+	}
+}
+
+// TestRegistry_LoadDataRawDump tests adding modded prototypes from a dump of
+// the game.
+func TestRegistry_LoadDataRawDump(t *testing.T) {
+	f, err := os.Open("testdata/data-raw-dump.json")
+	if err != nil {
+		t.Fatalf("Failed to open dump: %v", err)
+	}
+	defer f.Close()
+
+	r := Vanilla()
+	n := r.Len()
+	if err := r.LoadDataRawDump(f); err != nil {
+		t.Fatalf("LoadDataRawDump() failed: %v", err)
+	}
+	if got := r.Len(); got != n+3 {
+		t.Errorf("Bad data: want = '%d prototypes' got '%d'", n+3, got)
+	}
+
+	tcs := []Prototype{
+		{"assembling-machine-1", "assembling-machine", 3, 3, NewBox(-1.2, -1.2, 1.2, 1.2), 4},
+		{"modded-smelter", "assembling-machine", 4, 2, NewBox(-1.7, -0.7, 1.7, 0.7), 1},
+		{"modded-chest", "container", 1, 1, NewBox(-0.35, -0.35, 0.35, 0.35), 1},
+		{"modded-marker", "simple-entity-with-owner", 2, 2, NewBox(-0.2, -0.2, 0.2, 0.2), 4},
+	}
+	for _, want := range tcs {
+		got, ok := r.Lookup(want.Name)
+		if !ok || got != want {
+			t.Errorf("Bad data: want = '%+v' got '%+v'", want, got)
+		}
+	}
+
+	for _, dump := range []string{
+		`[]`,
+		`{"container": {"broken": {"collision_box": [[0, 0]]}}}`,
+		`{"container": {"broken": {"collision_box": [[0, 0], {"x": 1}]}}}`,
+	} {
+		if err := NewRegistry().LoadDataRawDump(strings.NewReader(dump)); err == nil {
+			t.Errorf("Bad data: want = 'error for %s' got 'nil'", dump)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package prototype_blueprint
+
+// square returns a collision box reaching r tiles from the center each way.
+func square(r float64) Box {
+	return NewBox(-r, -r, r, r)
+}
+
+// vanilla are the entities of the base game and Space Age which can be
+// placed by blueprints, from the prototypes of Factorio 2.0, followed by the
+// names of Factorio 1.1 which were renamed.
+var vanilla = []Prototype{
+	// Belts.
+	{"transport-belt", "transport-belt", 1, 1, square(0.4), 4},
+	{"fast-transport-belt", "transport-belt", 1, 1, square(0.4), 4},
+	{"express-transport-belt", "transport-belt", 1, 1, square(0.4), 4},
+	{"turbo-transport-belt", "transport-belt", 1, 1, square(0.4), 4},
+	{"underground-belt", "underground-belt", 1, 1, square(0.4), 4},
+	{"fast-underground-belt", "underground-belt", 1, 1, square(0.4), 4},
+	{"express-underground-belt", "underground-belt", 1, 1, square(0.4), 4},
+	{"turbo-underground-belt", "underground-belt", 1, 1, square(0.4), 4},
+	{"splitter", "splitter", 2, 1, NewBox(-0.9, -0.4, 0.9, 0.4), 4},
+	{"fast-splitter", "splitter", 2, 1, NewBox(-0.9, -0.4, 0.9, 0.4), 4},
+	{"express-splitter", "splitter", 2, 1, NewBox(-0.9, -0.4, 0.9, 0.4), 4},
+	{"turbo-splitter", "splitter", 2, 1, NewBox(-0.9, -0.4, 0.9, 0.4), 4},
+	{"loader", "loader", 1, 2, NewBox(-0.4, -0.9, 0.4, 0.9), 4},
+	{"fast-loader", "loader", 1, 2, NewBox(-0.4, -0.9, 0.4, 0.9), 4},
+	{"express-loader", "loader", 1, 2, NewBox(-0.4, -0.9, 0.4, 0.9), 4},
+	{"turbo-loader", "loader", 1, 2, NewBox(-0.4, -0.9, 0.4, 0.9), 4},
+
+	// Inserters.
+	{"burner-inserter", "inserter", 1, 1, square(0.15), 4},
+	{"inserter", "inserter", 1, 1, square(0.15), 4},
+	{"long-handed-inserter", "inserter", 1, 1, square(0.15), 4},
+	{"fast-inserter", "inserter", 1, 1, square(0.15), 4},
+	{"bulk-inserter", "inserter", 1, 1, square(0.15), 4},
+	{"stack-inserter", "inserter", 1, 1, square(0.15), 4},
+
+	// Storage.
+	{"wooden-chest", "container", 1, 1, square(0.35), 1},
+	{"iron-chest", "container", 1, 1, square(0.35), 1},
+	{"steel-chest", "container", 1, 1, square(0.35), 1},
+	{"active-provider-chest", "logistic-container", 1, 1, square(0.35), 1},
+	{"passive-provider-chest", "logistic-container", 1, 1, square(0.35), 1},
+	{"storage-chest", "logistic-container", 1, 1, square(0.35), 1},
+	{"buffer-chest", "logistic-container", 1, 1, square(0.35), 1},
+	{"requester-chest", "logistic-container", 1, 1, square(0.35), 1},
+	{"storage-tank", "storage-tank", 3, 3, square(1.3), 4},
+
+	// Pipes and pumps.
+	{"pipe", "pipe", 1, 1, square(0.29), 1},
+	{"pipe-to-ground", "pipe-to-ground", 1, 1, square(0.29), 4},
+	{"pump", "pump", 1, 2, NewBox(-0.29, -0.9, 0.29, 0.9), 4},
+	{"offshore-pump", "offshore-pump", 1, 2, NewBox(-0.4, -0.9, 0.4, 0.9), 4},
+
+	// Electric network.
+	{"small-electric-pole", "electric-pole", 1, 1, square(0.15), 1},
+	{"medium-electric-pole", "electric-pole", 1, 1, square(0.15), 1},
+	{"big-electric-pole", "electric-pole", 2, 2, square(0.65), 1},
+	{"substation", "electric-pole", 2, 2, square(0.7), 1},
+	{"power-switch", "power-switch", 2, 2, square(0.7), 1},
+
+	// Energy.
+	{"boiler", "boiler", 3, 2, NewBox(-1.29, -0.79, 1.29, 0.79), 4},
+	{"steam-engine", "generator", 3, 5, NewBox(-1.35, -2.35, 1.35, 2.35), 4},
+	{"steam-turbine", "generator", 3, 5, NewBox(-1.35, -2.35, 1.35, 2.35), 4},
+	{"solar-panel", "solar-panel", 3, 3, square(1.4), 1},
+	{"accumulator", "accumulator", 2, 2, square(0.9), 1},
+	{"nuclear-reactor", "reactor", 5, 5, square(2.2), 1},
+	{"heat-exchanger", "boiler", 3, 2, NewBox(-1.29, -0.79, 1.29, 0.79), 4},
+	{"heat-pipe", "heat-pipe", 1, 1, square(0.3), 1},
+
+	// Mining.
+	{"burner-mining-drill", "mining-drill", 2, 2, square(0.7), 4},
+	{"electric-mining-drill", "mining-drill", 3, 3, square(1.4), 4},
+	{"pumpjack", "mining-drill", 3, 3, square(1.2), 4},
+
+	// Production.
+	{"stone-furnace", "furnace", 2, 2, square(0.7), 1},
+	{"steel-furnace", "furnace", 2, 2, square(0.875), 1},
+	{"electric-furnace", "furnace", 3, 3, square(1.2), 1},
+	{"assembling-machine-1", "assembling-machine", 3, 3, square(1.2), 4},
+	{"assembling-machine-2", "assembling-machine", 3, 3, square(1.2), 4},
+	{"assembling-machine-3", "assembling-machine", 3, 3, square(1.2), 4},
+	{"oil-refinery", "assembling-machine", 5, 5, square(2.4), 4},
+	{"chemical-plant", "assembling-machine", 3, 3, square(1.2), 4},
+	{"centrifuge", "assembling-machine", 3, 3, square(1.2), 4},
+	{"lab", "lab", 3, 3, square(1.2), 1},
+	{"beacon", "beacon", 3, 3, square(1.2), 1},
+	{"rocket-silo", "rocket-silo", 9, 9, square(4.4), 1},
+
+	// Logistics network.
+	{"roboport", "roboport", 4, 4, square(1.7), 1},
+
+	// Circuit network.
+	{"small-lamp", "lamp", 1, 1, square(0.15), 1},
+	{"arithmetic-combinator", "arithmetic-combinator", 1, 2, NewBox(-0.35, -0.65, 0.35, 0.65), 4},
+	{"decider-combinator", "decider-combinator", 1, 2, NewBox(-0.35, -0.65, 0.35, 0.65), 4},
+	{"selector-combinator", "selector-combinator", 1, 2, NewBox(-0.35, -0.65, 0.35, 0.65), 4},
+	{"constant-combinator", "constant-combinator", 1, 1, square(0.35), 4},
+	{"programmable-speaker", "programmable-speaker", 1, 1, square(0.3), 1},
+	{"display-panel", "display-panel", 1, 1, square(0.15), 4},
+
+	// Trains.
+	{"straight-rail", "straight-rail", 2, 2, NewBox(-0.7, -0.99, 0.7, 0.99), 8},
+	{"half-diagonal-rail", "half-diagonal-rail", 2, 4, NewBox(-0.75, -1.9, 0.75, 1.9), 8},
+	{"curved-rail-a", "curved-rail-a", 2, 6, NewBox(-0.75, -2.5, 0.75, 2.5), 16},
+	{"curved-rail-b", "curved-rail-b", 2, 6, NewBox(-0.75, -2.3, 0.75, 2.3), 16},
+	{"rail-ramp", "rail-ramp", 4, 16, NewBox(-1.6, -7.6, 1.6, 7.6), 4},
+	{"train-stop", "train-stop", 2, 2, square(0.5), 4},
+	{"rail-signal", "rail-signal", 1, 1, square(0.2), 16},
+	{"rail-chain-signal", "rail-chain-signal", 1, 1, square(0.2), 16},
+	{"locomotive", "locomotive", 2, 6, NewBox(-0.6, -2.6, 0.6, 2.6), 1},
+	{"cargo-wagon", "cargo-wagon", 2, 6, NewBox(-0.6, -2.4, 0.6, 2.4), 1},
+	{"fluid-wagon", "fluid-wagon", 2, 6, NewBox(-0.6, -2.4, 0.6, 2.4), 1},
+	{"artillery-wagon", "artillery-wagon", 2, 6, NewBox(-0.6, -2.4, 0.6, 2.4), 1},
+
+	// Defense.
+	{"stone-wall", "wall", 1, 1, square(0.49), 1},
+	{"gate", "gate", 1, 1, square(0.29), 4},
+	{"gun-turret", "ammo-turret", 2, 2, square(0.7), 1},
+	{"laser-turret", "electric-turret", 2, 2, square(0.7), 1},
+	{"flamethrower-turret", "fluid-turret", 2, 3, NewBox(-0.7, -1.2, 0.7, 1.2), 4},
+	{"artillery-turret", "artillery-turret", 3, 3, square(1.45), 4},
+	{"radar", "radar", 3, 3, square(1.2), 1},
+	{"land-mine", "land-mine", 1, 1, square(0.4), 1},
+
+	// Space Age.
+	{"foundry", "assembling-machine", 5, 5, square(2.2), 4},
+	{"electromagnetic-plant", "assembling-machine", 4, 4, square(1.7), 4},
+	{"cryogenic-plant", "assembling-machine", 5, 5, square(2.2), 4},
+	{"biochamber", "assembling-machine", 3, 3, square(1.3), 4},
+	{"crusher", "assembling-machine", 2, 3, NewBox(-0.7, -1.2, 0.7, 1.2), 4},
+	{"recycler", "furnace", 2, 4, NewBox(-0.7, -1.7, 0.7, 1.7), 4},
+	{"big-mining-drill", "mining-drill", 5, 5, square(2.4), 4},
+	{"agricultural-tower", "agricultural-tower", 3, 3, square(1.2), 1},
+	{"captive-biter-spawner", "assembling-machine", 5, 5, square(2.2), 1},
+	{"heating-tower", "reactor", 3, 3, square(1.25), 1},
+	{"fusion-reactor", "fusion-reactor", 6, 6, square(2.7), 4},
+	{"fusion-generator", "fusion-generator", 3, 5, NewBox(-1.3, -2.3, 1.3, 2.3), 4},
+	{"lightning-rod", "lightning-attractor", 1, 1, square(0.2), 1},
+	{"lightning-collector", "lightning-attractor", 2, 2, square(0.7), 1},
+	{"railgun-turret", "ammo-turret", 4, 4, square(1.7), 4},
+	{"rocket-turret", "ammo-turret", 3, 3, square(1.2), 1},
+	{"tesla-turret", "electric-turret", 2, 2, square(0.7), 1},
+	{"asteroid-collector", "asteroid-collector", 3, 3, square(1.2), 4},
+	{"cargo-bay", "cargo-bay", 4, 4, square(1.9), 1},
+	{"cargo-landing-pad", "cargo-landing-pad", 8, 8, square(3.9), 1},
+	{"space-platform-hub", "space-platform-hub", 8, 8, square(3.9), 1},
+	{"thruster", "thruster", 6, 10, NewBox(-2.9, -4.9, 2.9, 4.9), 1},
+	{"elevated-straight-rail", "elevated-straight-rail", 2, 2, NewBox(-0.7, -0.99, 0.7, 0.99), 8},
+	{"elevated-half-diagonal-rail", "elevated-half-diagonal-rail", 2, 4, NewBox(-0.75, -1.9, 0.75, 1.9), 8},
+	{"elevated-curved-rail-a", "elevated-curved-rail-a", 2, 6, NewBox(-0.75, -2.5, 0.75, 2.5), 16},
+	{"elevated-curved-rail-b", "elevated-curved-rail-b", 2, 6, NewBox(-0.75, -2.3, 0.75, 2.3), 16},
+	{"rail-support", "rail-support", 4, 4, square(1.9), 8},
+
+	// Names of Factorio 1.1.
+	{"filter-inserter", "inserter", 1, 1, square(0.15), 4},
+	{"stack-filter-inserter", "inserter", 1, 1, square(0.15), 4},
+	{"logistic-chest-active-provider", "logistic-container", 1, 1, square(0.35), 1},
+	{"logistic-chest-passive-provider", "logistic-container", 1, 1, square(0.35), 1},
+	{"logistic-chest-storage", "logistic-container", 1, 1, square(0.35), 1},
+	{"logistic-chest-buffer", "logistic-container", 1, 1, square(0.35), 1},
+	{"logistic-chest-requester", "logistic-container", 1, 1, square(0.35), 1},
+	{"curved-rail", "curved-rail", 4, 8, NewBox(-1.5, -4, 1.5, 4), 8},
+}
@@ -0,0 +1,121 @@
+package read_blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by AsJSONReader, AsStruct and AsStructLossless for strings
+// which cannot be read, so that their cause can be told apart with errors.Is
+// and errors.As. Offsets count bytes from the start of the string, where the
+// version byte is at offset 0, not counting line breaks.
+
+// ErrEmpty is returned for an empty string.
+var ErrEmpty = errors.New("empty blueprint string")
+
+// VersionError is returned if the string does not start with a known version
+// byte.
+type VersionError struct {
+	Version byte
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("version byte: got = '%c', want = '{' or '0'", e.Version)
+}
+
+// Base64Error is returned if the string has a byte which is not part of the
+// base64 encoding.
+type Base64Error struct {
+	Offset int64
+}
+
+func (e *Base64Error) Error() string {
+	return fmt.Sprintf("base64 corrupt at offset %d", e.Offset)
+}
+
+// TruncatedError is returned if the string ends before the end of the
+// compressed data, such as when a paste was cut short.
+type TruncatedError struct {
+	// Length is the length of the string.
+	Length int64
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("string truncated after %d chars", e.Length)
+}
+
+// ZlibError is returned if the compressed data is corrupt, such as a bad
+// header or checksum. Err is the error of the zlib reader, whose message
+// already starts with "zlib: ".
+type ZlibError struct {
+	Err error
+}
+
+func (e *ZlibError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ZlibError) Unwrap() error {
+	return e.Err
+}
+
+// SyntaxError is returned if the decompressed data is not valid JSON.
+type SyntaxError struct {
+	// Offset is the offset in the JSON, not in the string.
+	Offset int64
+	// Line and Column are where Offset is in the JSON, both starting at 1.
+	Line, Column int
+
+	Err *json.SyntaxError
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("JSON syntax error at line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaError is returned if the decompressed data is valid JSON which does
+// not fit the schema, such as a missing required field or a string where a
+// number belongs. Err is often a *json.UnmarshalTypeError.
+type SchemaError struct {
+	Err error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("schema mismatch: %v", e.Err)
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// jsonError turns an error from decoding data into a SyntaxError or a
+// SchemaError.
+func jsonError(data []byte, err error) error {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		line, column := position(data, syntax.Offset)
+		return &SyntaxError{Offset: syntax.Offset, Line: line, Column: column, Err: syntax}
+	}
+	return &SchemaError{Err: err}
+}
+
+// position returns the line and column of an offset in data, both starting
+// at 1. encoding/json reports the offset after the byte it failed on.
+func position(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n') - 1
+	if column == 0 {
+		column = 1
+	}
+	return line, column
+}
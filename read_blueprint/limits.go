@@ -0,0 +1,166 @@
+package read_blueprint
+
+import (
+	"fmt"
+	"io"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Limits bound the resources used to decode a blueprint string, such as one
+// pasted by a user into a shared service. A short string can decompress to
+// gigabytes, or nest arrays deep enough to exhaust the stack. Zero means no
+// limit.
+type Limits struct {
+	// MaxCompressedLength is the maximum length of the string in bytes, as
+	// read, including the version byte and line breaks.
+	MaxCompressedLength int64
+
+	// MaxDecompressedBytes is the maximum length of the JSON.
+	MaxDecompressedBytes int64
+
+	// MaxNesting is the maximum depth of nested JSON arrays and objects. The
+	// object around everything is at depth 1.
+	MaxNesting int
+
+	// MaxEntities is the maximum number of entities, counting all blueprints
+	// in a book together.
+	MaxEntities int
+
+	// MaxTiles is the maximum number of tiles, counting all blueprints in a
+	// book together.
+	MaxTiles int
+
+	// MaxBookDepth is the maximum depth of books nested in books. A book on
+	// its own is at depth 1.
+	MaxBookDepth int
+}
+
+// DefaultLimits are limits which allow any blueprint made in the game, while
+// keeping the memory used to decode a string to a few hundred megabytes.
+var DefaultLimits = Limits{
+	MaxCompressedLength:  16 << 20,
+	MaxDecompressedBytes: 128 << 20,
+	MaxNesting:           64,
+	MaxEntities:          500000,
+	MaxTiles:             1000000,
+	MaxBookDepth:         16,
+}
+
+// LimitError is returned when decoding a string hits one of its Limits.
+type LimitError struct {
+	// Limit is the name of the field of Limits which was hit.
+	Limit string
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("limit %s of %d exceeded", e.Limit, e.Max)
+}
+
+// limitedReader returns a LimitError once more than max bytes are read.
+type limitedReader struct {
+	r     io.Reader
+	n     int64
+	limit string
+	max   int64
+}
+
+func newLimitedReader(r io.Reader, limit string, n int64) io.Reader {
+	if n <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, n: n, limit: limit, max: n}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, &LimitError{Limit: l.limit, Max: l.max}
+	}
+	// Read one byte more than allowed, to tell an exact fit from too much.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n + int(l.n), &LimitError{Limit: l.limit, Max: l.max}
+	}
+	return n, err
+}
+
+// checkNesting returns a LimitError if arrays and objects in the JSON are
+// nested deeper than limit. It does not check that data is valid JSON.
+func checkNesting(data []byte, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '[' || c == '{':
+			depth++
+			if depth > limit {
+				return &LimitError{Limit: "MaxNesting", Max: int64(limit)}
+			}
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return nil
+}
+
+// checkCounts returns a LimitError if the decoded blueprint has more entities,
+// tiles or nested books than allowed.
+func checkCounts(m *blueprint_schema.BlueprintSchemaJSON, limits Limits) error {
+	c := &counter{limits: limits}
+	if m.Blueprint != nil {
+		c.blueprint(m.Blueprint)
+	}
+	if m.BlueprintBook != nil {
+		c.book(m.BlueprintBook, 1)
+	}
+	return c.err
+}
+
+type counter struct {
+	limits          Limits
+	entities, tiles int
+	err             error
+}
+
+func (c *counter) book(book *blueprint_schema.BlueprintBook, depth int) {
+	if n := c.limits.MaxBookDepth; n > 0 && depth > n {
+		c.err = &LimitError{Limit: "MaxBookDepth", Max: int64(n)}
+		return
+	}
+	for _, entry := range book.Blueprints {
+		if c.err != nil {
+			return
+		}
+		if entry.Blueprint != nil {
+			c.blueprint(entry.Blueprint)
+		}
+		if entry.BlueprintBook != nil {
+			c.book(entry.BlueprintBook, depth+1)
+		}
+	}
+}
+
+func (c *counter) blueprint(bp *blueprint_schema.Blueprint) {
+	c.entities += len(bp.Entities)
+	c.tiles += len(bp.Tiles)
+	if n := c.limits.MaxEntities; n > 0 && c.entities > n {
+		c.err = &LimitError{Limit: "MaxEntities", Max: int64(n)}
+	} else if n := c.limits.MaxTiles; n > 0 && c.tiles > n {
+		c.err = &LimitError{Limit: "MaxTiles", Max: int64(n)}
+	}
+}
@@ -1,13 +1,27 @@
 package read_blueprint
 
 import (
+	"bytes"
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+	"badc0de.net/pkg/factorioblueprint/write_blueprint"
+
+	"github.com/klauspost/compress/zlib"
 )
 
 // SimpleTxt is a simple blueprint with some belts and inserters.
@@ -133,3 +147,855 @@ func TestAsStruct(t *testing.T) {
 		})
 	}
 }
+
+// TestAsStructLossless_roundTrip decodes every file of the corpus with
+// AsStructLossless, encodes it again with write_blueprint.FromStructLossless,
+// decodes the resulting string and checks that the JSON is unchanged but for
+// the whitespace.
+func TestAsStructLossless_roundTrip(t *testing.T) {
+	corpus, err := filepath.Glob("testdata/lossless/*.json")
+	if err != nil {
+		t.Fatalf("Failed to list corpus: %v", err)
+	}
+	corpus = append(corpus, "simple.json")
+
+	for _, filename := range corpus {
+		t.Run(filepath.Base(filename), func(t *testing.T) {
+			want, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("Failed to read corpus file: %v", err)
+			}
+
+			m, err := AsStructLossless(bytes.NewReader(want))
+			if err != nil {
+				t.Fatalf("Failed to decode JSON: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := write_blueprint.FromStructLossless(&buf, m); err != nil {
+				t.Fatalf("Failed to encode blueprint: %v", err)
+			}
+
+			decompressed, err := AsJSONReader(&buf)
+			if err != nil {
+				t.Fatalf("Failed to decompress JSON: %v", err)
+			}
+			got, err := ioutil.ReadAll(decompressed)
+			if err != nil {
+				t.Fatalf("Failed to read decompressed JSON: %v", err)
+			}
+
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, want); err != nil {
+				t.Fatalf("Failed to compact JSON: %v", err)
+			}
+			if w := compact.Bytes(); !bytes.Equal(got, w) {
+				t.Errorf("JSON changed after round trip:\ngot:  %s\nwant: %s", got, w)
+			}
+		})
+	}
+}
+
+// TestAsStructLossless_extra checks that unknown fields end up in the Extra
+// field of the struct they belong to.
+func TestAsStructLossless_extra(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/lossless/modded.json")
+	if err != nil {
+		t.Fatalf("Failed to read corpus file: %v", err)
+	}
+	m, err := AsStructLossless(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	if _, ok := m.Blueprint.Extra["tags"]; !ok {
+		t.Errorf("Blueprint tags not kept: %v", m.Blueprint.Extra)
+	}
+	// Quality used to be unknown to the schema, and is now decoded into its
+	// own field.
+	if _, ok := m.Blueprint.Entities[0].Extra["quality"]; ok {
+		t.Errorf("Bad data: quality kept in Extra: %v", m.Blueprint.Entities[0].Extra)
+	}
+	if q := blueprint_schema.QualityOf(m.Blueprint.Entities[0].Quality); q != blueprint_schema.QualityLegendary {
+		t.Errorf("Bad data: want quality = 'legendary' got '%v'", q)
+	}
+	// Integers above 2^53 must not lose precision.
+	if id := m.Blueprint.Entities[0].Tags["bp_id"]; id != int64(9007199254740993) {
+		t.Errorf("Bad data: want bp_id = 9007199254740993 got '%v'", id)
+	}
+	if v := m.Blueprint.Entities[1].ControlBehavior.Extra["circuit_read_hand_contents"]; v != true {
+		t.Errorf("Bad data: want circuit_read_hand_contents = true got '%v'", v)
+	}
+	if v := m.Blueprint.Entities[1].ControlBehavior.CircuitCondition.Extra["fulfilled"]; v != false {
+		t.Errorf("Bad data: want fulfilled = false got '%v'", v)
+	}
+
+	// Known fields are unaffected.
+	if m.Blueprint.Entities[1].Name != "fast-inserter" {
+		t.Errorf("Bad data: want name = 'fast-inserter' got '%v'", m.Blueprint.Entities[1].Name)
+	}
+}
+
+// TestAsStruct_planners checks that upgrade and deconstruction planners, on
+// their own and inside a book, are decoded into their own types.
+func TestAsStruct_planners(t *testing.T) {
+	read := func(filename string) blueprint_schema.BlueprintSchemaJSON {
+		t.Helper()
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("Failed to read corpus file: %v", err)
+		}
+		m, err := AsStruct(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Failed to decode JSON: %v", err)
+		}
+		return m
+	}
+
+	up := read("testdata/lossless/upgrade_planner.json").UpgradePlanner
+	if up == nil || up.Settings == nil || len(up.Settings.Mappers) != 3 {
+		t.Fatalf("Bad data: want upgrade planner with 3 mappers got '%+v'", up)
+	}
+	if to := up.Settings.Mappers[0].To; to == nil || to.Name != "fast-transport-belt" || to.Type != blueprint_schema.UpgradeMapperTargetTypeEntity {
+		t.Fatalf("Bad data: want to = 'fast-transport-belt' got '%+v'", to)
+	}
+	if to := up.Settings.Mappers[1].To; to == nil || blueprint_schema.QualityOf(to.Quality) != blueprint_schema.QualityRare {
+		t.Fatalf("Bad data: want to quality = 'rare' got '%+v'", to)
+	}
+	if to := up.Settings.Mappers[2].To; to != nil {
+		t.Fatalf("Bad data: want empty mapper target got '%+v'", to)
+	}
+
+	dp := read("testdata/lossless/deconstruction_planner.json").DeconstructionPlanner
+	if dp == nil || dp.Settings == nil {
+		t.Fatalf("Bad data: want deconstruction planner with settings got '%+v'", dp)
+	}
+	if mode := dp.Settings.EntityFilterMode; mode == nil || *mode != blueprint_schema.FilterModeBlacklist {
+		t.Fatalf("Bad data: want entity_filter_mode = 1 got '%v'", mode)
+	}
+	if mode := dp.Settings.TileSelectionMode; mode == nil || *mode != blueprint_schema.TileSelectionModeOnly {
+		t.Fatalf("Bad data: want tile_selection_mode = 3 got '%v'", mode)
+	}
+	if f := dp.Settings.EntityFilters[1]; f.Name != "assembling-machine-1" || f.Index != 2 || f.Quality == nil || !f.Quality.IsNormal() {
+		t.Fatalf("Bad data: want filter 'assembling-machine-1' of normal quality at 2 got '%+v'", f)
+	}
+	if q := dp.Settings.EntityFilters[0].Quality; q != nil {
+		t.Fatalf("Bad data: want filter of any quality at 1 got '%v'", *q)
+	}
+
+	book := read("testdata/lossless/book_planners.json").BlueprintBook
+	if book == nil || len(book.Blueprints) != 3 {
+		t.Fatalf("Bad data: want book with 3 entries got '%+v'", book)
+	}
+	if e := book.Blueprints[0]; e.UpgradePlanner == nil || e.Blueprint != nil {
+		t.Fatalf("Bad data: want upgrade planner at 0 got '%+v'", e)
+	}
+	if e := book.Blueprints[1]; e.DeconstructionPlanner == nil || e.DeconstructionPlanner.Label == nil || *e.DeconstructionPlanner.Label != "Trees" {
+		t.Fatalf("Bad data: want deconstruction planner 'Trees' at 1 got '%+v'", e)
+	}
+	if e := book.Blueprints[2]; e.Blueprint == nil || e.Blueprint.Entities[0].Name != "inserter" {
+		t.Fatalf("Bad data: want blueprint at 2 got '%+v'", e)
+	}
+}
+
+// TestAsJSONReader_errors checks that broken strings are reported with the
+// error types of the package, by AsJSONReader or by the reader it returns.
+func TestAsJSONReader_errors(t *testing.T) {
+	simple := strings.TrimSpace(SimpleTxt)
+	// "0" followed by the base64 of "hello", which has no zlib header.
+	notZlib := "0aGVsbG8="
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+		check func(err error) bool
+	}{
+		{"empty", "", "empty blueprint string", func(err error) bool { return errors.Is(err, ErrEmpty) }},
+		{"version", "1abc", "version byte: got = '1', want = '{' or '0'", func(err error) bool {
+			var e *VersionError
+			return errors.As(err, &e) && e.Version == '1'
+		}},
+		{"base64", simple[:10] + "!" + simple[11:], "base64 corrupt at offset 10", func(err error) bool {
+			var e *Base64Error
+			return errors.As(err, &e) && e.Offset == 10
+		}},
+		{"truncated in base64", simple[:103], "string truncated after 103 chars", func(err error) bool {
+			var e *TruncatedError
+			return errors.As(err, &e) && e.Length == 103
+		}},
+		{"truncated in zlib", simple[:101], "string truncated after 101 chars", func(err error) bool {
+			var e *TruncatedError
+			return errors.As(err, &e) && e.Length == 101
+		}},
+		{"zlib", notZlib, "zlib: invalid header", func(err error) bool {
+			var e *ZlibError
+			return errors.As(err, &e)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Errors past the first bytes are returned by the reader.
+			r, err := AsJSONReader(strings.NewReader(tc.input))
+			if err == nil {
+				_, err = ioutil.ReadAll(r)
+			}
+			if err == nil || err.Error() != tc.want {
+				t.Fatalf("Bad data: want = '%v' got '%v'", tc.want, err)
+			}
+			if !tc.check(err) {
+				t.Fatalf("Bad data: wrong error type '%T'", err)
+			}
+		})
+	}
+}
+
+// TestAsJSONReader_lazy checks that the string is only read as the JSON is,
+// so that an error reading the end of it comes from the returned reader.
+func TestAsJSONReader_lazy(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	r := io.MultiReader(strings.NewReader(strings.TrimSpace(SimpleTxt)[:40]), iotest.ErrReader(errBroken))
+	decompressed, err := AsJSONReader(r)
+	if err != nil {
+		t.Fatalf("AsJSONReader() failed: %v", err)
+	}
+	if _, err := ioutil.ReadAll(decompressed); !errors.Is(err, errBroken) {
+		t.Fatalf("Bad data: want = '%v' got '%v'", errBroken, err)
+	}
+}
+
+// TestAsStruct_errors checks that errors in the JSON are reported with their
+// position.
+func TestAsStruct_errors(t *testing.T) {
+	_, err := AsStruct(strings.NewReader("{\n  \"blueprint\": {\n    \"item\" \"blueprint\"\n}}"))
+	var syntax *SyntaxError
+	if !errors.As(err, &syntax) || syntax.Line != 3 || syntax.Column != 12 {
+		t.Fatalf("Bad data: want syntax error at 3:12 got '%v'", err)
+	}
+
+	_, err = AsStructLossless(strings.NewReader(`{"blueprint": {"item": 5, "entities": [], "icons": [], "version": 1}}`))
+	var schema *SchemaError
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &schema) || !errors.As(err, &typeErr) || typeErr.Field != "item" {
+		t.Fatalf("Bad data: want schema error on item got '%v'", err)
+	}
+}
+
+// TestLimits checks that each limit is reported with a LimitError.
+func TestLimits(t *testing.T) {
+	// A string which decompresses to a megabyte of whitespace.
+	var bomb bytes.Buffer
+	bomb.WriteString("0")
+	b64 := base64.NewEncoder(base64.StdEncoding, &bomb)
+	z := zlib.NewWriter(b64)
+	z.Write([]byte(`{"blueprint": {"item": "blueprint", "label": "bomb"` + strings.Repeat(" ", 1<<20) + `}}`))
+	z.Close()
+	b64.Close()
+
+	nested := `{"blueprint": {"item": "blueprint", "version": 0, "entities": [], "icons": [], "label": "[[[[", "tags": ` + strings.Repeat("[", 10) + strings.Repeat("]", 10) + `}}`
+	book := `{"blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": [
+		{"index": 0, "blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": [
+			{"index": 0, "blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": []}}
+		]}}
+	]}}`
+
+	for _, tc := range []struct {
+		name   string
+		input  string
+		limits Limits
+		want   string
+	}{
+		{"default", SimpleTxt, DefaultLimits, ""},
+		{"default JSON", SimpleJSON, DefaultLimits, ""},
+		{"compressed", SimpleTxt, Limits{MaxCompressedLength: 100}, "limit MaxCompressedLength of 100 exceeded"},
+		{"decompressed", bomb.String(), Limits{MaxDecompressedBytes: 1000}, "limit MaxDecompressedBytes of 1000 exceeded"},
+		{"decompressed JSON", SimpleJSON, Limits{MaxDecompressedBytes: 100}, "limit MaxDecompressedBytes of 100 exceeded"},
+		{"nesting", nested, Limits{MaxNesting: 11}, "limit MaxNesting of 11 exceeded"},
+		{"nesting fits", nested, Limits{MaxNesting: 12}, ""},
+		{"entities", SimpleJSON, Limits{MaxEntities: 1}, "limit MaxEntities of 1 exceeded"},
+		{"book depth", book, Limits{MaxBookDepth: 2}, "limit MaxBookDepth of 2 exceeded"},
+		{"book depth fits", book, Limits{MaxBookDepth: 3}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			decompressed, err := AsJSONReaderWithLimits(strings.NewReader(tc.input), tc.limits)
+			if err == nil {
+				_, err = AsStructLosslessWithLimits(decompressed, tc.limits)
+			}
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("Failed to decode: %v", err)
+				}
+				return
+			}
+			var limit *LimitError
+			if !errors.As(err, &limit) || err.Error() != tc.want {
+				t.Fatalf("Bad data: want = '%v' got '%v'", tc.want, err)
+			}
+		})
+	}
+}
+
+// addSeeds adds the corpus to the seed corpus of a fuzz target, both as JSON
+// and as blueprint strings, along with a few odd inputs.
+func addSeeds(f *testing.F) {
+	f.Add([]byte(SimpleTxt))
+	f.Add([]byte(SimpleJSON))
+	corpus, err := filepath.Glob("testdata/lossless/*.json")
+	if err != nil {
+		f.Fatalf("Failed to list corpus: %v", err)
+	}
+	for _, filename := range corpus {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			f.Fatalf("Failed to read corpus file: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := write_blueprint.FromJSON(&buf, bytes.NewReader(data)); err != nil {
+			f.Fatalf("Failed to encode corpus file %v: %v", filename, err)
+		}
+		f.Add(data)
+		f.Add(buf.Bytes())
+	}
+	for _, s := range []string{"", "0", "0eN", "0eJ====", "{", "{}", "[]", "null", `{"blueprint": null}`, `{"blueprint_book": {"blueprints": [{}]}}`} {
+		f.Add([]byte(s))
+	}
+}
+
+// FuzzAsJSONReader checks that AsJSONReader does not panic, and that it only
+// returns the error types of this package.
+func FuzzAsJSONReader(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decompressed, err := AsJSONReaderWithLimits(bytes.NewReader(data), DefaultLimits)
+		if err != nil {
+			var (
+				version   *VersionError
+				b64       *Base64Error
+				truncated *TruncatedError
+				z         *ZlibError
+				limit     *LimitError
+			)
+			if !errors.Is(err, ErrEmpty) && !errors.As(err, &version) && !errors.As(err, &b64) &&
+				!errors.As(err, &truncated) && !errors.As(err, &z) && !errors.As(err, &limit) {
+				t.Fatalf("Bad data: unexpected error type %T: %v", err, err)
+			}
+			return
+		}
+		if _, err := ioutil.ReadAll(decompressed); err != nil {
+			var limit *LimitError
+			if !errors.As(err, &limit) {
+				t.Fatalf("Failed to read decompressed JSON: %v", err)
+			}
+		}
+	})
+}
+
+// FuzzAsStruct checks that decoding arbitrary JSON does not panic, and that
+// whatever AsStructLossless accepts stays the same when encoded and decoded
+// again.
+func FuzzAsStruct(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		AsStructWithLimits(bytes.NewReader(data), DefaultLimits)
+
+		m, err := AsStructLosslessWithLimits(bytes.NewReader(data), DefaultLimits)
+		if err != nil {
+			return
+		}
+		once, err := blueprint_schema.MarshalLossless(m)
+		if err != nil {
+			t.Fatalf("Failed to encode JSON: %v", err)
+		}
+		m, err = AsStructLossless(bytes.NewReader(once))
+		if err != nil {
+			t.Fatalf("Failed to decode encoded JSON %s: %v", once, err)
+		}
+		twice, err := blueprint_schema.MarshalLossless(m)
+		if err != nil {
+			t.Fatalf("Failed to encode JSON: %v", err)
+		}
+		if !bytes.Equal(once, twice) {
+			t.Fatalf("Bad data: JSON changed after round trip:\ngot:  %s\nwant: %s", twice, once)
+		}
+	})
+}
+
+// randomBlueprints generates random valid blueprints and books. Empty slices
+// are nil, as they are left out when encoding.
+type randomBlueprints struct {
+	*rand.Rand
+	lossless bool
+}
+
+func (r randomBlueprints) string() string {
+	runes := []rune("abcXYZ019 -_\"\\/<>&\n\té€😀")
+	s := make([]rune, r.Intn(12))
+	for i := range s {
+		s[i] = runes[r.Intn(len(runes))]
+	}
+	return string(s)
+}
+
+func (r randomBlueprints) name() string {
+	names := []string{"transport-belt", "inserter", "assembling-machine-2", "small-electric-pole", "constant-combinator", "stone-furnace"}
+	return names[r.Intn(len(names))]
+}
+
+func (r randomBlueprints) position() blueprint_schema.Position {
+	return blueprint_schema.Position{X: float64(r.Intn(201)-100) / 2, Y: float64(r.Intn(201)-100) / 2}
+}
+
+func (r randomBlueprints) extra() blueprint_schema.ExtraFields {
+	if !r.lossless || r.Intn(3) != 0 {
+		return nil
+	}
+	return blueprint_schema.ExtraFields{
+		"mod_" + r.name(): int64(r.Intn(1000)),
+		"mod_note":        []interface{}{r.string(), map[string]interface{}{"flag": r.Intn(2) == 0}},
+	}
+}
+
+func (r randomBlueprints) color() *blueprint_schema.Color {
+	if r.Intn(2) == 0 {
+		return nil
+	}
+	c := &blueprint_schema.Color{R: r.Float64(), G: r.Float64(), B: r.Float64()}
+	if r.Intn(2) == 0 {
+		a := r.Float64()
+		c.A = &a
+	}
+	return c
+}
+
+func (r randomBlueprints) optString() *string {
+	if r.Intn(2) == 0 {
+		return nil
+	}
+	s := r.string()
+	return &s
+}
+
+func (r randomBlueprints) version() blueprint_schema.GameVersion {
+	return blueprint_schema.NewGameVersion(uint16(r.Intn(3)), uint16(r.Intn(100)), uint16(r.Intn(100)), uint16(r.Intn(1<<16)))
+}
+
+func (r randomBlueprints) blueprint() *blueprint_schema.Blueprint {
+	signalType := blueprint_schema.SignalIDTypeItem
+	bp := &blueprint_schema.Blueprint{
+		Item:        "blueprint",
+		Label:       r.optString(),
+		LabelColor:  r.color(),
+		Description: r.optString(),
+		Version:     r.version(),
+		Extra:       r.extra(),
+	}
+	for i := 0; i <= r.Intn(4); i++ {
+		bp.Icons = append(bp.Icons, blueprint_schema.Icon{Index: i + 1, Signal: blueprint_schema.SignalID{Name: r.name(), Type: &signalType}})
+	}
+
+	n := r.Intn(20)
+	for i := 1; i <= n; i++ {
+		e := blueprint_schema.Entity{
+			EntityNumber: i,
+			Name:         r.name(),
+			Position:     r.position(),
+			Color:        r.color(),
+			Extra:        r.extra(),
+		}
+		if r.Intn(2) == 0 {
+			d := r.Intn(16)
+			e.Direction = &d
+		}
+		if r.Intn(3) == 0 {
+			qualities := []blueprint_schema.Quality{blueprint_schema.QualityNormal, blueprint_schema.QualityRare, blueprint_schema.QualityLegendary}
+			q := qualities[r.Intn(len(qualities))]
+			e.Quality = &q
+		}
+		if r.Intn(4) == 0 {
+			e.Tags = blueprint_schema.EntityTags{"text": r.string(), "on": r.Intn(2) == 0}
+		}
+		if r.Intn(4) == 0 {
+			e.Neighbours = []int{1 + r.Intn(n)}
+		}
+		if n > 1 && r.Intn(3) == 0 {
+			bp.Wires = append(bp.Wires, blueprint_schema.Wire{i, 1 + r.Intn(2), 1 + r.Intn(n), 1 + r.Intn(2)})
+		}
+		bp.Entities = append(bp.Entities, e)
+	}
+	for i := r.Intn(10); i > 0; i-- {
+		p := r.position()
+		p.X, p.Y = float64(int(p.X)), float64(int(p.Y))
+		bp.Tiles = append(bp.Tiles, blueprint_schema.Tile{Name: "concrete", Position: p, Extra: r.extra()})
+	}
+	return bp
+}
+
+func (r randomBlueprints) book(depth int) *blueprint_schema.BlueprintBook {
+	book := &blueprint_schema.BlueprintBook{
+		Item:    blueprint_schema.BlueprintBookItemBlueprintBook,
+		Label:   r.optString(),
+		Version: r.version(),
+		Extra:   r.extra(),
+	}
+	for i := 0; i <= r.Intn(3); i++ {
+		entry := blueprint_schema.BlueprintBookBlueprintsElem{Index: i, Extra: r.extra()}
+		if depth < 3 && r.Intn(4) == 0 {
+			entry.BlueprintBook = r.book(depth + 1)
+		} else {
+			entry.Blueprint = r.blueprint()
+		}
+		book.Blueprints = append(book.Blueprints, entry)
+	}
+	if r.Intn(2) == 0 {
+		active := r.Intn(len(book.Blueprints))
+		book.ActiveIndex = &active
+	}
+	return book
+}
+
+func (r randomBlueprints) schema() blueprint_schema.BlueprintSchemaJSON {
+	if r.Intn(3) == 0 {
+		return blueprint_schema.BlueprintSchemaJSON{BlueprintBook: r.book(1), Extra: r.extra()}
+	}
+	return blueprint_schema.BlueprintSchemaJSON{Blueprint: r.blueprint(), Extra: r.extra()}
+}
+
+// clearLayouts sets the Layout field of every schema struct in rv to nil, as
+// values built in code have none.
+func clearLayouts(rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !rv.IsNil() {
+			clearLayouts(rv.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			f := rv.Field(i)
+			if !f.CanSet() {
+				continue
+			}
+			if _, ok := f.Interface().(*blueprint_schema.ObjectLayout); ok {
+				f.Set(reflect.Zero(f.Type()))
+				continue
+			}
+			clearLayouts(f)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			clearLayouts(rv.Index(i))
+		}
+	}
+}
+
+// TestRoundTrip_random checks that random blueprints and books are the same
+// after being encoded into strings and decoded again, with and without the
+// fields unknown to the schema.
+func TestRoundTrip_random(t *testing.T) {
+	for _, lossless := range []bool{false, true} {
+		r := randomBlueprints{Rand: rand.New(rand.NewSource(1)), lossless: lossless}
+		for i := 0; i < 200; i++ {
+			want := r.schema()
+
+			var buf bytes.Buffer
+			encode, decode := write_blueprint.FromStruct, AsStruct
+			if lossless {
+				encode, decode = write_blueprint.FromStructLossless, AsStructLossless
+			}
+			if err := encode(&buf, want); err != nil {
+				t.Fatalf("Failed to encode blueprint: %v", err)
+			}
+			decompressed, err := AsJSONReader(&buf)
+			if err != nil {
+				t.Fatalf("Failed to decompress JSON: %v", err)
+			}
+			got, err := decode(decompressed)
+			if err != nil {
+				t.Fatalf("Failed to decode JSON: %v", err)
+			}
+			clearLayouts(reflect.ValueOf(&got))
+
+			if !reflect.DeepEqual(got, want) {
+				g, _ := blueprint_schema.MarshalLossless(got)
+				w, _ := blueprint_schema.MarshalLossless(want)
+				t.Fatalf("Bad data: lossless %v, value %d changed after round trip:\ngot:  %s\nwant: %s", lossless, i, g, w)
+			}
+		}
+	}
+}
+
+// TestStreamBook checks that the entries and the rest of a book streamed by
+// StreamBook are the same as those decoded by AsStructLossless.
+func TestStreamBook(t *testing.T) {
+	for _, filename := range []string{"testdata/lossless/book.json", "testdata/lossless/book_nested.json", "testdata/lossless/book_planners.json"} {
+		t.Run(filepath.Base(filename), func(t *testing.T) {
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("Failed to read corpus file: %v", err)
+			}
+			want, err := AsStructLossless(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Failed to decode JSON: %v", err)
+			}
+
+			var entries []blueprint_schema.BlueprintBookBlueprintsElem
+			got, err := StreamBook(bytes.NewReader(data), func(entry blueprint_schema.BlueprintBookBlueprintsElem) error {
+				entries = append(entries, entry)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamBook() failed: %v", err)
+			}
+			if got.BlueprintBook.Blueprints != nil {
+				t.Fatalf("Bad data: want no entries in the book got '%v'", got.BlueprintBook.Blueprints)
+			}
+			got.BlueprintBook.Blueprints = entries
+
+			if !reflect.DeepEqual(got, want) {
+				g, _ := blueprint_schema.MarshalLossless(got)
+				w, _ := blueprint_schema.MarshalLossless(want)
+				t.Fatalf("Bad data: want = '%s' got '%s'", w, g)
+			}
+		})
+	}
+}
+
+func TestStreamBook_errors(t *testing.T) {
+	book := `{"blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": [{"index": 0, "blueprint": {"item": "blueprint", "icons": [], "version": 0}}, {"index": 1}]}}`
+	stop := errors.New("stop")
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		fn    func(entry blueprint_schema.BlueprintBookBlueprintsElem) error
+		want  string
+	}{
+		{"blueprint", SimpleJSON, nil, "not a blueprint book"},
+		{"empty object", "{}", nil, "not a blueprint book"},
+		{"callback", book, func(blueprint_schema.BlueprintBookBlueprintsElem) error { return stop }, "stop"},
+		{"truncated", book[:100], nil, "JSON syntax error at line 1, column 100: unexpected end of JSON input"},
+		{"bad character", "{\n" + book[1:100] + "x", nil, "JSON syntax error at line 2, column 100: invalid character 'x'"},
+		{"no entries", `{"blueprint_book": {"item": "blueprint-book", "version": 0}}`, nil, "schema mismatch: field blueprints in BlueprintBook: required"},
+		{"entries not an array", `{"blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": {}}}`, nil, "schema mismatch: want [, got {"},
+		{"bad entry", `{"blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": [{"index": "a"}]}}`, nil, "schema mismatch: "},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fn := tc.fn
+			if fn == nil {
+				fn = func(blueprint_schema.BlueprintBookBlueprintsElem) error { return nil }
+			}
+			_, err := StreamBook(strings.NewReader(tc.input), fn)
+			if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+				t.Fatalf("Bad data: want = '%v' got '%v'", tc.want, err)
+			}
+			var syntax *SyntaxError
+			var schema *SchemaError
+			if strings.HasPrefix(tc.want, "JSON") && !errors.As(err, &syntax) || strings.HasPrefix(tc.want, "schema") && !errors.As(err, &schema) {
+				t.Fatalf("Bad data: wrong error type '%T'", err)
+			}
+		})
+	}
+}
+
+// TestStreamBookWithLimits checks that limits are hit while streaming, and
+// that errors of a blueprint string come from its reader.
+func TestStreamBookWithLimits(t *testing.T) {
+	book := `{"blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": [
+		{"index": 0, "blueprint": {"item": "blueprint", "icons": [], "version": 0, "entities": [
+			{"entity_number": 1, "name": "wooden-chest", "position": {"x": 0.5, "y": 0.5}},
+			{"entity_number": 2, "name": "wooden-chest", "position": {"x": 1.5, "y": 0.5}}
+		]}},
+		{"index": 1, "blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": []}}
+	]}}`
+
+	for _, tc := range []struct {
+		name   string
+		limits Limits
+		want   string
+	}{
+		{"none", Limits{}, ""},
+		{"decompressed", Limits{MaxDecompressedBytes: 100}, "limit MaxDecompressedBytes of 100 exceeded"},
+		{"nesting", Limits{MaxNesting: 7}, "limit MaxNesting of 7 exceeded"},
+		{"nesting fits", Limits{MaxNesting: 8}, ""},
+		{"entities", Limits{MaxEntities: 1}, "limit MaxEntities of 1 exceeded"},
+		{"book depth", Limits{MaxBookDepth: 1}, "limit MaxBookDepth of 1 exceeded"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := StreamBookWithLimits(strings.NewReader(book), tc.limits, func(blueprint_schema.BlueprintBookBlueprintsElem) error { return nil })
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("StreamBookWithLimits() failed: %v", err)
+				}
+				return
+			}
+			var limit *LimitError
+			if !errors.As(err, &limit) || err.Error() != tc.want {
+				t.Fatalf("Bad data: want = '%v' got '%v'", tc.want, err)
+			}
+		})
+	}
+
+	// A string long enough not to be read at once, broken near its end.
+	var js strings.Builder
+	js.WriteString(`{"blueprint_book": {"item": "blueprint-book", "version": 0, "blueprints": [{"index": 0, "blueprint": {"item": "blueprint", "icons": [], "version": 0, "entities": [`)
+	for i := 1; i <= 2000; i++ {
+		if i > 1 {
+			js.WriteString(",")
+		}
+		fmt.Fprintf(&js, `{"entity_number": %d, "name": "wooden-chest", "position": {"x": %d.5, "y": %d.5}}`, i, i*7919%1000, i*104729%1000)
+	}
+	js.WriteString("]}}]}}")
+	var s bytes.Buffer
+	if err := write_blueprint.FromJSON(&s, strings.NewReader(js.String())); err != nil {
+		t.Fatalf("FromJSON() failed: %v", err)
+	}
+	str := strings.TrimSpace(s.String())
+	broken := str[:len(str)-10] + "!" + str[len(str)-9:]
+	decompressed, err := AsJSONReader(strings.NewReader(broken))
+	if err != nil {
+		t.Fatalf("AsJSONReader() failed: %v", err)
+	}
+	_, err = StreamBook(decompressed, func(blueprint_schema.BlueprintBookBlueprintsElem) error { return nil })
+	var b64 *Base64Error
+	if !errors.As(err, &b64) || b64.Offset != int64(len(str)-10) {
+		t.Fatalf("Bad data: want = 'base64 error at offset %d' got '%v'", len(str)-10, err)
+	}
+}
+
+// hugeBook returns the JSON of a book of blueprints, each with the given
+// number of entities.
+func hugeBook(b *testing.B, blueprints, entities int) []byte {
+	var bp blueprint_schema.Blueprint
+	if err := json.Unmarshal([]byte(`{"item": "blueprint", "icons": [], "version": 562949954076673}`), &bp); err != nil {
+		b.Fatalf("Failed to decode JSON: %v", err)
+	}
+	for i := 1; i <= entities; i++ {
+		direction := 4
+		bp.Entities = append(bp.Entities, blueprint_schema.Entity{
+			EntityNumber: i,
+			Name:         "transport-belt",
+			Position:     blueprint_schema.Position{X: float64(i%100) + 0.5, Y: float64(i/100) + 0.5},
+			Direction:    &direction,
+		})
+	}
+	book := blueprint_schema.BlueprintSchemaJSON{BlueprintBook: &blueprint_schema.BlueprintBook{Item: "blueprint-book", Version: bp.Version}}
+	for i := 0; i < blueprints; i++ {
+		book.BlueprintBook.Blueprints = append(book.BlueprintBook.Blueprints, blueprint_schema.BlueprintBookBlueprintsElem{Index: i, Blueprint: &bp})
+	}
+	data, err := json.Marshal(book)
+	if err != nil {
+		b.Fatalf("Failed to encode JSON: %v", err)
+	}
+	return data
+}
+
+// hugeBookString returns the blueprint string of hugeBook, and the length of
+// its JSON.
+func hugeBookString(b *testing.B, blueprints, entities int) (string, int64) {
+	data := hugeBook(b, blueprints, entities)
+	var s bytes.Buffer
+	if err := write_blueprint.FromJSON(&s, bytes.NewReader(data)); err != nil {
+		b.Fatalf("FromJSON() failed: %v", err)
+	}
+	return s.String(), int64(len(data))
+}
+
+// hugeBooks are the shapes of books the benchmarks decode: a few large
+// entries, and many entries which are small relative to the whole book.
+var hugeBooks = []struct {
+	name                 string
+	blueprints, entities int
+}{
+	{"large_entries", 50, 500},
+	{"small_entries", 2500, 10},
+}
+
+// runPeakHeap calls fn b.N times, and reports the peak of the heap in use
+// while it runs, above what was in use before, as peak-heap-B. The heap is
+// sampled every millisecond, so short peaks may be missed.
+func runPeakHeap(b *testing.B, fn func()) {
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var peak uint64
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		var ms runtime.MemStats
+		for {
+			runtime.ReadMemStats(&ms)
+			if ms.HeapInuse > peak {
+				peak = ms.HeapInuse
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fn()
+	}
+	b.StopTimer()
+	close(stop)
+	<-done
+
+	if peak < before.HeapInuse {
+		peak = before.HeapInuse
+	}
+	b.ReportMetric(float64(peak-before.HeapInuse), "peak-heap-B")
+}
+
+func BenchmarkAsStruct_book(b *testing.B) {
+	for _, hb := range hugeBooks {
+		b.Run(hb.name, func(b *testing.B) {
+			s, n := hugeBookString(b, hb.blueprints, hb.entities)
+			b.SetBytes(n)
+			b.ReportAllocs()
+			runPeakHeap(b, func() {
+				decompressed, err := AsJSONReader(strings.NewReader(s))
+				if err != nil {
+					b.Fatalf("AsJSONReader() failed: %v", err)
+				}
+				m, err := AsStructLossless(decompressed)
+				if err != nil {
+					b.Fatalf("Failed to decode JSON: %v", err)
+				}
+				if len(m.BlueprintBook.Blueprints) != hb.blueprints {
+					b.Fatalf("Bad data: want %d entries got %d", hb.blueprints, len(m.BlueprintBook.Blueprints))
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkStreamBook(b *testing.B) {
+	for _, hb := range hugeBooks {
+		b.Run(hb.name, func(b *testing.B) {
+			s, n := hugeBookString(b, hb.blueprints, hb.entities)
+			b.SetBytes(n)
+			b.ReportAllocs()
+			runPeakHeap(b, func() {
+				decompressed, err := AsJSONReader(strings.NewReader(s))
+				if err != nil {
+					b.Fatalf("AsJSONReader() failed: %v", err)
+				}
+				n := 0
+				_, err = StreamBook(decompressed, func(entry blueprint_schema.BlueprintBookBlueprintsElem) error {
+					n++
+					return nil
+				})
+				if err != nil {
+					b.Fatalf("StreamBook() failed: %v", err)
+				}
+				if n != hb.blueprints {
+					b.Fatalf("Bad data: want %d entries got %d", hb.blueprints, n)
+				}
+			})
+		})
+	}
+}
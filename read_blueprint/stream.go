@@ -0,0 +1,277 @@
+package read_blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// ErrNotBook is returned by StreamBook for a blueprint or a planner which is
+// not in a book.
+var ErrNotBook = errors.New("not a blueprint book")
+
+// StreamBook decodes the JSON of a blueprint book from AsJSONReader one entry
+// at a time, and calls fn with each entry of the book in order, so that only
+// one entry is in memory at a time instead of the whole book. Entries are
+// decoded like AsStructLossless does. A book nested in the book is a single
+// entry.
+//
+// If fn returns an error, StreamBook stops and returns it. Otherwise it
+// returns everything but the entries of the book once all are read, with
+// Blueprints of the book set to nil. Errors in the JSON are returned as a
+// *SyntaxError or a *SchemaError, and errors of the string as returned by
+// the reader of AsJSONReader.
+//
+// As AsJSONReader decompresses a blueprint string while it is read, the
+// memory used is in proportion to the largest entry, not to the book.
+func StreamBook(decompressed io.Reader, fn func(entry blueprint_schema.BlueprintBookBlueprintsElem) error) (m blueprint_schema.BlueprintSchemaJSON, err error) {
+	return StreamBookWithLimits(decompressed, Limits{}, fn)
+}
+
+// StreamBookWithLimits works like StreamBook, but returns a *LimitError if
+// the JSON is longer, more nested, or has more entities, tiles or nested
+// books than limits allow, as AsStructWithLimits does. Entries before the
+// one hitting a limit have been passed to fn.
+func StreamBookWithLimits(decompressed io.Reader, limits Limits, fn func(entry blueprint_schema.BlueprintBookBlueprintsElem) error) (m blueprint_schema.BlueprintSchemaJSON, err error) {
+	lines := &lineCounter{r: newLimitedReader(decompressed, "MaxDecompressedBytes", limits.MaxDecompressedBytes)}
+	s := &bookStream{d: json.NewDecoder(lines), lines: lines, limits: limits, counter: &counter{limits: limits}}
+
+	fields, err := s.object(func(key string) (bool, error) {
+		switch key {
+		case "blueprint_book":
+			return true, s.book(&m, fn)
+		case "blueprint", "upgrade_planner", "deconstruction_planner":
+			return true, ErrNotBook
+		}
+		return false, nil
+	})
+	if err != nil {
+		return m, err
+	}
+	if m.BlueprintBook == nil {
+		return m, ErrNotBook
+	}
+
+	// Decode the other fields around the book, such as those added by mods.
+	book := m.BlueprintBook
+	m.BlueprintBook = nil
+	placeholder := json.RawMessage(`{"item": "blueprint-book", "version": 0, "blueprints": []}`)
+	if err := blueprint_schema.UnmarshalLossless(join(fields, placeholder), &m); err != nil {
+		return m, &SchemaError{Err: err}
+	}
+	m.BlueprintBook = book
+	return m, nil
+}
+
+// bookStream reads a book token by token.
+type bookStream struct {
+	d     *json.Decoder
+	lines *lineCounter
+
+	limits  Limits
+	counter *counter
+}
+
+// entryDepth is the depth of the entries of a book in the JSON: in the array
+// of entries, in the book, in the object around everything.
+const entryDepth = 3
+
+// book reads the object of a book, calling fn for each entry, and sets the
+// book of m to the other fields.
+func (s *bookStream) book(m *blueprint_schema.BlueprintSchemaJSON, fn func(entry blueprint_schema.BlueprintBookBlueprintsElem) error) error {
+	sawEntries := false
+	fields, err := s.object(func(key string) (bool, error) {
+		if key != "blueprints" {
+			return false, nil
+		}
+		sawEntries = true
+		return true, s.entries(fn)
+	})
+	if err != nil {
+		return err
+	}
+	if !sawEntries {
+		return &SchemaError{Err: errors.New("field blueprints in BlueprintBook: required")}
+	}
+
+	var book blueprint_schema.BlueprintBook
+	if err := blueprint_schema.UnmarshalLossless(join(fields, json.RawMessage(`[]`)), &book); err != nil {
+		return &SchemaError{Err: err}
+	}
+	book.Blueprints = nil
+	m.BlueprintBook = &book
+	return nil
+}
+
+// entries reads the array of entries of a book, calling fn for each.
+func (s *bookStream) entries(fn func(entry blueprint_schema.BlueprintBookBlueprintsElem) error) error {
+	if err := s.delim('['); err != nil {
+		return err
+	}
+	for s.d.More() {
+		var raw json.RawMessage
+		if err := s.d.Decode(&raw); err != nil {
+			return s.syntaxError(err)
+		}
+		if n := s.limits.MaxNesting; n > 0 {
+			if n <= entryDepth || checkNesting(raw, n-entryDepth) != nil {
+				return &LimitError{Limit: "MaxNesting", Max: int64(n)}
+			}
+		}
+		var entry blueprint_schema.BlueprintBookBlueprintsElem
+		if err := blueprint_schema.UnmarshalLossless(raw, &entry); err != nil {
+			return &SchemaError{Err: err}
+		}
+		if entry.Blueprint != nil {
+			s.counter.blueprint(entry.Blueprint)
+		}
+		if entry.BlueprintBook != nil {
+			s.counter.book(entry.BlueprintBook, 2)
+		}
+		if s.counter.err != nil {
+			return s.counter.err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return s.delim(']')
+}
+
+// object reads an object. For each key, field is called with the decoder at
+// the value; if it reads the value itself it returns true, otherwise the
+// value is kept in the returned fields, which are in the order of the object.
+// Fields read by field are returned without a value.
+func (s *bookStream) object(field func(key string) (bool, error)) ([]rawField, error) {
+	if err := s.delim('{'); err != nil {
+		return nil, err
+	}
+	var fields []rawField
+	for s.d.More() {
+		t, err := s.d.Token()
+		if err != nil {
+			return nil, s.syntaxError(err)
+		}
+		key, ok := t.(string)
+		if !ok {
+			return nil, &SchemaError{Err: fmt.Errorf("want object key, got %v", t)}
+		}
+		if read, err := field(key); err != nil {
+			return nil, err
+		} else if read {
+			fields = append(fields, rawField{key: key})
+			continue
+		}
+		var raw json.RawMessage
+		if err := s.d.Decode(&raw); err != nil {
+			return nil, s.syntaxError(err)
+		}
+		fields = append(fields, rawField{key: key, value: raw})
+	}
+	return fields, s.delim('}')
+}
+
+// delim reads the delimiter want.
+func (s *bookStream) delim(want json.Delim) error {
+	t, err := s.d.Token()
+	if err != nil {
+		return s.syntaxError(err)
+	}
+	if t != want {
+		return &SchemaError{Err: fmt.Errorf("want %v, got %v", want, t)}
+	}
+	return nil
+}
+
+// errUnexpectedEnd is the error of encoding/json for JSON which ends early,
+// which has no exported constructor.
+var errUnexpectedEnd = func() *json.SyntaxError {
+	var v interface{}
+	return json.Unmarshal([]byte("["), &v).(*json.SyntaxError)
+}()
+
+// syntaxError turns an error of the decoder into a SyntaxError. Errors of
+// reading the JSON, such as those of AsJSONReader, are kept.
+func (s *bookStream) syntaxError(err error) error {
+	var syntax *json.SyntaxError
+	switch {
+	case errors.As(err, &syntax):
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		// The input ended: everything has been read.
+		end := *errUnexpectedEnd
+		end.Offset = s.lines.offset
+		syntax = &end
+	default:
+		return err
+	}
+	line, column := s.lines.position(syntax.Offset)
+	return &SyntaxError{Offset: syntax.Offset, Line: line, Column: column, Err: syntax}
+}
+
+// lineCounter keeps the offsets of the line breaks read, to tell the line
+// and column of an offset without keeping the JSON. Blueprint strings have
+// their JSON on a single line.
+type lineCounter struct {
+	r      io.Reader
+	offset int64
+	breaks []int64
+}
+
+func (l *lineCounter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	for i, c := range p[:n] {
+		if c == '\n' {
+			l.breaks = append(l.breaks, l.offset+int64(i))
+		}
+	}
+	l.offset += int64(n)
+	return n, err
+}
+
+// position returns the line and column of offset, both starting at 1, like
+// the function position does for JSON in memory.
+func (l *lineCounter) position(offset int64) (line, column int) {
+	line = sort.Search(len(l.breaks), func(i int) bool { return l.breaks[i] >= offset })
+	start := int64(0)
+	if line > 0 {
+		start = l.breaks[line-1] + 1
+	}
+	column = int(offset - start)
+	if column == 0 {
+		column = 1
+	}
+	return line + 1, column
+}
+
+// rawField is a key of an object and its value.
+type rawField struct {
+	key   string
+	value json.RawMessage
+}
+
+// join puts fields back together into an object, in their order, with
+// placeholder as the value of fields without one.
+func join(fields []rawField, placeholder json.RawMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, f := range fields {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(f.key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		if f.value == nil {
+			buf.Write(placeholder)
+		} else {
+			buf.Write(f.value)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
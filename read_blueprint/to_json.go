@@ -3,19 +3,40 @@ package read_blueprint
 import (
 	"bytes"
 	"encoding/base64"
-	"fmt"
+	"errors"
 	"io"
+	"sync"
 
 	"github.com/klauspost/compress/zlib" // we could use compress/zlib from stdlib
 )
 
+// AsJSONReader returns a reader of the JSON in a blueprint string. If the
+// string starts with '{', it is assumed to be JSON already and is passed
+// through.
+//
+// Otherwise the string is decoded and decompressed as the returned reader is
+// read, so that neither the string nor the JSON has to be in memory as a
+// whole. The version byte and the zlib header are checked up front; a broken
+// string is reported with the error types of this package either by
+// AsJSONReader or, for errors further into the string, by the returned
+// reader.
 func AsJSONReader(r io.Reader) (io.Reader, error) {
+	return AsJSONReaderWithLimits(r, Limits{})
+}
+
+// AsJSONReaderWithLimits works like AsJSONReader, but returns a *LimitError if
+// the string is longer than limits.MaxCompressedLength, or if the JSON is
+// longer than limits.MaxDecompressedBytes. Limits hit after the first bytes
+// are returned by the returned reader.
+func AsJSONReaderWithLimits(r io.Reader, limits Limits) (io.Reader, error) {
+	r = newLimitedReader(r, "MaxCompressedLength", limits.MaxCompressedLength)
+
 	// Read first byte.
 	var version = make([]byte, 1)
-	if n, err := r.Read(version); err != nil {
-		return nil, fmt.Errorf("failed to read version byte: %w", err)
-	} else if n != 1 {
-		return nil, fmt.Errorf("failed to read version byte: read %v bytes", n)
+	if _, err := io.ReadFull(r, version); err == io.EOF {
+		return nil, ErrEmpty
+	} else if err != nil {
+		return nil, err
 	}
 
 	// Assert version byte is rune 0 or rune {. If it's {, assume it's JSON.
@@ -23,21 +44,197 @@ func AsJSONReader(r io.Reader) (io.Reader, error) {
 	case '{':
 		// Return a new reader with the version byte prepended.
 		r2 := io.MultiReader(bytes.NewReader(version), r)
-		return r2, nil
+		return newLimitedReader(r2, "MaxDecompressedBytes", limits.MaxDecompressedBytes), nil
 	case '0':
 		// Continue.
 	default:
-		return nil, fmt.Errorf("version byte: got = '%c', want = '{' or '0'", v)
+		return nil, &VersionError{Version: v}
 	}
 
-	// Decode b64 using standard encoding and io.Reader.
-	decoder := base64.NewDecoder(base64.StdEncoding, r)
-
-	// Decompress zlib.
-	decompressed, err := zlib.NewReader(decoder)
+	// Decode b64 using standard encoding, then decompress zlib. Reading the
+	// zlib header checks the first bytes of both.
+	b64 := &base64Reader{r: &lineBreakSkipper{r: r}, offset: 1}
+	zr, err := newZlibReader(b64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start decompressing with zlib: %w", err)
+		return nil, zlibError(err, b64.offset)
+	}
+	return &jsonReader{
+		b64:     b64,
+		zr:      zr,
+		limited: newLimitedReader(zr, "MaxDecompressedBytes", limits.MaxDecompressedBytes),
+	}, nil
+}
+
+// jsonReader reads the JSON out of a blueprint string, turning errors into
+// the error types of this package.
+type jsonReader struct {
+	b64     *base64Reader
+	zr      io.ReadCloser
+	limited io.Reader
+	err     error
+}
+
+func (j *jsonReader) Read(p []byte) (int, error) {
+	if j.err != nil {
+		return 0, j.err
+	}
+	n, err := j.limited.Read(p)
+	if err == io.EOF {
+		// The checksum is checked before zlib returns io.EOF.
+		if cerr := j.zr.Close(); cerr != nil {
+			err = zlibError(cerr, j.b64.offset)
+		} else {
+			zlibReaders.Put(j.zr)
+		}
+		j.zr = nil
+	} else if err != nil {
+		err = zlibError(err, j.b64.offset)
+	}
+	j.err = err
+	return n, err
+}
+
+// lineBreakSkipper drops line breaks, like base64.NewDecoder does.
+type lineBreakSkipper struct {
+	r io.Reader
+}
+
+func (l *lineBreakSkipper) Read(p []byte) (int, error) {
+	for {
+		n, err := l.r.Read(p)
+		kept := 0
+		for _, c := range p[:n] {
+			if c != '\r' && c != '\n' {
+				p[kept] = c
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
 	}
+}
+
+// base64Reader decodes base64 in the standard encoding, returning a
+// *Base64Error with the offset in the string for a bad byte, and a
+// *TruncatedError if the string ends within a group of four bytes.
+// base64.NewDecoder does neither.
+type base64Reader struct {
+	r io.Reader
 
-	return decompressed, nil
+	// offset is the offset in the string of buf[0], counting the version
+	// byte. Once the string is read, it is the length of the string.
+	offset int64
+
+	buf     [4096]byte // base64 not yet decoded
+	nbuf    int
+	decoded [3072]byte
+	out     []byte // decoded but not yet read
+	err     error
+}
+
+func (b *base64Reader) Read(p []byte) (int, error) {
+	for len(b.out) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		b.fill()
+	}
+	n := copy(p, b.out)
+	b.out = b.out[n:]
+	return n, nil
+}
+
+// fill decodes the next groups of four bytes into out, or sets err.
+func (b *base64Reader) fill() {
+	var err error
+	for b.nbuf < 4 && err == nil {
+		var n int
+		n, err = b.r.Read(b.buf[b.nbuf:])
+		b.nbuf += n
+	}
+	if err != nil && err != io.EOF {
+		b.err = err
+		return
+	}
+	eof := err == io.EOF
+
+	encoded := b.buf[:b.nbuf]
+	if !eof {
+		encoded = encoded[:len(encoded)/4*4]
+	}
+	n, derr := base64.StdEncoding.Decode(b.decoded[:], encoded)
+	if corrupt, ok := derr.(base64.CorruptInputError); ok {
+		if eof && truncatedBase64(encoded, int(corrupt)) {
+			b.err = &TruncatedError{Length: b.offset + int64(b.nbuf)}
+		} else {
+			b.err = &Base64Error{Offset: b.offset + int64(corrupt)}
+		}
+		return
+	} else if derr != nil {
+		b.err = derr
+		return
+	}
+	b.out = b.decoded[:n]
+
+	b.offset += int64(len(encoded))
+	b.nbuf = copy(b.buf[:], b.buf[len(encoded):b.nbuf])
+	if eof {
+		b.err = io.EOF
+	}
+}
+
+// zlibReaders holds zlib readers for reuse, as each one allocates buffers of
+// tens of kilobytes, and strings are often decoded by the thousand.
+var zlibReaders sync.Pool
+
+// newZlibReader returns a zlib reader of r, reusing one from zlibReaders if
+// there is one. It is put back into zlibReaders once done with.
+func newZlibReader(r io.Reader) (io.ReadCloser, error) {
+	if zr, ok := zlibReaders.Get().(io.ReadCloser); ok {
+		if err := zr.(zlib.Resetter).Reset(r, nil); err != nil {
+			zlibReaders.Put(zr)
+			return nil, err
+		}
+		return zr, nil
+	}
+	return zlib.NewReader(r)
+}
+
+const base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/="
+
+// truncatedBase64 returns whether the base64 encoding failed at offset only
+// because the last group of four bytes is incomplete.
+func truncatedBase64(encoded []byte, offset int) bool {
+	rest := encoded[offset:]
+	if len(rest) >= 4 {
+		return false
+	}
+	for _, c := range rest {
+		if !bytes.ContainsRune([]byte(base64Alphabet), rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// zlibError turns an error from zlib into a TruncatedError if the compressed
+// data ended early, and a ZlibError otherwise. Errors of the string below
+// zlib, such as LimitErrors and Base64Errors, are kept.
+func zlibError(err error, length int64) error {
+	var limit *LimitError
+	var b64 *Base64Error
+	var truncated *TruncatedError
+	switch {
+	case errors.As(err, &limit):
+		return limit
+	case errors.As(err, &b64):
+		return b64
+	case errors.As(err, &truncated):
+		return truncated
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return &TruncatedError{Length: length}
+	}
+	return &ZlibError{Err: err}
 }
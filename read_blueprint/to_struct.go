@@ -3,13 +3,51 @@ package read_blueprint
 import (
 	"encoding/json"
 	"io"
+	"io/ioutil"
 
 	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
 )
 
+// AsStruct decodes the JSON from AsJSONReader. Errors in the JSON are
+// returned as a *SyntaxError or a *SchemaError.
 func AsStruct(decompressed io.Reader) (m blueprint_schema.BlueprintSchemaJSON, err error) {
-	d := json.NewDecoder(decompressed)
-	if err := d.Decode(&m); err != nil {
+	return AsStructWithLimits(decompressed, Limits{})
+}
+
+// AsStructWithLimits works like AsStruct, but returns a *LimitError if the
+// JSON is longer, more nested, or has more entities, tiles or nested books
+// than limits allow.
+func AsStructWithLimits(decompressed io.Reader, limits Limits) (m blueprint_schema.BlueprintSchemaJSON, err error) {
+	return decodeWithLimits(decompressed, limits, json.Unmarshal)
+}
+
+// AsStructLossless works like AsStruct, but fields which are not known to the
+// schema are kept in the Extra field of each struct instead of being dropped.
+// Use write_blueprint.FromStructLossless to write them out again.
+func AsStructLossless(decompressed io.Reader) (m blueprint_schema.BlueprintSchemaJSON, err error) {
+	return AsStructLosslessWithLimits(decompressed, Limits{})
+}
+
+// AsStructLosslessWithLimits works like AsStructLossless, with the limits of
+// AsStructWithLimits.
+func AsStructLosslessWithLimits(decompressed io.Reader, limits Limits) (m blueprint_schema.BlueprintSchemaJSON, err error) {
+	return decodeWithLimits(decompressed, limits, blueprint_schema.UnmarshalLossless)
+}
+
+// decodeWithLimits reads the JSON and decodes it with unmarshal, checking the
+// limits before and after decoding.
+func decodeWithLimits(decompressed io.Reader, limits Limits, unmarshal func([]byte, interface{}) error) (m blueprint_schema.BlueprintSchemaJSON, err error) {
+	data, err := ioutil.ReadAll(newLimitedReader(decompressed, "MaxDecompressedBytes", limits.MaxDecompressedBytes))
+	if err != nil {
+		return m, err
+	}
+	if err := checkNesting(data, limits.MaxNesting); err != nil {
+		return m, err
+	}
+	if err := unmarshal(data, &m); err != nil {
+		return m, jsonError(data, err)
+	}
+	if err := checkCounts(&m, limits); err != nil {
 		return m, err
 	}
 	return m, nil
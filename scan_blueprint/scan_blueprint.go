@@ -0,0 +1,205 @@
+// Package scan_blueprint finds blueprint strings in arbitrary text, such as
+// chat logs, markdown, forum posts and mod changelogs.
+//
+// A candidate is a '0' version byte followed by base64, with no base64 right
+// before it. It may be wrapped over several lines, indented or quoted with
+// '>', and be surrounded by code fences, tags or any other text. Each
+// candidate is checked by decoding it, so only strings which decode to JSON
+// are returned:
+//
+//	matches, err := scan_blueprint.Scan(f)
+//	if err != nil {
+//		return err
+//	}
+//	for _, m := range matches {
+//		fmt.Printf("%d: %s\n", m.Offset, m.String)
+//	}
+//
+// The public interface is unstable.
+package scan_blueprint // badc0de.net/pkg/factorioblueprint/scan_blueprint
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	"badc0de.net/pkg/factorioblueprint/read_blueprint"
+
+	"github.com/klauspost/compress/zlib"
+)
+
+// Match is a blueprint string found in text.
+type Match struct {
+	// Offset is the byte offset of the version byte in the text.
+	Offset int64
+
+	// End is the byte offset just past the last character of the string in
+	// the text. Line breaks and indentation of a wrapped string are between
+	// Offset and End.
+	End int64
+
+	// String is the blueprint string, without line breaks and indentation.
+	String string
+}
+
+// Scan returns every blueprint string in the text read from r, in the order
+// they appear. Candidates are decoded with read_blueprint.DefaultLimits, as
+// text from anywhere can hold strings made to exhaust memory; those which hit
+// a limit are skipped like broken ones.
+func Scan(r io.Reader) ([]Match, error) {
+	return ScanWithLimits(r, read_blueprint.DefaultLimits)
+}
+
+// ScanWithLimits works like Scan, but decodes candidates with limits.
+func ScanWithLimits(r io.Reader, limits read_blueprint.Limits) ([]Match, error) {
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for i := 0; i < len(text); i++ {
+		if !startsCandidate(text, i) {
+			continue
+		}
+		if m, ok := decodeCandidate(text, i, limits); ok {
+			matches = append(matches, m)
+			i = int(m.End) - 1
+		}
+	}
+	return matches, nil
+}
+
+// startsCandidate tells whether a blueprint string can start at text[i]. The
+// zlib header of every string encodes to base64 starting with 'e'.
+func startsCandidate(text []byte, i int) bool {
+	return text[i] == '0' && i+1 < len(text) && text[i+1] == 'e' &&
+		(i == 0 || !isBase64(text[i-1]))
+}
+
+func isBase64(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '+' || c == '/' || c == '='
+}
+
+// span is a part of a candidate on one line.
+type span struct {
+	start, end int
+}
+
+// candidateSpans returns the parts of the candidate starting at text[start]:
+// a run of base64, and the runs at the start of the following lines for as
+// long as each run goes to the end of its line. Padding ends the candidate.
+func candidateSpans(text []byte, start int) []span {
+	var spans []span
+	i := start
+	for {
+		s := span{start: i}
+		for i < len(text) && isBase64(text[i]) && text[i] != '=' {
+			i++
+		}
+		for i < len(text) && text[i] == '=' {
+			i++
+		}
+		s.end = i
+		spans = append(spans, s)
+		if i > s.start && text[i-1] == '=' {
+			return spans
+		}
+
+		// Continue on the next line, past indentation and quote markers.
+		if i < len(text) && text[i] == '\r' {
+			i++
+		}
+		if i >= len(text) || text[i] != '\n' {
+			return spans
+		}
+		i++
+		for i < len(text) && (text[i] == ' ' || text[i] == '\t' || text[i] == '>') {
+			i++
+		}
+		if i >= len(text) || !isBase64(text[i]) {
+			return spans
+		}
+	}
+}
+
+// decodeCandidate decodes the candidate starting at text[start] with all of
+// its lines, and ends it where its compressed data ends, so that text after
+// a string is not taken as part of it. If that fails, such as for a string
+// which is broken in its last lines, it tries the candidate with one line
+// more each time instead.
+func decodeCandidate(text []byte, start int, limits read_blueprint.Limits) (Match, bool) {
+	spans := candidateSpans(text, start)
+	var b strings.Builder
+	for _, s := range spans {
+		b.Write(text[s.start:s.end])
+	}
+	all := b.String()
+
+	if length, ok := stringLength(all, limits); ok && valid(all[:length], limits) {
+		// Find the end of the string in the text.
+		n := length
+		for _, s := range spans {
+			if n <= s.end-s.start {
+				return Match{Offset: int64(start), End: int64(s.start + n), String: all[:length]}, true
+			}
+			n -= s.end - s.start
+		}
+	}
+
+	b.Reset()
+	for _, s := range spans {
+		b.Write(text[s.start:s.end])
+		if valid(b.String(), limits) {
+			return Match{Offset: int64(start), End: int64(s.end), String: b.String()}, true
+		}
+	}
+	return Match{}, false
+}
+
+// stringLength returns the length of the blueprint string at the start of s,
+// which may be followed by other base64, by decompressing it up to the end
+// of its compressed data. It does not check that the JSON is valid.
+func stringLength(s string, limits read_blueprint.Limits) (int, bool) {
+	encoded := s[1:]
+	encoded = encoded[:len(encoded)/4*4]
+	compressed := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	// Bytes up to a corrupt one are decoded, which may be all the string.
+	n, _ := base64.StdEncoding.Decode(compressed, []byte(encoded))
+
+	// A bytes.Reader is read by zlib byte by byte, without reading ahead, so
+	// what is left of it is after the end of the string.
+	br := bytes.NewReader(compressed[:n])
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return 0, false
+	}
+	max := limits.MaxDecompressedBytes
+	if max <= 0 {
+		max = math.MaxInt64 - 1
+	}
+	if n, err := io.Copy(ioutil.Discard, io.LimitReader(zr, max+1)); err != nil || n > max {
+		return 0, false
+	}
+
+	// Each group of three bytes is encoded as four, with padding.
+	length := 1 + (int(br.Size())-br.Len()+2)/3*4
+	if length > len(s) {
+		return 0, false
+	}
+	return length, true
+}
+
+// valid tells whether s decodes to JSON.
+func valid(s string, limits read_blueprint.Limits) bool {
+	r, err := read_blueprint.AsJSONReaderWithLimits(strings.NewReader(s), limits)
+	if err != nil {
+		return false
+	}
+	data, err := ioutil.ReadAll(r)
+	return err == nil && json.Valid(data)
+}
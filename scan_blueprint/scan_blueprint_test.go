@@ -0,0 +1,113 @@
+package scan_blueprint
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/write_blueprint"
+)
+
+// Example of finding a blueprint string in a chat log.
+func ExampleScan() {
+	log := "<alice> try this one: 0eNqrVkrKKU0tKMrMK1Gyqq6tBQA0RwZE (untested)\n<bob> thanks!\n"
+	matches, err := Scan(strings.NewReader(log))
+	if err != nil {
+		panic(err)
+	}
+	for _, m := range matches {
+		fmt.Printf("%d-%d: %s\n", m.Offset, m.End, m.String)
+	}
+
+	// Output:
+	// 22-55: 0eNqrVkrKKU0tKMrMK1Gyqq6tBQA0RwZE
+}
+
+// wrap breaks s into lines of n characters, each starting with prefix.
+func wrap(s string, n int, prefix, newline string) string {
+	var lines []string
+	for len(s) > n {
+		lines = append(lines, prefix+s[:n])
+		s = s[n:]
+	}
+	lines = append(lines, prefix+s)
+	return strings.Join(lines, newline)
+}
+
+func TestScan(t *testing.T) {
+	data, err := ioutil.ReadFile("../read_blueprint/simple.txt")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	simple := strings.TrimSpace(string(data))
+	small := "0eNqrVkrKKU0tKMrMK1Gyqq6tBQA0RwZE"
+
+	for _, test := range []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"alone", simple, []string{simple}},
+		{"markdown", "Here it is:\n\n```\n" + simple + "\n```\n\nEnjoy `" + small + "`.", []string{simple, small}},
+		{"html", "<p>Book:</p><pre><code>" + small + "</code></pre>", []string{small}},
+		{"wrapped", "Pasted:\n" + wrap(simple, 60, "", "\n") + "\nmore text", []string{simple}},
+		{"wrapped crlf", wrap(simple, 76, "    ", "\r\n") + "\r\n", []string{simple}},
+		{"quoted", "> " + wrap(simple, 50, "> ", "\n") + "\n> Thanks\n", []string{simple}},
+		{"next lines", small + "\n" + small + "\nabcd\n", []string{small, small}},
+		{"glued", "abc" + small, nil},
+		{"broken", "0eNbogus and " + simple[:100] + " and " + small, []string{small}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			matches, err := Scan(strings.NewReader(test.text))
+			if err != nil {
+				t.Fatalf("Scan() failed: %v", err)
+			}
+			var got []string
+			for _, m := range matches {
+				if test.text[m.Offset] != '0' || m.End <= m.Offset {
+					t.Errorf("Bad data: bad offsets %d-%d", m.Offset, m.End)
+				}
+				got = append(got, m.String)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Fatalf("Bad data: want = '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+}
+
+// BenchmarkScan_wrapped scans strings of growing size, wrapped at 76
+// columns, to show that scanning takes time in proportion to the text.
+func BenchmarkScan_wrapped(b *testing.B) {
+	for _, entities := range []int{1000, 4000, 16000} {
+		var js strings.Builder
+		js.WriteString(`{"blueprint": {"item": "blueprint", "version": 0, "icons": [], "entities": [`)
+		rnd := rand.New(rand.NewSource(1))
+		for i := 1; i <= entities; i++ {
+			if i > 1 {
+				js.WriteString(",")
+			}
+			fmt.Fprintf(&js, `{"entity_number": %d, "name": "transport-belt", "position": {"x": %d.5, "y": %d.5}, "direction": %d}`, i, rnd.Intn(1000), rnd.Intn(1000), rnd.Intn(4)*4)
+		}
+		js.WriteString("]}}")
+		var s bytes.Buffer
+		if err := write_blueprint.FromJSON(&s, strings.NewReader(js.String())); err != nil {
+			b.Fatalf("FromJSON() failed: %v", err)
+		}
+		text := "Here:\n" + wrap(strings.TrimSpace(s.String()), 76, "", "\n") + "\nThanks\n"
+
+		b.Run(fmt.Sprintf("%dKB", len(text)>>10), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				matches, err := Scan(strings.NewReader(text))
+				if err != nil || len(matches) != 1 {
+					b.Fatalf("Bad data: want = '1 match' got '%d' and '%v'", len(matches), err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package factorioblueprint
+
+import (
+	_ "embed"
+)
+
+// SchemaJSON is blueprint.schema.json, the JSON schema the structs in
+// schema/blueprint_schema are generated from.
+//
+//go:embed blueprint.schema.json
+var SchemaJSON []byte
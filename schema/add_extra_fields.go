@@ -0,0 +1,126 @@
+//go:build ignore
+// +build ignore
+
+// add_extra_fields adds an Extra and a Layout field to every struct type in a
+// file generated by go-jsonschema. Extra holds object keys which are not known
+// to the schema, so that decoding and encoding a blueprint does not lose them,
+// and Layout the order of the keys and the text of the values, so that an
+// unchanged blueprint is written out as it was read.
+//
+// Run it after every regeneration of the schema (cook_schema.sh does this):
+//
+//	$ go run schema/add_extra_fields.go -file schema/blueprint_schema/blueprint.schema.json.go
+//
+// Fields which a struct already has are not added again, so running it twice
+// is harmless.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+const extraField = `
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields ` + "`" + `json:"-" yaml:",inline" mapstructure:",remain"` + "`" + `
+`
+
+const layoutField = `
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout ` + "`" + `json:"-" yaml:"-" mapstructure:"-"` + "`" + `
+`
+
+var file = flag.String("file", "", "The generated file to update in place.")
+
+func main() {
+	flag.Parse()
+	if *file == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s -file <generated.go>\n", os.Args[0])
+		os.Exit(1)
+	}
+	filename := *file
+
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Collect offsets of closing braces of top-level struct types. Types
+	// declared inside functions (such as 'type Plain Entity') are not structs
+	// and are not visited.
+	type insertion struct {
+		offset int
+		text   string
+	}
+	var insertions []insertion
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			st, ok := spec.(*ast.TypeSpec).Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			var text string
+			if !hasField(st, "Extra") {
+				text += extraField
+			}
+			if !hasField(st, "Layout") {
+				text += layoutField
+			}
+			if text != "" {
+				insertions = append(insertions, insertion{fset.Position(st.Fields.Closing).Offset, text})
+			}
+		}
+	}
+
+	// Insert from the back so that earlier offsets stay valid.
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].offset > insertions[j].offset })
+	for _, ins := range insertions {
+		src = append(src[:ins.offset], append([]byte(ins.text), src[ins.offset:]...)...)
+	}
+
+	out, err := format.Source(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to format file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(filename, out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write file: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// hasField returns whether the struct has a field with the given name.
+func hasField(st *ast.StructType, name string) bool {
+	for _, field := range st.Fields.List {
+		for _, n := range field.Names {
+			if n.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -20,6 +20,17 @@ type ArithmeticConditions struct {
 
 	// Second input signal.
 	SecondSignal *SignalID `json:"second_signal,omitempty" yaml:"second_signal,omitempty" mapstructure:"second_signal,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // An object representing a Factorio blueprint.
@@ -30,8 +41,9 @@ type Blueprint struct {
 	// An optional description of the blueprint.
 	Description *string `json:"description,omitempty" yaml:"description,omitempty" mapstructure:"description,omitempty"`
 
-	// An array of entities included in the blueprint.
-	Entities []Entity `json:"entities" yaml:"entities" mapstructure:"entities"`
+	// An array of entities included in the blueprint. Left out by the game for
+	// blueprints of tiles only.
+	Entities []Entity `json:"entities,omitempty" yaml:"entities,omitempty" mapstructure:"entities,omitempty"`
 
 	// Icons set by the user for the blueprint.
 	Icons []Icon `json:"icons" yaml:"icons" mapstructure:"icons"`
@@ -57,8 +69,23 @@ type Blueprint struct {
 	// An array of tiles included in the blueprint.
 	Tiles []Tile `json:"tiles,omitempty" yaml:"tiles,omitempty" mapstructure:"tiles,omitempty"`
 
-	// The game version when the blueprint was created.
-	Version int `json:"version" yaml:"version" mapstructure:"version"`
+	// The game version, as four 16-bit parts, when the blueprint was created.
+	Version GameVersion `json:"version" yaml:"version" mapstructure:"version"`
+
+	// Wires between entities (new in Factorio 2.0, replaces connections and
+	// neighbours of entities).
+	Wires []Wire `json:"wires,omitempty" yaml:"wires,omitempty" mapstructure:"wires,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // An object representing a Factorio blueprint book.
@@ -84,17 +111,49 @@ type BlueprintBook struct {
 	// The color assigned to the blueprint book's label.
 	LabelColor *Color `json:"label_color,omitempty" yaml:"label_color,omitempty" mapstructure:"label_color,omitempty"`
 
-	// The game version when the blueprint book was created.
-	Version int `json:"version" yaml:"version" mapstructure:"version"`
+	// The game version, as four 16-bit parts, when the blueprint book was created.
+	Version GameVersion `json:"version" yaml:"version" mapstructure:"version"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
-// An array containing the blueprints included in the book.
+// An entry of the book. Exactly one of blueprint, blueprint_book, upgrade_planner
+// and deconstruction_planner is set.
 type BlueprintBookBlueprintsElem struct {
 	// A blueprint object.
-	Blueprint Blueprint `json:"blueprint" yaml:"blueprint" mapstructure:"blueprint"`
+	Blueprint *Blueprint `json:"blueprint,omitempty" yaml:"blueprint,omitempty" mapstructure:"blueprint,omitempty"`
 
-	// Index of the blueprint in the book, 0-based.
+	// A nested blueprint book object.
+	BlueprintBook *BlueprintBook `json:"blueprint_book,omitempty" yaml:"blueprint_book,omitempty" mapstructure:"blueprint_book,omitempty"`
+
+	// A deconstruction planner object.
+	DeconstructionPlanner *DeconstructionPlanner `json:"deconstruction_planner,omitempty" yaml:"deconstruction_planner,omitempty" mapstructure:"deconstruction_planner,omitempty"`
+
+	// Index of the entry in the book, 0-based.
 	Index int `json:"index" yaml:"index" mapstructure:"index"`
+
+	// An upgrade planner object.
+	UpgradePlanner *UpgradePlanner `json:"upgrade_planner,omitempty" yaml:"upgrade_planner,omitempty" mapstructure:"upgrade_planner,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 type BlueprintBookItem string
@@ -125,18 +184,50 @@ type BlueprintLogisticFilter struct {
 	Name *string `json:"name,omitempty" yaml:"name,omitempty" mapstructure:"name,omitempty"`
 
 	// The prototype name of the quality. nil for any quality.
-	Quality *string `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
+	Quality *Quality `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
+
+	// The signal of a constant combinator filter in Factorio 1.1.
+	Signal *SignalID `json:"signal,omitempty" yaml:"signal,omitempty" mapstructure:"signal,omitempty"`
 
 	// The type of the logistic filter.
 	Type *SignalID `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 type BlueprintSchemaJSON struct {
 	// Blueprint corresponds to the JSON schema field "blueprint".
 	Blueprint *Blueprint `json:"blueprint,omitempty" yaml:"blueprint,omitempty" mapstructure:"blueprint,omitempty"`
 
-	// BlueprintBook corresponds to the JSON schema field "blueprint-book".
-	BlueprintBook *BlueprintBook `json:"blueprint-book,omitempty" yaml:"blueprint-book,omitempty" mapstructure:"blueprint-book,omitempty"`
+	// BlueprintBook corresponds to the JSON schema field "blueprint_book".
+	BlueprintBook *BlueprintBook `json:"blueprint_book,omitempty" yaml:"blueprint_book,omitempty" mapstructure:"blueprint_book,omitempty"`
+
+	// DeconstructionPlanner corresponds to the JSON schema field
+	// "deconstruction_planner".
+	DeconstructionPlanner *DeconstructionPlanner `json:"deconstruction_planner,omitempty" yaml:"deconstruction_planner,omitempty" mapstructure:"deconstruction_planner,omitempty"`
+
+	// UpgradePlanner corresponds to the JSON schema field "upgrade_planner".
+	UpgradePlanner *UpgradePlanner `json:"upgrade_planner,omitempty" yaml:"upgrade_planner,omitempty" mapstructure:"upgrade_planner,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // A color with RGBA components.
@@ -152,6 +243,17 @@ type Color struct {
 
 	// Red component (0 to 1).
 	R float64 `json:"r" yaml:"r" mapstructure:"r"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // A circuit or logistic condition.
@@ -167,6 +269,17 @@ type Condition struct {
 
 	// The second signal in the condition.
 	SecondSignal *SignalID `json:"second_signal,omitempty" yaml:"second_signal,omitempty" mapstructure:"second_signal,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // Circuit network connections for an entity.
@@ -176,6 +289,23 @@ type Connection struct {
 
 	// Second connection point (if applicable).
 	A2 *ConnectionPoint `json:"2,omitempty" yaml:"2,omitempty" mapstructure:"2,omitempty"`
+
+	// Copper wires connected to the left side of a power switch.
+	Cu0 []ConnectionData `json:"Cu0,omitempty" yaml:"Cu0,omitempty" mapstructure:"Cu0,omitempty"`
+
+	// Copper wires connected to the right side of a power switch.
+	Cu1 []ConnectionData `json:"Cu1,omitempty" yaml:"Cu1,omitempty" mapstructure:"Cu1,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // Information about a single circuit network connection.
@@ -185,6 +315,20 @@ type ConnectionData struct {
 
 	// Entity number of the connected entity.
 	EntityID int `json:"entity_id" yaml:"entity_id" mapstructure:"entity_id"`
+
+	// Wire ID of a copper wire connected to a power switch.
+	WireID *int `json:"wire_id,omitempty" yaml:"wire_id,omitempty" mapstructure:"wire_id,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // A connection point for circuit network wires.
@@ -194,6 +338,17 @@ type ConnectionPoint struct {
 
 	// Connections made with red wires.
 	Red []ConnectionData `json:"red,omitempty" yaml:"red,omitempty" mapstructure:"red,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // Control behavior settings for entities. (Updated for Factorio 2.0)
@@ -221,18 +376,23 @@ type ControlBehavior struct {
 	LogisticCondition *Condition `json:"logistic_condition,omitempty" yaml:"logistic_condition,omitempty" mapstructure:"logistic_condition,omitempty"`
 
 	// Sections of the control behavior.
-	Sections *ControlBehaviorSections `json:"sections,omitempty" yaml:"sections,omitempty" mapstructure:"sections,omitempty"`
+	Sections *LogisticSections `json:"sections,omitempty" yaml:"sections,omitempty" mapstructure:"sections,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // Parameters for circuit network behavior (new in Factorio 2.0).
 type ControlBehaviorCircuitParameters map[string]interface{}
 
-// Sections of the control behavior.
-type ControlBehaviorSections struct {
-	// List of sections in the control behavior.
-	Sections []Section `json:"sections,omitempty" yaml:"sections,omitempty" mapstructure:"sections,omitempty"`
-}
-
 // Parameters for decider combinators.
 type DeciderConditions struct {
 	// Comparator operator.
@@ -252,6 +412,117 @@ type DeciderConditions struct {
 
 	// Second input signal.
 	SecondSignal *SignalID `json:"second_signal,omitempty" yaml:"second_signal,omitempty" mapstructure:"second_signal,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// An entity or tile filter of a deconstruction planner.
+type DeconstructionFilter struct {
+	// The comparator for quality (new in Factorio 2.0). nil if any quality.
+	Comparator *string `json:"comparator,omitempty" yaml:"comparator,omitempty" mapstructure:"comparator,omitempty"`
+
+	// 1-based index of the filter slot.
+	Index int `json:"index" yaml:"index" mapstructure:"index"`
+
+	// Name of the entity or tile prototype.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// The prototype name of the quality (new in Factorio 2.0). nil for any quality.
+	Quality *Quality `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// An object representing a Factorio deconstruction planner.
+type DeconstructionPlanner struct {
+	// The name of the item; always 'deconstruction-planner' in vanilla Factorio.
+	Item DeconstructionPlannerItem `json:"item" yaml:"item" mapstructure:"item"`
+
+	// The user-defined name of the deconstruction planner.
+	Label *string `json:"label,omitempty" yaml:"label,omitempty" mapstructure:"label,omitempty"`
+
+	// The color assigned to the deconstruction planner's label.
+	LabelColor *Color `json:"label_color,omitempty" yaml:"label_color,omitempty" mapstructure:"label_color,omitempty"`
+
+	// The settings of the deconstruction planner.
+	Settings *DeconstructionPlannerSettings `json:"settings,omitempty" yaml:"settings,omitempty" mapstructure:"settings,omitempty"`
+
+	// The game version, as four 16-bit parts, when the deconstruction planner was
+	// created.
+	Version GameVersion `json:"version" yaml:"version" mapstructure:"version"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+type DeconstructionPlannerItem string
+
+const DeconstructionPlannerItemDeconstructionPlanner DeconstructionPlannerItem = "deconstruction-planner"
+
+// The settings of a deconstruction planner.
+type DeconstructionPlannerSettings struct {
+	// An optional description of the deconstruction planner.
+	Description *string `json:"description,omitempty" yaml:"description,omitempty" mapstructure:"description,omitempty"`
+
+	// 0 if entity_filters is a whitelist (default), 1 if it is a blacklist.
+	EntityFilterMode *int `json:"entity_filter_mode,omitempty" yaml:"entity_filter_mode,omitempty" mapstructure:"entity_filter_mode,omitempty"`
+
+	// Entities the deconstruction planner is limited to or skips, depending on
+	// entity_filter_mode.
+	EntityFilters []DeconstructionFilter `json:"entity_filters,omitempty" yaml:"entity_filters,omitempty" mapstructure:"entity_filters,omitempty"`
+
+	// Icons set by the user for the deconstruction planner.
+	Icons []Icon `json:"icons,omitempty" yaml:"icons,omitempty" mapstructure:"icons,omitempty"`
+
+	// 0 if tile_filters is a whitelist (default), 1 if it is a blacklist.
+	TileFilterMode *int `json:"tile_filter_mode,omitempty" yaml:"tile_filter_mode,omitempty" mapstructure:"tile_filter_mode,omitempty"`
+
+	// Tiles the deconstruction planner is limited to or skips, depending on
+	// tile_filter_mode.
+	TileFilters []DeconstructionFilter `json:"tile_filters,omitempty" yaml:"tile_filters,omitempty" mapstructure:"tile_filters,omitempty"`
+
+	// 0 normal (default), 1 always, 2 never, 3 only; when tiles are deconstructed.
+	TileSelectionMode *int `json:"tile_selection_mode,omitempty" yaml:"tile_selection_mode,omitempty" mapstructure:"tile_selection_mode,omitempty"`
+
+	// Whether only trees and rocks are deconstructed.
+	TreesAndRocksOnly *bool `json:"trees_and_rocks_only,omitempty" yaml:"trees_and_rocks_only,omitempty" mapstructure:"trees_and_rocks_only,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // An entity placed within the blueprint.
@@ -305,12 +576,17 @@ type Entity struct {
 	// Cargo wagon inventory configuration (optional).
 	Inventory *Inventory `json:"inventory,omitempty" yaml:"inventory,omitempty" mapstructure:"inventory,omitempty"`
 
-	// Item requests by this entity (optional).
-	Items ItemRequest `json:"items,omitempty" yaml:"items,omitempty" mapstructure:"items,omitempty"`
+	// Item requests by this entity; an itemRequest in 1.1, an array of insertPlan in
+	// 2.0 (optional).
+	Items *EntityItems `json:"items,omitempty" yaml:"items,omitempty" mapstructure:"items,omitempty"`
 
 	// Manually set train limit of the train station (optional).
 	ManualTrainsLimit *int `json:"manual_trains_limit,omitempty" yaml:"manual_trains_limit,omitempty" mapstructure:"manual_trains_limit,omitempty"`
 
+	// Whether the entity is mirrored, keeping its fluid boxes mirrored too
+	// (optional, new in Factorio 2.0).
+	Mirror *bool `json:"mirror,omitempty" yaml:"mirror,omitempty" mapstructure:"mirror,omitempty"`
+
 	// Prototype name of the entity (e.g., "offshore-pump").
 	Name string `json:"name" yaml:"name" mapstructure:"name"`
 
@@ -338,11 +614,20 @@ type Entity struct {
 	// Position of the entity within the blueprint.
 	Position Position `json:"position" yaml:"position" mapstructure:"position"`
 
+	// The prototype name of the quality of the entity (optional, new in Factorio
+	// 2.0). nil for normal.
+	Quality *Quality `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
+
 	// Name of the recipe prototype this assembling machine is set to (optional).
 	Recipe *string `json:"recipe,omitempty" yaml:"recipe,omitempty" mapstructure:"recipe,omitempty"`
 
-	// Used by LogisticContainer; array of logistic filters (optional).
-	RequestFilters []LogisticFilter `json:"request_filters,omitempty" yaml:"request_filters,omitempty" mapstructure:"request_filters,omitempty"`
+	// The prototype name of the quality of the recipe (optional, new in Factorio
+	// 2.0). nil for normal.
+	RecipeQuality *Quality `json:"recipe_quality,omitempty" yaml:"recipe_quality,omitempty" mapstructure:"recipe_quality,omitempty"`
+
+	// Used by LogisticContainer; array of logistic filters in 1.1, logistic sections
+	// in 2.0 (optional).
+	RequestFilters *RequestFilters `json:"request_filters,omitempty" yaml:"request_filters,omitempty" mapstructure:"request_filters,omitempty"`
 
 	// Whether the requester chest can request from buffer chests (optional).
 	RequestFromBuffers *bool `json:"request_from_buffers,omitempty" yaml:"request_from_buffers,omitempty" mapstructure:"request_from_buffers,omitempty"`
@@ -364,8 +649,22 @@ type Entity struct {
 	// Type of the underground belt or loader (optional).
 	Type *EntityType `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type,omitempty"`
 
+	// Whether the inserter uses its filters (optional, new in Factorio 2.0).
+	UseFilters *bool `json:"use_filters,omitempty" yaml:"use_filters,omitempty" mapstructure:"use_filters,omitempty"`
+
 	// Used by SimpleEntityWithForce or SimpleEntityWithOwner (optional).
 	Variation *int `json:"variation,omitempty" yaml:"variation,omitempty" mapstructure:"variation,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 type EntityFilterMode string
@@ -390,160 +689,96 @@ type EntityTags map[string]interface{}
 type EntityType string
 
 const EntityTypeInput EntityType = "input"
-const EntityTypeOutput EntityType = "output"
-
-// A filter within a section.
-type Filter struct {
-	// Comparator used for filtering.
-	Comparator string `json:"comparator" yaml:"comparator" mapstructure:"comparator"`
-
-	// Count threshold for the filter.
-	Count int `json:"count" yaml:"count" mapstructure:"count"`
-
-	// Index of the filter.
-	Index int `json:"index" yaml:"index" mapstructure:"index"`
-
-	// Name of the filtered item.
-	Name string `json:"name" yaml:"name" mapstructure:"name"`
-
-	// Quality level of the item.
-	Quality *string `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
-}
-
-// An icon representing an item, fluid, or virtual signal.
-type Icon struct {
-	// The 1-based index of the icon.
-	Index int `json:"index" yaml:"index" mapstructure:"index"`
-
-	// The signal used as the icon.
-	Signal SignalID `json:"signal" yaml:"signal" mapstructure:"signal"`
-}
-
-// A filter for Infinity container items.
-type InfinityFilter struct {
-	// Desired item count.
-	Count *int `json:"count,omitempty" yaml:"count,omitempty" mapstructure:"count,omitempty"`
-
-	// 1-based index of the filter.
-	Index *int `json:"index,omitempty" yaml:"index,omitempty" mapstructure:"index,omitempty"`
-
-	// Mode defining how item count is maintained.
-	Mode *InfinityFilterMode `json:"mode,omitempty" yaml:"mode,omitempty" mapstructure:"mode,omitempty"`
-
-	// Name of the item prototype.
-	Name *string `json:"name,omitempty" yaml:"name,omitempty" mapstructure:"name,omitempty"`
-}
-
-type InfinityFilterMode string
-
-const InfinityFilterModeAtLeast InfinityFilterMode = "at-least"
-const InfinityFilterModeAtMost InfinityFilterMode = "at-most"
-const InfinityFilterModeExactly InfinityFilterMode = "exactly"
-
-// Settings for Infinity containers.
-type InfinitySettings struct {
-	// Filters specifying item settings.
-	Filters []InfinityFilter `json:"filters,omitempty" yaml:"filters,omitempty" mapstructure:"filters,omitempty"`
-
-	// Whether to remove items not specified in the filters.
-	RemoveUnfilteredItems *bool `json:"remove_unfiltered_items,omitempty" yaml:"remove_unfiltered_items,omitempty" mapstructure:"remove_unfiltered_items,omitempty"`
-}
 
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *EntityType) UnmarshalYAML(value *yaml.Node) error {
-	var v string
-	if err := value.Decode(&v); err != nil {
+func (j *Tile) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
 		return err
 	}
-	var ok bool
-	for _, expected := range enumValues_EntityType {
-		if reflect.DeepEqual(v, expected) {
-			ok = true
-			break
-		}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in Tile: required")
 	}
-	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityType, v)
+	if v, ok := raw["position"]; !ok || v == nil {
+		return fmt.Errorf("field position in Tile: required")
 	}
-	*j = EntityType(v)
-	return nil
-}
-
-var enumValues_EntityFilterMode = []interface{}{
-	"whitelist",
-	"blacklist",
-}
-
-// UnmarshalJSON implements json.Unmarshaler.
-func (j *EntityFilterMode) UnmarshalJSON(b []byte) error {
-	var v string
-	if err := json.Unmarshal(b, &v); err != nil {
+	type Plain Tile
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
 		return err
 	}
-	var ok bool
-	for _, expected := range enumValues_EntityFilterMode {
-		if reflect.DeepEqual(v, expected) {
-			ok = true
-			break
-		}
-	}
-	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityFilterMode, v)
-	}
-	*j = EntityFilterMode(v)
+	*j = Tile(plain)
 	return nil
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *EntityFilterMode) UnmarshalYAML(value *yaml.Node) error {
-	var v string
-	if err := value.Decode(&v); err != nil {
+func (j *Filter) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
 		return err
 	}
-	var ok bool
-	for _, expected := range enumValues_EntityFilterMode {
-		if reflect.DeepEqual(v, expected) {
-			ok = true
-			break
-		}
+	if v, ok := raw["comparator"]; !ok || v == nil {
+		return fmt.Errorf("field comparator in Filter: required")
 	}
-	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityFilterMode, v)
+	if v, ok := raw["count"]; !ok || v == nil {
+		return fmt.Errorf("field count in Filter: required")
 	}
-	*j = EntityFilterMode(v)
+	if v, ok := raw["index"]; !ok || v == nil {
+		return fmt.Errorf("field index in Filter: required")
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in Filter: required")
+	}
+	type Plain Filter
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
+		return err
+	}
+	*j = Filter(plain)
 	return nil
 }
 
+// A section within the control behavior.
+type Section struct {
+	// Filters within the section.
+	Filters []Filter `json:"filters" yaml:"filters" mapstructure:"filters"`
+
+	// Index of the section.
+	Index int `json:"index" yaml:"index" mapstructure:"index"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
 // UnmarshalJSON implements json.Unmarshaler.
-func (j *Position) UnmarshalJSON(b []byte) error {
+func (j *Section) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
 	if err := json.Unmarshal(b, &raw); err != nil {
 		return err
 	}
-	if v, ok := raw["x"]; !ok || v == nil {
-		return fmt.Errorf("field x in Position: required")
+	if v, ok := raw["filters"]; !ok || v == nil {
+		return fmt.Errorf("field filters in Section: required")
 	}
-	if v, ok := raw["y"]; !ok || v == nil {
-		return fmt.Errorf("field y in Position: required")
+	if v, ok := raw["index"]; !ok || v == nil {
+		return fmt.Errorf("field index in Section: required")
 	}
-	type Plain Position
+	type Plain Section
 	var plain Plain
 	if err := json.Unmarshal(b, &plain); err != nil {
 		return err
 	}
-	*j = Position(plain)
+	*j = Section(plain)
 	return nil
 }
 
-// A position in 2D space.
-type Position struct {
-	// The x-coordinate.
-	X float64 `json:"x" yaml:"x" mapstructure:"x"`
-
-	// The y-coordinate.
-	Y float64 `json:"y" yaml:"y" mapstructure:"y"`
-}
-
 // UnmarshalYAML implements yaml.Unmarshaler.
 func (j *Section) UnmarshalYAML(value *yaml.Node) error {
 	var raw map[string]interface{}
@@ -565,138 +800,121 @@ func (j *Section) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-var enumValues_InfinityFilterMode = []interface{}{
-	"at-least",
-	"at-most",
-	"exactly",
-}
+// Logistic sections of a constant combinator or a logistic container (new in
+// Factorio 2.0).
+type LogisticSections struct {
+	// List of sections.
+	Sections []Section `json:"sections,omitempty" yaml:"sections,omitempty" mapstructure:"sections,omitempty"`
 
-// UnmarshalJSON implements json.Unmarshaler.
-func (j *InfinityFilterMode) UnmarshalJSON(b []byte) error {
-	var v string
-	if err := json.Unmarshal(b, &v); err != nil {
-		return err
-	}
-	var ok bool
-	for _, expected := range enumValues_InfinityFilterMode {
-		if reflect.DeepEqual(v, expected) {
-			ok = true
-			break
-		}
-	}
-	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_InfinityFilterMode, v)
-	}
-	*j = InfinityFilterMode(v)
-	return nil
+	// Whether items which are not requested are moved to the trash slots (optional).
+	TrashNotRequested *bool `json:"trash_not_requested,omitempty" yaml:"trash_not_requested,omitempty" mapstructure:"trash_not_requested,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
-// UnmarshalYAML implements yaml.Unmarshaler.
-func (j *InfinityFilterMode) UnmarshalYAML(value *yaml.Node) error {
-	var v string
-	if err := value.Decode(&v); err != nil {
-		return err
-	}
-	var ok bool
-	for _, expected := range enumValues_InfinityFilterMode {
-		if reflect.DeepEqual(v, expected) {
-			ok = true
-			break
-		}
-	}
-	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_InfinityFilterMode, v)
-	}
-	*j = InfinityFilterMode(v)
-	return nil
+// A filter within a section.
+type Filter struct {
+	// Comparator used for filtering.
+	Comparator string `json:"comparator" yaml:"comparator" mapstructure:"comparator"`
+
+	// Count threshold for the filter.
+	Count int `json:"count" yaml:"count" mapstructure:"count"`
+
+	// Index of the filter.
+	Index int `json:"index" yaml:"index" mapstructure:"index"`
+
+	// Name of the filtered item.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// Quality level of the item.
+	Quality *Quality `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
+
+	// Type of the filtered signal; item if not set.
+	Type *string `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// A position in 2D space.
+type Position struct {
+	// The x-coordinate.
+	X float64 `json:"x" yaml:"x" mapstructure:"x"`
+
+	// The y-coordinate.
+	Y float64 `json:"y" yaml:"y" mapstructure:"y"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
-func (j *Section) UnmarshalJSON(b []byte) error {
+func (j *Position) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
 	if err := json.Unmarshal(b, &raw); err != nil {
 		return err
 	}
-	if v, ok := raw["filters"]; !ok || v == nil {
-		return fmt.Errorf("field filters in Section: required")
+	if v, ok := raw["x"]; !ok || v == nil {
+		return fmt.Errorf("field x in Position: required")
 	}
-	if v, ok := raw["index"]; !ok || v == nil {
-		return fmt.Errorf("field index in Section: required")
+	if v, ok := raw["y"]; !ok || v == nil {
+		return fmt.Errorf("field y in Position: required")
 	}
-	type Plain Section
+	type Plain Position
 	var plain Plain
 	if err := json.Unmarshal(b, &plain); err != nil {
 		return err
 	}
-	*j = Section(plain)
+	*j = Position(plain)
 	return nil
 }
 
-// A section within the control behavior.
-type Section struct {
-	// Filters within the section.
-	Filters []Filter `json:"filters" yaml:"filters" mapstructure:"filters"`
-
-	// Index of the section.
-	Index int `json:"index" yaml:"index" mapstructure:"index"`
-}
-
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *Filter) UnmarshalYAML(value *yaml.Node) error {
+func (j *Position) UnmarshalYAML(value *yaml.Node) error {
 	var raw map[string]interface{}
 	if err := value.Decode(&raw); err != nil {
 		return err
 	}
-	if v, ok := raw["comparator"]; !ok || v == nil {
-		return fmt.Errorf("field comparator in Filter: required")
-	}
-	if v, ok := raw["count"]; !ok || v == nil {
-		return fmt.Errorf("field count in Filter: required")
-	}
-	if v, ok := raw["index"]; !ok || v == nil {
-		return fmt.Errorf("field index in Filter: required")
+	if v, ok := raw["x"]; !ok || v == nil {
+		return fmt.Errorf("field x in Position: required")
 	}
-	if v, ok := raw["name"]; !ok || v == nil {
-		return fmt.Errorf("field name in Filter: required")
+	if v, ok := raw["y"]; !ok || v == nil {
+		return fmt.Errorf("field y in Position: required")
 	}
-	type Plain Filter
+	type Plain Position
 	var plain Plain
 	if err := value.Decode(&plain); err != nil {
 		return err
 	}
-	*j = Filter(plain)
-	return nil
-}
-
-// UnmarshalJSON implements json.Unmarshaler.
-func (j *Filter) UnmarshalJSON(b []byte) error {
-	var raw map[string]interface{}
-	if err := json.Unmarshal(b, &raw); err != nil {
-		return err
-	}
-	if v, ok := raw["comparator"]; !ok || v == nil {
-		return fmt.Errorf("field comparator in Filter: required")
-	}
-	if v, ok := raw["count"]; !ok || v == nil {
-		return fmt.Errorf("field count in Filter: required")
-	}
-	if v, ok := raw["index"]; !ok || v == nil {
-		return fmt.Errorf("field index in Filter: required")
-	}
-	if v, ok := raw["name"]; !ok || v == nil {
-		return fmt.Errorf("field name in Filter: required")
-	}
-	type Plain Filter
-	var plain Plain
-	if err := json.Unmarshal(b, &plain); err != nil {
-		return err
-	}
-	*j = Filter(plain)
+	*j = Position(plain)
 	return nil
 }
 
-type SignalIDType string
-
 // UnmarshalYAML implements yaml.Unmarshaler.
 func (j *ConnectionData) UnmarshalYAML(value *yaml.Node) error {
 	var raw map[string]interface{}
@@ -715,48 +933,48 @@ func (j *ConnectionData) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-var enumValues_EntityInputPriority = []interface{}{
-	"right",
-	"left",
+var enumValues_EntityFilterMode = []interface{}{
+	"whitelist",
+	"blacklist",
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
-func (j *EntityInputPriority) UnmarshalJSON(b []byte) error {
+func (j *EntityFilterMode) UnmarshalJSON(b []byte) error {
 	var v string
 	if err := json.Unmarshal(b, &v); err != nil {
 		return err
 	}
 	var ok bool
-	for _, expected := range enumValues_EntityInputPriority {
+	for _, expected := range enumValues_EntityFilterMode {
 		if reflect.DeepEqual(v, expected) {
 			ok = true
 			break
 		}
 	}
 	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityInputPriority, v)
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityFilterMode, v)
 	}
-	*j = EntityInputPriority(v)
+	*j = EntityFilterMode(v)
 	return nil
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *EntityInputPriority) UnmarshalYAML(value *yaml.Node) error {
+func (j *EntityFilterMode) UnmarshalYAML(value *yaml.Node) error {
 	var v string
 	if err := value.Decode(&v); err != nil {
 		return err
 	}
 	var ok bool
-	for _, expected := range enumValues_EntityInputPriority {
+	for _, expected := range enumValues_EntityFilterMode {
 		if reflect.DeepEqual(v, expected) {
 			ok = true
 			break
 		}
 	}
 	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityInputPriority, v)
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityFilterMode, v)
 	}
-	*j = EntityInputPriority(v)
+	*j = EntityFilterMode(v)
 	return nil
 }
 
@@ -802,8 +1020,103 @@ func (j *Color) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-// Item requests by the entity for construction.
-type ItemRequest map[string]int
+type InfinityFilterMode string
+
+var enumValues_InfinityFilterMode = []interface{}{
+	"at-least",
+	"at-most",
+	"exactly",
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *InfinityFilterMode) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_InfinityFilterMode {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_InfinityFilterMode, v)
+	}
+	*j = InfinityFilterMode(v)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *InfinityFilterMode) UnmarshalYAML(value *yaml.Node) error {
+	var v string
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_InfinityFilterMode {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_InfinityFilterMode, v)
+	}
+	*j = InfinityFilterMode(v)
+	return nil
+}
+
+const InfinityFilterModeAtLeast InfinityFilterMode = "at-least"
+const InfinityFilterModeAtMost InfinityFilterMode = "at-most"
+const InfinityFilterModeExactly InfinityFilterMode = "exactly"
+
+// A filter for Infinity container items.
+type InfinityFilter struct {
+	// Desired item count.
+	Count *int `json:"count,omitempty" yaml:"count,omitempty" mapstructure:"count,omitempty"`
+
+	// 1-based index of the filter.
+	Index *int `json:"index,omitempty" yaml:"index,omitempty" mapstructure:"index,omitempty"`
+
+	// Mode defining how item count is maintained.
+	Mode *InfinityFilterMode `json:"mode,omitempty" yaml:"mode,omitempty" mapstructure:"mode,omitempty"`
+
+	// Name of the item prototype.
+	Name *string `json:"name,omitempty" yaml:"name,omitempty" mapstructure:"name,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// Settings for Infinity containers.
+type InfinitySettings struct {
+	// Filters specifying item settings.
+	Filters []InfinityFilter `json:"filters,omitempty" yaml:"filters,omitempty" mapstructure:"filters,omitempty"`
+
+	// Whether to remove items not specified in the filters.
+	RemoveUnfilteredItems *bool `json:"remove_unfiltered_items,omitempty" yaml:"remove_unfiltered_items,omitempty" mapstructure:"remove_unfiltered_items,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
 
 // UnmarshalJSON implements json.Unmarshaler.
 func (j *Color) UnmarshalJSON(b []byte) error {
@@ -829,48 +1142,48 @@ func (j *Color) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-var enumValues_EntityOutputPriority = []interface{}{
+var enumValues_EntityInputPriority = []interface{}{
 	"right",
 	"left",
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
-func (j *EntityOutputPriority) UnmarshalJSON(b []byte) error {
+func (j *EntityInputPriority) UnmarshalJSON(b []byte) error {
 	var v string
 	if err := json.Unmarshal(b, &v); err != nil {
 		return err
 	}
 	var ok bool
-	for _, expected := range enumValues_EntityOutputPriority {
+	for _, expected := range enumValues_EntityInputPriority {
 		if reflect.DeepEqual(v, expected) {
 			ok = true
 			break
 		}
 	}
 	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityOutputPriority, v)
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityInputPriority, v)
 	}
-	*j = EntityOutputPriority(v)
+	*j = EntityInputPriority(v)
 	return nil
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *EntityOutputPriority) UnmarshalYAML(value *yaml.Node) error {
+func (j *EntityInputPriority) UnmarshalYAML(value *yaml.Node) error {
 	var v string
 	if err := value.Decode(&v); err != nil {
 		return err
 	}
 	var ok bool
-	for _, expected := range enumValues_EntityOutputPriority {
+	for _, expected := range enumValues_EntityInputPriority {
 		if reflect.DeepEqual(v, expected) {
 			ok = true
 			break
 		}
 	}
 	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityOutputPriority, v)
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityInputPriority, v)
 	}
-	*j = EntityOutputPriority(v)
+	*j = EntityInputPriority(v)
 	return nil
 }
 
@@ -881,6 +1194,17 @@ type Inventory struct {
 
 	// Array of item filters.
 	Filters []ItemFilter `json:"filters,omitempty" yaml:"filters,omitempty" mapstructure:"filters,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -904,30 +1228,6 @@ func (j *ItemFilter) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-// Playback settings for a programmable speaker.
-type SpeakerParameters struct {
-	// Whether multiple sounds can play simultaneously.
-	AllowPolyphony *bool `json:"allow_polyphony,omitempty" yaml:"allow_polyphony,omitempty" mapstructure:"allow_polyphony,omitempty"`
-
-	// Whether the sound plays globally.
-	PlaybackGlobally *bool `json:"playback_globally,omitempty" yaml:"playback_globally,omitempty" mapstructure:"playback_globally,omitempty"`
-
-	// Volume of the speaker.
-	PlaybackVolume *float64 `json:"playback_volume,omitempty" yaml:"playback_volume,omitempty" mapstructure:"playback_volume,omitempty"`
-}
-
-// Filter settings for logistic containers.
-type LogisticFilter struct {
-	// Requested item count (0 for storage chests).
-	Count *int `json:"count,omitempty" yaml:"count,omitempty" mapstructure:"count,omitempty"`
-
-	// 1-based index of the filter slot.
-	Index *int `json:"index,omitempty" yaml:"index,omitempty" mapstructure:"index,omitempty"`
-
-	// Name of the item prototype.
-	Name *string `json:"name,omitempty" yaml:"name,omitempty" mapstructure:"name,omitempty"`
-}
-
 // UnmarshalJSON implements json.Unmarshaler.
 func (j *ItemFilter) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
@@ -949,61 +1249,77 @@ func (j *ItemFilter) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-// Filter settings for items in an inventory.
-type ItemFilter struct {
-	// 1-based index of the filter slot.
-	Index int `json:"index" yaml:"index" mapstructure:"index"`
-
-	// Name of the item prototype.
-	Name string `json:"name" yaml:"name" mapstructure:"name"`
-}
-
-var enumValues_EntityType = []interface{}{
-	"input",
-	"output",
+var enumValues_EntityOutputPriority = []interface{}{
+	"right",
+	"left",
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
-func (j *EntityType) UnmarshalJSON(b []byte) error {
+func (j *EntityOutputPriority) UnmarshalJSON(b []byte) error {
 	var v string
 	if err := json.Unmarshal(b, &v); err != nil {
 		return err
 	}
 	var ok bool
-	for _, expected := range enumValues_EntityType {
+	for _, expected := range enumValues_EntityOutputPriority {
 		if reflect.DeepEqual(v, expected) {
 			ok = true
 			break
 		}
 	}
 	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityType, v)
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityOutputPriority, v)
 	}
-	*j = EntityType(v)
+	*j = EntityOutputPriority(v)
 	return nil
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *Position) UnmarshalYAML(value *yaml.Node) error {
-	var raw map[string]interface{}
-	if err := value.Decode(&raw); err != nil {
+func (j *EntityOutputPriority) UnmarshalYAML(value *yaml.Node) error {
+	var v string
+	if err := value.Decode(&v); err != nil {
 		return err
 	}
-	if v, ok := raw["x"]; !ok || v == nil {
-		return fmt.Errorf("field x in Position: required")
-	}
-	if v, ok := raw["y"]; !ok || v == nil {
-		return fmt.Errorf("field y in Position: required")
+	var ok bool
+	for _, expected := range enumValues_EntityOutputPriority {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
 	}
-	type Plain Position
-	var plain Plain
-	if err := value.Decode(&plain); err != nil {
-		return err
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityOutputPriority, v)
 	}
-	*j = Position(plain)
+	*j = EntityOutputPriority(v)
 	return nil
 }
 
+// Filter settings for items in an inventory.
+type ItemFilter struct {
+	// The comparator for quality (new in Factorio 2.0). nil if any quality.
+	Comparator *string `json:"comparator,omitempty" yaml:"comparator,omitempty" mapstructure:"comparator,omitempty"`
+
+	// 1-based index of the filter slot.
+	Index int `json:"index" yaml:"index" mapstructure:"index"`
+
+	// Name of the item prototype.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// The prototype name of the quality (new in Factorio 2.0). nil for any quality.
+	Quality *Quality `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
 // Alert settings for a programmable speaker.
 type SpeakerAlertParameters struct {
 	// Custom message for the alert.
@@ -1017,6 +1333,40 @@ type SpeakerAlertParameters struct {
 
 	// Whether to show the alert on the map.
 	ShowOnMap *bool `json:"show_on_map,omitempty" yaml:"show_on_map,omitempty" mapstructure:"show_on_map,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// Playback settings for a programmable speaker.
+type SpeakerParameters struct {
+	// Whether multiple sounds can play simultaneously.
+	AllowPolyphony *bool `json:"allow_polyphony,omitempty" yaml:"allow_polyphony,omitempty" mapstructure:"allow_polyphony,omitempty"`
+
+	// Whether the sound plays globally.
+	PlaybackGlobally *bool `json:"playback_globally,omitempty" yaml:"playback_globally,omitempty" mapstructure:"playback_globally,omitempty"`
+
+	// Volume of the speaker.
+	PlaybackVolume *float64 `json:"playback_volume,omitempty" yaml:"playback_volume,omitempty" mapstructure:"playback_volume,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -1055,6 +1405,80 @@ func (j *SignalID) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+var enumValues_EntityType = []interface{}{
+	"input",
+	"output",
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *EntityType) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_EntityType {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityType, v)
+	}
+	*j = EntityType(v)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *EntityType) UnmarshalYAML(value *yaml.Node) error {
+	var v string
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_EntityType {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_EntityType, v)
+	}
+	*j = EntityType(v)
+	return nil
+}
+
+type SignalIDType string
+
+const EntityTypeOutput EntityType = "output"
+
+// An identifier for a signal in the game.
+type SignalID struct {
+	// The name of the signal.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// The prototype name of the quality of the signal (new in Factorio 2.0). nil for
+	// normal.
+	Quality *Quality `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
+
+	// The type of the signal; item if not set. Types other than item, fluid and
+	// virtual are new in Factorio 2.0.
+	Type *SignalIDType `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
 // UnmarshalJSON implements json.Unmarshaler.
 func (j *Entity) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
@@ -1103,13 +1527,24 @@ func (j *Entity) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-// An identifier for a signal in the game.
-type SignalID struct {
-	// The name of the signal.
-	Name string `json:"name" yaml:"name" mapstructure:"name"`
+// An icon representing an item, fluid, or virtual signal.
+type Icon struct {
+	// The 1-based index of the icon.
+	Index int `json:"index" yaml:"index" mapstructure:"index"`
 
-	// The type of the signal.
-	Type *SignalIDType `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type,omitempty"`
+	// The signal used as the icon.
+	Signal SignalID `json:"signal" yaml:"signal" mapstructure:"signal"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -1156,192 +1591,692 @@ func (j *Icon) UnmarshalYAML(value *yaml.Node) error {
 
 type WaitConditionCompareType string
 
-var enumValues_WaitConditionCompareType = []interface{}{
-	"and",
-	"or",
+var enumValues_WaitConditionCompareType = []interface{}{
+	"and",
+	"or",
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *WaitConditionCompareType) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_WaitConditionCompareType {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_WaitConditionCompareType, v)
+	}
+	*j = WaitConditionCompareType(v)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *WaitConditionCompareType) UnmarshalYAML(value *yaml.Node) error {
+	var v string
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_WaitConditionCompareType {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_WaitConditionCompareType, v)
+	}
+	*j = WaitConditionCompareType(v)
+	return nil
+}
+
+const WaitConditionCompareTypeAnd WaitConditionCompareType = "and"
+const WaitConditionCompareTypeOr WaitConditionCompareType = "or"
+
+// A condition defining how long a train waits at a station.
+type WaitCondition struct {
+	// Logical operator for combining conditions.
+	CompareType *WaitConditionCompareType `json:"compare_type,omitempty" yaml:"compare_type,omitempty" mapstructure:"compare_type,omitempty"`
+
+	// A condition object used when type is 'item_count', 'circuit', or 'fluid_count'
+	// (optional).
+	Condition *Condition `json:"condition,omitempty" yaml:"condition,omitempty" mapstructure:"condition,omitempty"`
+
+	// Number of ticks to wait (used with 'time' or 'inactivity' types).
+	Ticks *int `json:"ticks,omitempty" yaml:"ticks,omitempty" mapstructure:"ticks,omitempty"`
+
+	// Type of the wait condition.
+	Type *string `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// A single record in a train schedule.
+type ScheduleRecord struct {
+	// Name of the train stop.
+	Station *string `json:"station,omitempty" yaml:"station,omitempty" mapstructure:"station,omitempty"`
+
+	// Conditions under which the train waits at this stop.
+	WaitConditions []WaitCondition `json:"wait_conditions,omitempty" yaml:"wait_conditions,omitempty" mapstructure:"wait_conditions,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// Train schedule data.
+type Schedule struct {
+	// Entity numbers of locomotives using this schedule.
+	Locomotives []int `json:"locomotives,omitempty" yaml:"locomotives,omitempty" mapstructure:"locomotives,omitempty"`
+
+	// Array of schedule records.
+	Schedule []ScheduleRecord `json:"schedule,omitempty" yaml:"schedule,omitempty" mapstructure:"schedule,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// A tile placed within the blueprint.
+type Tile struct {
+	// The prototype name of the tile.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// The position of the tile on the blueprint grid.
+	Position Position `json:"position" yaml:"position" mapstructure:"position"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *Tile) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in Tile: required")
+	}
+	if v, ok := raw["position"]; !ok || v == nil {
+		return fmt.Errorf("field position in Tile: required")
+	}
+	type Plain Tile
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = Tile(plain)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *Filter) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["comparator"]; !ok || v == nil {
+		return fmt.Errorf("field comparator in Filter: required")
+	}
+	if v, ok := raw["count"]; !ok || v == nil {
+		return fmt.Errorf("field count in Filter: required")
+	}
+	if v, ok := raw["index"]; !ok || v == nil {
+		return fmt.Errorf("field index in Filter: required")
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in Filter: required")
+	}
+	type Plain Filter
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = Filter(plain)
+	return nil
+}
+
+// A wire between two wire connectors, as [entity_number, wire_connector_id,
+// entity_number, wire_connector_id].
+type Wire []int
+
+const SignalIDTypeAsteroidChunk SignalIDType = "asteroid-chunk"
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *Blueprint) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["icons"]; !ok || v == nil {
+		return fmt.Errorf("field icons in Blueprint: required")
+	}
+	if v, ok := raw["item"]; !ok || v == nil {
+		return fmt.Errorf("field item in Blueprint: required")
+	}
+	if v, ok := raw["version"]; !ok || v == nil {
+		return fmt.Errorf("field version in Blueprint: required")
+	}
+	type Plain Blueprint
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = Blueprint(plain)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *Blueprint) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	if v, ok := raw["icons"]; !ok || v == nil {
+		return fmt.Errorf("field icons in Blueprint: required")
+	}
+	if v, ok := raw["item"]; !ok || v == nil {
+		return fmt.Errorf("field item in Blueprint: required")
+	}
+	if v, ok := raw["version"]; !ok || v == nil {
+		return fmt.Errorf("field version in Blueprint: required")
+	}
+	type Plain Blueprint
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
+		return err
+	}
+	*j = Blueprint(plain)
+	return nil
+}
+
+const SignalIDTypeSpaceLocation SignalIDType = "space-location"
+
+var enumValues_DeconstructionPlannerItem = []interface{}{
+	"deconstruction-planner",
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *DeconstructionPlannerItem) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_DeconstructionPlannerItem {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_DeconstructionPlannerItem, v)
+	}
+	*j = DeconstructionPlannerItem(v)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *DeconstructionPlannerItem) UnmarshalYAML(value *yaml.Node) error {
+	var v string
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_DeconstructionPlannerItem {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_DeconstructionPlannerItem, v)
+	}
+	*j = DeconstructionPlannerItem(v)
+	return nil
+}
+
+const SignalIDTypeQuality SignalIDType = "quality"
+const SignalIDTypeRecipe SignalIDType = "recipe"
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *DeconstructionFilter) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["index"]; !ok || v == nil {
+		return fmt.Errorf("field index in DeconstructionFilter: required")
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in DeconstructionFilter: required")
+	}
+	type Plain DeconstructionFilter
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = DeconstructionFilter(plain)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *DeconstructionFilter) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	if v, ok := raw["index"]; !ok || v == nil {
+		return fmt.Errorf("field index in DeconstructionFilter: required")
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in DeconstructionFilter: required")
+	}
+	type Plain DeconstructionFilter
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
+		return err
+	}
+	*j = DeconstructionFilter(plain)
+	return nil
+}
+
+const SignalIDTypeEntity SignalIDType = "entity"
+const SignalIDTypeVirtual SignalIDType = "virtual"
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *DeconstructionPlanner) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["item"]; !ok || v == nil {
+		return fmt.Errorf("field item in DeconstructionPlanner: required")
+	}
+	if v, ok := raw["version"]; !ok || v == nil {
+		return fmt.Errorf("field version in DeconstructionPlanner: required")
+	}
+	type Plain DeconstructionPlanner
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = DeconstructionPlanner(plain)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *DeconstructionPlanner) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	if v, ok := raw["item"]; !ok || v == nil {
+		return fmt.Errorf("field item in DeconstructionPlanner: required")
+	}
+	if v, ok := raw["version"]; !ok || v == nil {
+		return fmt.Errorf("field version in DeconstructionPlanner: required")
+	}
+	type Plain DeconstructionPlanner
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
+		return err
+	}
+	*j = DeconstructionPlanner(plain)
+	return nil
+}
+
+type UpgradePlannerItem string
+
+var enumValues_UpgradePlannerItem = []interface{}{
+	"upgrade-planner",
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *UpgradePlannerItem) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_UpgradePlannerItem {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_UpgradePlannerItem, v)
+	}
+	*j = UpgradePlannerItem(v)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *UpgradePlannerItem) UnmarshalYAML(value *yaml.Node) error {
+	var v string
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_UpgradePlannerItem {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_UpgradePlannerItem, v)
+	}
+	*j = UpgradePlannerItem(v)
+	return nil
+}
+
+const UpgradePlannerItemUpgradePlanner UpgradePlannerItem = "upgrade-planner"
+
+type UpgradeMapperTargetType string
+
+var enumValues_UpgradeMapperTargetType = []interface{}{
+	"entity",
+	"item",
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
-func (j *WaitConditionCompareType) UnmarshalJSON(b []byte) error {
+func (j *UpgradeMapperTargetType) UnmarshalJSON(b []byte) error {
 	var v string
 	if err := json.Unmarshal(b, &v); err != nil {
 		return err
 	}
 	var ok bool
-	for _, expected := range enumValues_WaitConditionCompareType {
+	for _, expected := range enumValues_UpgradeMapperTargetType {
 		if reflect.DeepEqual(v, expected) {
 			ok = true
 			break
 		}
 	}
 	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_WaitConditionCompareType, v)
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_UpgradeMapperTargetType, v)
 	}
-	*j = WaitConditionCompareType(v)
+	*j = UpgradeMapperTargetType(v)
 	return nil
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *WaitConditionCompareType) UnmarshalYAML(value *yaml.Node) error {
+func (j *UpgradeMapperTargetType) UnmarshalYAML(value *yaml.Node) error {
 	var v string
 	if err := value.Decode(&v); err != nil {
 		return err
 	}
 	var ok bool
-	for _, expected := range enumValues_WaitConditionCompareType {
+	for _, expected := range enumValues_UpgradeMapperTargetType {
 		if reflect.DeepEqual(v, expected) {
 			ok = true
 			break
 		}
 	}
 	if !ok {
-		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_WaitConditionCompareType, v)
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_UpgradeMapperTargetType, v)
 	}
-	*j = WaitConditionCompareType(v)
+	*j = UpgradeMapperTargetType(v)
 	return nil
 }
 
-const WaitConditionCompareTypeAnd WaitConditionCompareType = "and"
-const WaitConditionCompareTypeOr WaitConditionCompareType = "or"
+const UpgradeMapperTargetTypeEntity UpgradeMapperTargetType = "entity"
+const UpgradeMapperTargetTypeItem UpgradeMapperTargetType = "item"
 
-// A condition defining how long a train waits at a station.
-type WaitCondition struct {
-	// Logical operator for combining conditions.
-	CompareType *WaitConditionCompareType `json:"compare_type,omitempty" yaml:"compare_type,omitempty" mapstructure:"compare_type,omitempty"`
+// An entity or item on either side of an upgrade planner mapper.
+type UpgradeMapperTarget struct {
+	// Name of the prototype.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
 
-	// A condition object used when type is 'item_count', 'circuit', or 'fluid_count'
-	// (optional).
-	Condition *Condition `json:"condition,omitempty" yaml:"condition,omitempty" mapstructure:"condition,omitempty"`
+	// The prototype name of the quality (new in Factorio 2.0). nil for normal.
+	Quality *Quality `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
 
-	// Number of ticks to wait (used with 'time' or 'inactivity' types).
-	Ticks *int `json:"ticks,omitempty" yaml:"ticks,omitempty" mapstructure:"ticks,omitempty"`
+	// Whether name is an entity or an item (such as a module) prototype.
+	Type UpgradeMapperTargetType `json:"type" yaml:"type" mapstructure:"type"`
 
-	// Type of the wait condition.
-	Type *string `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type,omitempty"`
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
-// A single record in a train schedule.
-type ScheduleRecord struct {
-	// Name of the train stop.
-	Station *string `json:"station,omitempty" yaml:"station,omitempty" mapstructure:"station,omitempty"`
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *UpgradeMapperTarget) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in UpgradeMapperTarget: required")
+	}
+	if v, ok := raw["type"]; !ok || v == nil {
+		return fmt.Errorf("field type in UpgradeMapperTarget: required")
+	}
+	type Plain UpgradeMapperTarget
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = UpgradeMapperTarget(plain)
+	return nil
+}
 
-	// Conditions under which the train waits at this stop.
-	WaitConditions []WaitCondition `json:"wait_conditions,omitempty" yaml:"wait_conditions,omitempty" mapstructure:"wait_conditions,omitempty"`
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *UpgradeMapperTarget) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in UpgradeMapperTarget: required")
+	}
+	if v, ok := raw["type"]; !ok || v == nil {
+		return fmt.Errorf("field type in UpgradeMapperTarget: required")
+	}
+	type Plain UpgradeMapperTarget
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
+		return err
+	}
+	*j = UpgradeMapperTarget(plain)
+	return nil
 }
 
-// Train schedule data.
-type Schedule struct {
-	// Entity numbers of locomotives using this schedule.
-	Locomotives []int `json:"locomotives,omitempty" yaml:"locomotives,omitempty" mapstructure:"locomotives,omitempty"`
+// A single replacement of an upgrade planner.
+type UpgradeMapper struct {
+	// The entity or item which is replaced.
+	From *UpgradeMapperTarget `json:"from,omitempty" yaml:"from,omitempty" mapstructure:"from,omitempty"`
 
-	// Array of schedule records.
-	Schedule []ScheduleRecord `json:"schedule,omitempty" yaml:"schedule,omitempty" mapstructure:"schedule,omitempty"`
-}
+	// 0-based index of the mapper slot.
+	Index int `json:"index" yaml:"index" mapstructure:"index"`
 
-// A tile placed within the blueprint.
-type Tile struct {
-	// The prototype name of the tile.
-	Name string `json:"name" yaml:"name" mapstructure:"name"`
+	// The entity or item it is replaced with.
+	To *UpgradeMapperTarget `json:"to,omitempty" yaml:"to,omitempty" mapstructure:"to,omitempty"`
 
-	// The position of the tile on the blueprint grid.
-	Position Position `json:"position" yaml:"position" mapstructure:"position"`
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
-func (j *Tile) UnmarshalJSON(b []byte) error {
+func (j *UpgradeMapper) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
 	if err := json.Unmarshal(b, &raw); err != nil {
 		return err
 	}
-	if v, ok := raw["name"]; !ok || v == nil {
-		return fmt.Errorf("field name in Tile: required")
-	}
-	if v, ok := raw["position"]; !ok || v == nil {
-		return fmt.Errorf("field position in Tile: required")
+	if v, ok := raw["index"]; !ok || v == nil {
+		return fmt.Errorf("field index in UpgradeMapper: required")
 	}
-	type Plain Tile
+	type Plain UpgradeMapper
 	var plain Plain
 	if err := json.Unmarshal(b, &plain); err != nil {
 		return err
 	}
-	*j = Tile(plain)
+	*j = UpgradeMapper(plain)
 	return nil
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *Tile) UnmarshalYAML(value *yaml.Node) error {
+func (j *UpgradeMapper) UnmarshalYAML(value *yaml.Node) error {
 	var raw map[string]interface{}
 	if err := value.Decode(&raw); err != nil {
 		return err
 	}
-	if v, ok := raw["name"]; !ok || v == nil {
-		return fmt.Errorf("field name in Tile: required")
-	}
-	if v, ok := raw["position"]; !ok || v == nil {
-		return fmt.Errorf("field position in Tile: required")
+	if v, ok := raw["index"]; !ok || v == nil {
+		return fmt.Errorf("field index in UpgradeMapper: required")
 	}
-	type Plain Tile
+	type Plain UpgradeMapper
 	var plain Plain
 	if err := value.Decode(&plain); err != nil {
 		return err
 	}
-	*j = Tile(plain)
+	*j = UpgradeMapper(plain)
 	return nil
 }
 
-const SignalIDTypeVirtual SignalIDType = "virtual"
+// The settings of an upgrade planner.
+type UpgradePlannerSettings struct {
+	// An optional description of the upgrade planner.
+	Description *string `json:"description,omitempty" yaml:"description,omitempty" mapstructure:"description,omitempty"`
+
+	// Icons set by the user for the upgrade planner.
+	Icons []Icon `json:"icons,omitempty" yaml:"icons,omitempty" mapstructure:"icons,omitempty"`
+
+	// The replacements made by the upgrade planner.
+	Mappers []UpgradeMapper `json:"mappers,omitempty" yaml:"mappers,omitempty" mapstructure:"mappers,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// An object representing a Factorio upgrade planner.
+type UpgradePlanner struct {
+	// The name of the item; always 'upgrade-planner' in vanilla Factorio.
+	Item UpgradePlannerItem `json:"item" yaml:"item" mapstructure:"item"`
+
+	// The user-defined name of the upgrade planner.
+	Label *string `json:"label,omitempty" yaml:"label,omitempty" mapstructure:"label,omitempty"`
+
+	// The color assigned to the upgrade planner's label.
+	LabelColor *Color `json:"label_color,omitempty" yaml:"label_color,omitempty" mapstructure:"label_color,omitempty"`
+
+	// The settings of the upgrade planner.
+	Settings *UpgradePlannerSettings `json:"settings,omitempty" yaml:"settings,omitempty" mapstructure:"settings,omitempty"`
+
+	// The game version, as four 16-bit parts, when the upgrade planner was created.
+	Version GameVersion `json:"version" yaml:"version" mapstructure:"version"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
 
 // UnmarshalJSON implements json.Unmarshaler.
-func (j *Blueprint) UnmarshalJSON(b []byte) error {
+func (j *UpgradePlanner) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
 	if err := json.Unmarshal(b, &raw); err != nil {
 		return err
 	}
-	if v, ok := raw["entities"]; !ok || v == nil {
-		return fmt.Errorf("field entities in Blueprint: required")
-	}
-	if v, ok := raw["icons"]; !ok || v == nil {
-		return fmt.Errorf("field icons in Blueprint: required")
-	}
 	if v, ok := raw["item"]; !ok || v == nil {
-		return fmt.Errorf("field item in Blueprint: required")
+		return fmt.Errorf("field item in UpgradePlanner: required")
 	}
 	if v, ok := raw["version"]; !ok || v == nil {
-		return fmt.Errorf("field version in Blueprint: required")
+		return fmt.Errorf("field version in UpgradePlanner: required")
 	}
-	type Plain Blueprint
+	type Plain UpgradePlanner
 	var plain Plain
 	if err := json.Unmarshal(b, &plain); err != nil {
 		return err
 	}
-	*j = Blueprint(plain)
+	*j = UpgradePlanner(plain)
 	return nil
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
-func (j *Blueprint) UnmarshalYAML(value *yaml.Node) error {
+func (j *UpgradePlanner) UnmarshalYAML(value *yaml.Node) error {
 	var raw map[string]interface{}
 	if err := value.Decode(&raw); err != nil {
 		return err
 	}
-	if v, ok := raw["entities"]; !ok || v == nil {
-		return fmt.Errorf("field entities in Blueprint: required")
-	}
-	if v, ok := raw["icons"]; !ok || v == nil {
-		return fmt.Errorf("field icons in Blueprint: required")
-	}
 	if v, ok := raw["item"]; !ok || v == nil {
-		return fmt.Errorf("field item in Blueprint: required")
+		return fmt.Errorf("field item in UpgradePlanner: required")
 	}
 	if v, ok := raw["version"]; !ok || v == nil {
-		return fmt.Errorf("field version in Blueprint: required")
+		return fmt.Errorf("field version in UpgradePlanner: required")
 	}
-	type Plain Blueprint
+	type Plain UpgradePlanner
 	var plain Plain
 	if err := value.Decode(&plain); err != nil {
 		return err
 	}
-	*j = Blueprint(plain)
+	*j = UpgradePlanner(plain)
 	return nil
 }
 
@@ -1353,9 +2288,6 @@ func (j *BlueprintBookBlueprintsElem) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(b, &raw); err != nil {
 		return err
 	}
-	if v, ok := raw["blueprint"]; !ok || v == nil {
-		return fmt.Errorf("field blueprint in BlueprintBookBlueprintsElem: required")
-	}
 	if v, ok := raw["index"]; !ok || v == nil {
 		return fmt.Errorf("field index in BlueprintBookBlueprintsElem: required")
 	}
@@ -1374,9 +2306,6 @@ func (j *BlueprintBookBlueprintsElem) UnmarshalYAML(value *yaml.Node) error {
 	if err := value.Decode(&raw); err != nil {
 		return err
 	}
-	if v, ok := raw["blueprint"]; !ok || v == nil {
-		return fmt.Errorf("field blueprint in BlueprintBookBlueprintsElem: required")
-	}
 	if v, ok := raw["index"]; !ok || v == nil {
 		return fmt.Errorf("field index in BlueprintBookBlueprintsElem: required")
 	}
@@ -1523,8 +2452,243 @@ func (j *BlueprintBook) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// An item prototype of a quality.
+type ItemIDAndQuality struct {
+	// Name of the item prototype.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// The prototype name of the quality. nil for normal.
+	Quality *Quality `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *ItemIDAndQuality) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in ItemIDAndQuality: required")
+	}
+	type Plain ItemIDAndQuality
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = ItemIDAndQuality(plain)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *ItemIDAndQuality) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name in ItemIDAndQuality: required")
+	}
+	type Plain ItemIDAndQuality
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
+		return err
+	}
+	*j = ItemIDAndQuality(plain)
+	return nil
+}
+
+// A slot of an inventory of an entity.
+type InventoryPosition struct {
+	// Number of items going into the slot. 1 if not set.
+	Count *int `json:"count,omitempty" yaml:"count,omitempty" mapstructure:"count,omitempty"`
+
+	// The defines.inventory index of the inventory.
+	Inventory int `json:"inventory" yaml:"inventory" mapstructure:"inventory"`
+
+	// 0-based index of the slot in the inventory.
+	Stack int `json:"stack" yaml:"stack" mapstructure:"stack"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *InventoryPosition) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["inventory"]; !ok || v == nil {
+		return fmt.Errorf("field inventory in InventoryPosition: required")
+	}
+	if v, ok := raw["stack"]; !ok || v == nil {
+		return fmt.Errorf("field stack in InventoryPosition: required")
+	}
+	type Plain InventoryPosition
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = InventoryPosition(plain)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *InventoryPosition) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	if v, ok := raw["inventory"]; !ok || v == nil {
+		return fmt.Errorf("field inventory in InventoryPosition: required")
+	}
+	if v, ok := raw["stack"]; !ok || v == nil {
+		return fmt.Errorf("field stack in InventoryPosition: required")
+	}
+	type Plain InventoryPosition
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
+		return err
+	}
+	*j = InventoryPosition(plain)
+	return nil
+}
+
+// Positions of requested items in the inventories and equipment grid of an entity.
+type ItemInventoryPositions struct {
+	// Number of items going into the equipment grid.
+	GridCount *int `json:"grid_count,omitempty" yaml:"grid_count,omitempty" mapstructure:"grid_count,omitempty"`
+
+	// Inventory slots the items go into.
+	InInventory []InventoryPosition `json:"in_inventory,omitempty" yaml:"in_inventory,omitempty" mapstructure:"in_inventory,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// Items of one kind and quality requested by an entity, and where they go (new in
+// Factorio 2.0).
+type InsertPlan struct {
+	// The requested item.
+	ID ItemIDAndQuality `json:"id" yaml:"id" mapstructure:"id"`
+
+	// Where the requested items go.
+	Items ItemInventoryPositions `json:"items" yaml:"items" mapstructure:"items"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *InsertPlan) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["id"]; !ok || v == nil {
+		return fmt.Errorf("field id in InsertPlan: required")
+	}
+	if v, ok := raw["items"]; !ok || v == nil {
+		return fmt.Errorf("field items in InsertPlan: required")
+	}
+	type Plain InsertPlan
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = InsertPlan(plain)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *InsertPlan) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	if v, ok := raw["id"]; !ok || v == nil {
+		return fmt.Errorf("field id in InsertPlan: required")
+	}
+	if v, ok := raw["items"]; !ok || v == nil {
+		return fmt.Errorf("field items in InsertPlan: required")
+	}
+	type Plain InsertPlan
+	var plain Plain
+	if err := value.Decode(&plain); err != nil {
+		return err
+	}
+	*j = InsertPlan(plain)
+	return nil
+}
+
+// Item requests by the entity for construction.
+type ItemRequest map[string]int
+
+// Filter settings for logistic containers.
+type LogisticFilter struct {
+	// Requested item count (0 for storage chests).
+	Count *int `json:"count,omitempty" yaml:"count,omitempty" mapstructure:"count,omitempty"`
+
+	// 1-based index of the filter slot.
+	Index *int `json:"index,omitempty" yaml:"index,omitempty" mapstructure:"index,omitempty"`
+
+	// Name of the item prototype.
+	Name *string `json:"name,omitempty" yaml:"name,omitempty" mapstructure:"name,omitempty"`
+
+	// Extra holds fields which are not known to the schema. It is filled in by
+	// UnmarshalLossless and YAML decoding, and written out by MarshalLossless
+	// and YAML encoding.
+	Extra ExtraFields `json:"-" yaml:",inline" mapstructure:",remain"`
+
+	// Layout records the order of the keys and the text of the values of the
+	// JSON object the struct was decoded from. It is filled in by
+	// UnmarshalLossless, and used by MarshalLossless to write out unchanged
+	// values as they were.
+	Layout *ObjectLayout `json:"-" yaml:"-" mapstructure:"-"`
+}
+
 var enumValues_SignalIDType = []interface{}{
 	"item",
 	"fluid",
 	"virtual",
+	"entity",
+	"recipe",
+	"quality",
+	"space-location",
+	"asteroid-chunk",
 }
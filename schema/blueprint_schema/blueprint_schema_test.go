@@ -0,0 +1,331 @@
+package blueprint_schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Example of reading the parts of the version of a blueprint.
+func ExampleGameVersion() {
+	var m BlueprintSchemaJSON
+	data := `{"blueprint": {"item": "blueprint", "entities": [], "icons": [], "version": 281479278886912}}`
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		panic(err)
+	}
+
+	v := m.Blueprint.Version
+	fmt.Println(v)
+	fmt.Println(v.Major(), v.Minor(), v.Patch(), v.Build())
+	fmt.Println(v.AtLeast(GameVersion2_0))
+
+	// Output:
+	// 1.1.110.0
+	// 1 1 110 0
+	// false
+}
+
+func TestGameVersion(t *testing.T) {
+	for _, tc := range []struct {
+		packed GameVersion
+		s      string
+	}{
+		{281479271677952, "1.1.0.0"},
+		{281479273986304, "1.1.35.14592"},
+		{281479278886912, "1.1.110.0"},
+		{562949954076673, "2.0.10.1"},
+		{NewGameVersion(65535, 65535, 65535, 65535), "65535.65535.65535.65535"},
+	} {
+		if got := tc.packed.String(); got != tc.s {
+			t.Errorf("Bad data: want = '%v' got '%v'", tc.s, got)
+		}
+		v, err := ParseGameVersion(tc.s)
+		if err != nil {
+			t.Fatalf("ParseGameVersion(%q) failed: %v", tc.s, err)
+		}
+		if v != tc.packed {
+			t.Errorf("Bad data: want = %d got %d", uint64(tc.packed), uint64(v))
+		}
+		if got := NewGameVersion(v.Major(), v.Minor(), v.Patch(), v.Build()); got != tc.packed {
+			t.Errorf("Bad data: want = %d got %d", uint64(tc.packed), uint64(got))
+		}
+	}
+
+	if v, err := ParseGameVersion("2.0.28"); err != nil || v != NewGameVersion(2, 0, 28, 0) {
+		t.Errorf("Bad data: want = 2.0.28.0 got '%v' (%v)", v, err)
+	}
+	for _, s := range []string{"", "1.1", "1.1.1.1.1", "1.x.0", "1.65536.0"} {
+		if _, err := ParseGameVersion(s); err == nil {
+			t.Errorf("ParseGameVersion(%q): got no error", s)
+		}
+	}
+
+	older, newer := NewGameVersion(1, 1, 110, 0), NewGameVersion(2, 0, 0, 0)
+	if older.Compare(newer) != -1 || newer.Compare(older) != 1 || older.Compare(older) != 0 {
+		t.Errorf("Bad data: Compare of %v and %v", older, newer)
+	}
+}
+
+// Example of counting requested items of each quality, such as for a bill of
+// materials.
+func ExampleEntityItems_Counts() {
+	var e Entity
+	data := `{"entity_number": 1, "name": "assembling-machine-3", "position": {"x": 1.5, "y": 1.5}, "items": [
+		{"id": {"name": "speed-module", "quality": "rare"}, "items": {"in_inventory": [{"inventory": 4, "stack": 0}, {"inventory": 4, "stack": 1}]}},
+		{"id": {"name": "speed-module"}, "items": {"in_inventory": [{"inventory": 4, "stack": 2}]}}
+	]}`
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		panic(err)
+	}
+
+	counts := e.Items.Counts()
+	fmt.Println(counts[ItemWithQuality{Name: "speed-module", Quality: QualityRare}])
+	fmt.Println(counts[ItemWithQuality{Name: "speed-module", Quality: QualityNormal}])
+
+	// Output:
+	// 2
+	// 1
+}
+
+func TestBlueprint_RenumberEntities(t *testing.T) {
+	var bp Blueprint
+	data := `{"item": "blueprint", "version": 0, "icons": [], "entities": [
+		{"entity_number": 1, "name": "small-electric-pole", "position": {"x": 0.5, "y": 0.5}, "neighbours": [2]},
+		{"entity_number": 2, "name": "small-electric-pole", "position": {"x": 4.5, "y": 0.5}, "neighbours": [1],
+			"connections": {"1": {"red": [{"entity_id": 1}]}}},
+		{"entity_number": 3, "name": "locomotive", "position": {"x": 0, "y": 5}}
+	], "wires": [[1, 1, 2, 1]], "schedules": [{"locomotives": [3]}]}`
+	if err := json.Unmarshal([]byte(data), &bp); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	if err := bp.RenumberEntities(map[int]int{1: 10, 2: 20}); err == nil {
+		t.Fatalf("Bad data: want error for entity 3 without a new number")
+	}
+	if bp.Entities[0].EntityNumber != 1 || bp.Wires[0][0] != 1 {
+		t.Fatalf("Bad data: blueprint changed on error: '%+v'", bp)
+	}
+
+	if err := bp.RenumberEntities(map[int]int{1: 10, 2: 20, 3: 30}); err != nil {
+		t.Fatalf("RenumberEntities() failed: %v", err)
+	}
+	got := fmt.Sprint(bp.Entities[0].EntityNumber, bp.Entities[0].Neighbours, bp.Entities[1].Connections.A1.Red[0].EntityID, bp.Wires, bp.Schedules[0].Locomotives)
+	if want := "10 [20] 10 [[10 1 20 1]] [30]"; got != want {
+		t.Fatalf("Bad data: want = '%v' got '%v'", want, got)
+	}
+}
+
+// TestMarshalLossless_layout tests that keys keep their order, and unchanged
+// values their text, with changed and new keys written as usual.
+func TestMarshalLossless_layout(t *testing.T) {
+	data := `{"name": "inserter", "position": {"y": 2.50, "x": 1.0}, "mod_note": "caf\u00e9", "entity_number": 1, "tags": {"b": 1, "a": 2}}`
+	var e Entity
+	if err := UnmarshalLossless([]byte(data), &e); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	got, err := MarshalLossless(&e)
+	if err != nil {
+		t.Fatalf("Failed to encode JSON: %v", err)
+	}
+	if want := `{"name":"inserter","position":{"y":2.50,"x":1.0},"mod_note":"caf\u00e9","entity_number":1,"tags":{"b":1,"a":2}}`; string(got) != want {
+		t.Errorf("Bad data: want = '%s' got '%s'", want, got)
+	}
+
+	e.Position.X = 3
+	direction := 4
+	e.Direction = &direction
+	e.Extra["mod_new"] = true
+	got, err = MarshalLossless(&e)
+	if err != nil {
+		t.Fatalf("Failed to encode JSON: %v", err)
+	}
+	if want := `{"name":"inserter","position":{"y":2.50,"x":3},"mod_note":"caf\u00e9","entity_number":1,"tags":{"b":1,"a":2},"direction":4,"mod_new":true}`; string(got) != want {
+		t.Errorf("Bad data: want = '%s' got '%s'", want, got)
+	}
+}
+
+// Example of moving an entity exactly, and finding the tiles it covers.
+func ExampleFixedPosition() {
+	// A 3x3 assembling machine.
+	p := Position{X: 1.5, Y: -0.5}.Fixed()
+	p = p.Add(FixedPosition{X: FixedFromFloat(0.1), Y: FixedOne})
+	fmt.Println(p, p.SnapCenter(3, 3), p.SnapCenter(3, 3).TopLeft(3, 3))
+
+	b, _ := json.Marshal(p.Rotate(1))
+	fmt.Println(string(b))
+
+	// Output:
+	// (1.6015625, 0.5) (1.5, 0.5) {0 -1}
+	// {"x":-0.5,"y":1.6015625}
+}
+
+func TestFixed(t *testing.T) {
+	for _, tc := range []struct {
+		in    float64
+		want  Fixed
+		str   string
+		floor int
+	}{
+		{0, 0, "0", 0},
+		{math.Copysign(0, -1), 0, "0", 0},
+		{0.5, 128, "0.5", 0},
+		{-0.5, -128, "-0.5", -1},
+		{-1, -256, "-1", -1},
+		{1.0 / 3, 85, "0.33203125", 0},
+		{0.1 + 0.2, 77, "0.30078125", 0},
+		{-2.00390625, -513, "-2.00390625", -3},
+	} {
+		got := FixedFromFloat(tc.in)
+		if got != tc.want || got.String() != tc.str || got.Floor() != tc.floor {
+			t.Errorf("Bad data: %v: want = '%v %v %v' got '%v %v %v'", tc.in, tc.want, tc.str, tc.floor, int32(got), got, got.Floor())
+		}
+	}
+
+	// Sums are exact.
+	var f Fixed
+	for i := 0; i < 10; i++ {
+		f += FixedFromFloat(0.125)
+	}
+	if f != FixedFromTiles(1)+FixedFromFloat(0.25) {
+		t.Errorf("Bad data: want = '1.25' got '%v'", f)
+	}
+
+	var p FixedPosition
+	if err := json.Unmarshal([]byte(`{"x": -3.5, "y": 1e-9}`), &p); err != nil || p != (FixedPosition{X: -896}) {
+		t.Errorf("Bad data: want = '(-3.5, 0)' got '%v' '%v'", p, err)
+	}
+	if err := json.Unmarshal([]byte(`{"x": 1e10, "y": 0}`), &p); err == nil {
+		t.Errorf("Bad data: want error for position out of range got '%v'", p)
+	}
+	var y struct{ P FixedPosition }
+	if err := yaml.Unmarshal([]byte("p: {x: 0.5, y: -2}\n"), &y); err != nil || y.P != (FixedPosition{X: 128, Y: -512}) {
+		t.Errorf("Bad data: want = '(0.5, -2)' got '%v' '%v'", y.P, err)
+	}
+	if b, err := yaml.Marshal(y.P); err != nil || string(b) != "x: 0.5\n\"y\": -2\n" {
+		t.Errorf("Bad data: want YAML of (0.5, -2) got '%s' '%v'", b, err)
+	}
+}
+
+func TestFixedPosition_entitySize(t *testing.T) {
+	for _, tc := range []struct {
+		center        Position
+		width, height int
+		topLeft       TilePosition
+	}{
+		{Position{X: 0.5, Y: 0.5}, 1, 1, TilePosition{0, 0}},
+		{Position{X: -0.5, Y: -0.5}, 1, 1, TilePosition{-1, -1}},
+		{Position{X: 0, Y: 0}, 2, 2, TilePosition{-1, -1}},
+		{Position{X: 1.5, Y: 1.5}, 3, 3, TilePosition{0, 0}},
+		{Position{X: 1, Y: 0.5}, 2, 1, TilePosition{0, 0}},
+		{Position{X: 0.5, Y: 2}, 1, 4, TilePosition{0, 0}},
+	} {
+		p := tc.center.Fixed()
+		if got := p.TopLeft(tc.width, tc.height); got != tc.topLeft {
+			t.Errorf("Bad data: %v of %dx%d: want top left = '%v' got '%v'", tc.center, tc.width, tc.height, tc.topLeft, got)
+		}
+		if got := tc.topLeft.CenterOf(tc.width, tc.height); got != p {
+			t.Errorf("Bad data: %v of %dx%d: want center = '%v' got '%v'", tc.topLeft, tc.width, tc.height, p, got)
+		}
+		if got := p.Add(FixedPosition{X: 100, Y: -100}).SnapCenter(tc.width, tc.height); got != p {
+			t.Errorf("Bad data: %v of %dx%d: want snapped = '%v' got '%v'", tc.center, tc.width, tc.height, p, got)
+		}
+	}
+
+	p := FixedPosition{X: 3, Y: -7}
+	for turns := -4; turns <= 4; turns++ {
+		if got := p.Rotate(turns).Rotate(-turns); got != p {
+			t.Errorf("Bad data: %d turns: want = '%v' got '%v'", turns, p, got)
+		}
+	}
+	if got := p.Rotate(1).Rotate(1); got != p.Rotate(2) {
+		t.Errorf("Bad data: want = '%v' got '%v'", p.Rotate(2), got)
+	}
+}
+
+// Example of turning an inserter of a 1.1 blueprint around.
+func ExampleDirection() {
+	bp := Blueprint{Version: GameVersion1_1}
+	direction := 2
+	e := Entity{EntityNumber: 1, Name: "inserter", Direction: &direction}
+
+	d := bp.EntityDirection(&e)
+	fmt.Println(d, d.Opposite(), d.Rotate(1), d.MirrorX())
+	if err := bp.SetEntityDirection(&e, d.Opposite()); err != nil {
+		panic(err)
+	}
+	fmt.Println(*e.Direction)
+	fmt.Println(bp.SetEntityDirection(&e, DirectionEastNorthEast))
+
+	// Output:
+	// east west south west
+	// 6
+	// entity 1: direction eastnortheast has no equivalent in version 1.1.0.0
+}
+
+func TestDirection(t *testing.T) {
+	for d := Direction(-16); d < 32; d++ {
+		n := d.normalize()
+		if n.Opposite().Opposite() != n || n.MirrorX().MirrorX() != n || n.MirrorY().MirrorY() != n {
+			t.Errorf("Bad data: %v: want operations to undo themselves", d)
+		}
+		if n.Rotate(2) != n.Opposite() || n.Rotate(4) != n || n.Rotate(-1) != n.Rotate(3) {
+			t.Errorf("Bad data: %v: want rotations to add up", d)
+		}
+		if n.MirrorX().MirrorY() != n.Opposite() {
+			t.Errorf("Bad data: %v: want mirroring both ways to be turning around", d)
+		}
+	}
+
+	for _, tc := range []struct {
+		d                  Direction
+		mirrorX, mirrorY   Direction
+		cardinal, diagonal bool
+	}{
+		{DirectionNorth, DirectionNorth, DirectionSouth, true, false},
+		{DirectionEast, DirectionWest, DirectionEast, true, false},
+		{DirectionNorthEast, DirectionNorthWest, DirectionSouthEast, false, true},
+		{DirectionNorthNorthEast, DirectionNorthNorthWest, DirectionSouthSouthEast, false, false},
+		{DirectionWestSouthWest, DirectionEastSouthEast, DirectionWestNorthWest, false, false},
+	} {
+		if got := tc.d.MirrorX(); got != tc.mirrorX {
+			t.Errorf("Bad data: %v mirrored x: want = '%v' got '%v'", tc.d, tc.mirrorX, got)
+		}
+		if got := tc.d.MirrorY(); got != tc.mirrorY {
+			t.Errorf("Bad data: %v mirrored y: want = '%v' got '%v'", tc.d, tc.mirrorY, got)
+		}
+		if tc.d.IsCardinal() != tc.cardinal || tc.d.IsDiagonal() != tc.diagonal {
+			t.Errorf("Bad data: %v: want cardinal %v diagonal %v", tc.d, tc.cardinal, tc.diagonal)
+		}
+	}
+
+	for _, tc := range []struct {
+		raw     int
+		version GameVersion
+		want    Direction
+	}{
+		{0, 0, DirectionNorth},
+		{3, GameVersion1_1, DirectionSouthEast},
+		{7, GameVersion1_1, DirectionNorthWest},
+		{3, GameVersion2_0, DirectionEastNorthEast},
+		{12, NewGameVersion(2, 0, 28, 0), DirectionWest},
+		{16, GameVersion2_0, DirectionNorth},
+		{-1, GameVersion2_0, DirectionNorthNorthWest},
+	} {
+		if got := DirectionFromRaw(tc.raw, tc.version); got != tc.want {
+			t.Errorf("Bad data: %d in %v: want = '%v' got '%v'", tc.raw, tc.version, tc.want, got)
+		}
+	}
+	if raw, ok := DirectionSouthWest.Raw(GameVersion1_1); raw != 5 || !ok {
+		t.Errorf("Bad data: want = '5 true' got '%v %v'", raw, ok)
+	}
+	if raw, ok := DirectionNorthNorthWest.Raw(GameVersion1_1); raw != 7 || ok {
+		t.Errorf("Bad data: want = '7 false' got '%v %v'", raw, ok)
+	}
+	if s := Direction(16).String(); s != "direction(16)" {
+		t.Errorf("Bad data: want = 'direction(16)' got '%v'", s)
+	}
+}
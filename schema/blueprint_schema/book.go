@@ -0,0 +1,80 @@
+package blueprint_schema
+
+import "fmt"
+
+// BookEntryKind tells which of the objects of a BlueprintBookBlueprintsElem is
+// set. Its String is the JSON key of that object.
+type BookEntryKind int
+
+const (
+	// BookEntryUnknown is an entry with none of the known objects set, such
+	// as an item added by a mod, which is then kept in Extra.
+	BookEntryUnknown BookEntryKind = iota
+	BookEntryBlueprint
+	BookEntryBlueprintBook
+	BookEntryUpgradePlanner
+	BookEntryDeconstructionPlanner
+)
+
+// String returns the JSON key of the object of this kind.
+func (k BookEntryKind) String() string {
+	switch k {
+	case BookEntryBlueprint:
+		return "blueprint"
+	case BookEntryBlueprintBook:
+		return "blueprint_book"
+	case BookEntryUpgradePlanner:
+		return "upgrade_planner"
+	case BookEntryDeconstructionPlanner:
+		return "deconstruction_planner"
+	default:
+		return "unknown"
+	}
+}
+
+// Kind returns which object the entry holds. It returns an error if more than
+// one is set, which the game never does.
+func (j *BlueprintBookBlueprintsElem) Kind() (BookEntryKind, error) {
+	kind := BookEntryUnknown
+	set := 0
+	if j.Blueprint != nil {
+		kind = BookEntryBlueprint
+		set++
+	}
+	if j.BlueprintBook != nil {
+		kind = BookEntryBlueprintBook
+		set++
+	}
+	if j.UpgradePlanner != nil {
+		kind = BookEntryUpgradePlanner
+		set++
+	}
+	if j.DeconstructionPlanner != nil {
+		kind = BookEntryDeconstructionPlanner
+		set++
+	}
+	if set > 1 {
+		return BookEntryUnknown, fmt.Errorf("book entry %d: %d objects set, want at most 1", j.Index, set)
+	}
+	return kind, nil
+}
+
+// Label returns the user-defined name of the object held by the entry, or an
+// empty string if it has none.
+func (j *BlueprintBookBlueprintsElem) Label() string {
+	var label *string
+	switch {
+	case j.Blueprint != nil:
+		label = j.Blueprint.Label
+	case j.BlueprintBook != nil:
+		label = j.BlueprintBook.Label
+	case j.UpgradePlanner != nil:
+		label = j.UpgradePlanner.Label
+	case j.DeconstructionPlanner != nil:
+		label = j.DeconstructionPlanner.Label
+	}
+	if label == nil {
+		return ""
+	}
+	return *label
+}
@@ -0,0 +1,15 @@
+package blueprint_schema
+
+// Clone returns a deep copy of the blueprint, including the fields unknown to
+// the schema kept in Extra.
+func (bp *Blueprint) Clone() (*Blueprint, error) {
+	b, err := MarshalLossless(bp)
+	if err != nil {
+		return nil, err
+	}
+	var out Blueprint
+	if err := UnmarshalLossless(b, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,138 @@
+package blueprint_schema
+
+import (
+	"fmt"
+)
+
+// Direction is the direction an entity faces, in the 16-way numbering of
+// Factorio 2.0, clockwise from north. Blueprints from before 2.0 number the
+// same directions 8-way, so use DirectionFromRaw and Direction.Raw, or the
+// EntityDirection and SetEntityDirection methods of Blueprint, to convert
+// between Direction and Entity.Direction.
+type Direction int
+
+// Directions, as named by defines.direction of the game.
+const (
+	DirectionNorth Direction = iota
+	DirectionNorthNorthEast
+	DirectionNorthEast
+	DirectionEastNorthEast
+	DirectionEast
+	DirectionEastSouthEast
+	DirectionSouthEast
+	DirectionSouthSouthEast
+	DirectionSouth
+	DirectionSouthSouthWest
+	DirectionSouthWest
+	DirectionWestSouthWest
+	DirectionWest
+	DirectionWestNorthWest
+	DirectionNorthWest
+	DirectionNorthNorthWest
+
+	// directionCount is the number of directions.
+	directionCount
+)
+
+var directionNames = [directionCount]string{
+	"north", "northnortheast", "northeast", "eastnortheast",
+	"east", "eastsoutheast", "southeast", "southsoutheast",
+	"south", "southsouthwest", "southwest", "westsouthwest",
+	"west", "westnorthwest", "northwest", "northnorthwest",
+}
+
+// is16Way returns whether blueprints of version number directions 16-way.
+// Blueprints without a version are taken to be from before 2.0.
+func is16Way(version GameVersion) bool {
+	return version.AtLeast(GameVersion2_0)
+}
+
+// DirectionFromRaw returns the Direction of a value of Entity.Direction in a
+// blueprint of version. Values out of range wrap around.
+func DirectionFromRaw(raw int, version GameVersion) Direction {
+	if !is16Way(version) {
+		raw *= 2
+	}
+	return Direction(raw).normalize()
+}
+
+// Raw returns the value of Entity.Direction for d in a blueprint of version.
+// It returns false if version numbers directions 8-way and d is between two
+// of those, such as north-northeast; the value is then rounded towards
+// north, counterclockwise.
+func (d Direction) Raw(version GameVersion) (int, bool) {
+	d = d.normalize()
+	if !is16Way(version) {
+		return int(d) / 2, d%2 == 0
+	}
+	return int(d), true
+}
+
+func (d Direction) normalize() Direction {
+	return ((d % directionCount) + directionCount) % directionCount
+}
+
+// Rotate returns d turned clockwise by the number of quarter turns. Negative
+// turns go counterclockwise.
+func (d Direction) Rotate(quarterTurns int) Direction {
+	return (d + Direction(quarterTurns)*DirectionEast).normalize()
+}
+
+// Opposite returns the direction opposite d.
+func (d Direction) Opposite() Direction {
+	return (d + DirectionSouth).normalize()
+}
+
+// MirrorX returns d mirrored left to right, swapping east and west.
+func (d Direction) MirrorX() Direction {
+	return (directionCount - d).normalize()
+}
+
+// MirrorY returns d mirrored top to bottom, swapping north and south.
+func (d Direction) MirrorY() Direction {
+	return (DirectionSouth - d).normalize()
+}
+
+// IsCardinal returns whether d is one of north, east, south and west.
+func (d Direction) IsCardinal() bool {
+	return d.normalize()%DirectionEast == 0
+}
+
+// IsDiagonal returns whether d is one of northeast, southeast, southwest and
+// northwest.
+func (d Direction) IsDiagonal() bool {
+	return d.normalize()%DirectionEast == DirectionNorthEast
+}
+
+// String returns the name of d, such as "northeast".
+func (d Direction) String() string {
+	if d < 0 || d >= directionCount {
+		return fmt.Sprintf("direction(%d)", int(d))
+	}
+	return directionNames[d]
+}
+
+// EntityDirection returns the direction of e, an entity of bp. Entities
+// without a direction face north.
+func (bp *Blueprint) EntityDirection(e *Entity) Direction {
+	if e.Direction == nil {
+		return DirectionNorth
+	}
+	return DirectionFromRaw(*e.Direction, bp.Version)
+}
+
+// SetEntityDirection sets the direction of e, an entity of bp. North is left
+// out, as the game does. It returns an error, and leaves e unchanged, if bp
+// numbers directions 8-way and d is between two of those.
+func (bp *Blueprint) SetEntityDirection(e *Entity, d Direction) error {
+	raw, ok := d.Raw(bp.Version)
+	if !ok {
+		return fmt.Errorf("entity %d: direction %v has no equivalent in version %v", e.EntityNumber, d, bp.Version)
+	}
+	if raw == 0 {
+		e.Direction = nil
+	} else {
+		e.Direction = &raw
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package blueprint_schema
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EntityItems are the items requested by an entity, such as modules or fuel.
+// Factorio 1.1 stores them as an object mapping item names to counts,
+// Factorio 2.0 as an array of insert plans which also hold the quality of the
+// items and which slots they go into. After decoding, Requests is set for the
+// former and InsertPlans for the latter.
+//
+// If both are set, InsertPlans is encoded.
+type EntityItems struct {
+	// Requests is the Factorio 1.1 form.
+	Requests ItemRequest
+
+	// InsertPlans is the Factorio 2.0 form.
+	InsertPlans []InsertPlan
+}
+
+// Counts returns the number of requested items of each name and quality.
+// Items in the 1.1 form are of normal quality.
+func (j EntityItems) Counts() map[ItemWithQuality]int {
+	counts := make(map[ItemWithQuality]int)
+	for name, count := range j.Requests {
+		counts[ItemWithQuality{Name: name, Quality: QualityNormal}] += count
+	}
+	for _, plan := range j.InsertPlans {
+		counts[ItemWithQuality{Name: plan.ID.Name, Quality: QualityOf(plan.ID.Quality)}] += plan.Items.Count()
+	}
+	return counts
+}
+
+// Count returns the number of items going into inventories and the
+// equipment grid.
+func (j ItemInventoryPositions) Count() int {
+	n := 0
+	for _, pos := range j.InInventory {
+		if pos.Count == nil {
+			n++
+		} else {
+			n += *pos.Count
+		}
+	}
+	if j.GridCount != nil {
+		n += *j.GridCount
+	}
+	return n
+}
+
+// MarshalJSON implements json.Marshaler. Fields unknown to the schema are
+// kept, as with MarshalLossless.
+func (j EntityItems) MarshalJSON() ([]byte, error) {
+	if j.InsertPlans != nil {
+		return MarshalLossless(j.InsertPlans)
+	}
+	return MarshalLossless(j.Requests)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Fields unknown to the schema are
+// kept, as with UnmarshalLossless.
+func (j *EntityItems) UnmarshalJSON(b []byte) error {
+	switch trimmed := bytes.TrimSpace(b); {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		*j = EntityItems{InsertPlans: []InsertPlan{}}
+		return UnmarshalLossless(trimmed, &j.InsertPlans)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		*j = EntityItems{}
+		return UnmarshalLossless(trimmed, &j.Requests)
+	case bytes.Equal(trimmed, []byte("null")):
+		return nil
+	default:
+		return fmt.Errorf("field items: want an array or an object")
+	}
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (j EntityItems) MarshalYAML() (interface{}, error) {
+	if j.InsertPlans != nil {
+		return j.InsertPlans, nil
+	}
+	return j.Requests, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *EntityItems) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		*j = EntityItems{InsertPlans: []InsertPlan{}}
+		return value.Decode(&j.InsertPlans)
+	case yaml.MappingNode:
+		*j = EntityItems{}
+		return value.Decode(&j.Requests)
+	default:
+		return fmt.Errorf("field items: want a sequence or a mapping")
+	}
+}
@@ -0,0 +1,192 @@
+package blueprint_schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixed is a map coordinate in the fixed point format of the game: a signed
+// number of 1/256 of a tile. Positions in blueprints are written as floating
+// point numbers, but the game only keeps this precision, so arithmetic on
+// Fixed is exact where arithmetic on float64 may drift.
+//
+// Fixed is written to JSON and YAML as a number of tiles, such as 1.5.
+type Fixed int32
+
+// FixedOne is one tile.
+const FixedOne Fixed = 256
+
+// FixedHalf is half a tile, the offset of the center of a tile from its
+// corner.
+const FixedHalf Fixed = FixedOne / 2
+
+// FixedFromFloat rounds a number of tiles to the nearest Fixed, halves away
+// from zero.
+func FixedFromFloat(f float64) Fixed {
+	return Fixed(math.Round(f * float64(FixedOne)))
+}
+
+// FixedFromTiles returns the Fixed of a whole number of tiles.
+func FixedFromTiles(n int) Fixed {
+	return Fixed(n) * FixedOne
+}
+
+// Float64 returns f as a number of tiles. It is exact.
+func (f Fixed) Float64() float64 {
+	return float64(f) / float64(FixedOne)
+}
+
+// Floor returns the whole number of tiles at or before f, which is the tile f
+// is in.
+func (f Fixed) Floor() int {
+	// Arithmetic shift rounds towards negative infinity.
+	return int(f >> 8)
+}
+
+// String returns f as the shortest decimal number of tiles, such as "-0.5".
+func (f Fixed) String() string {
+	return strconv.FormatFloat(f.Float64(), 'f', -1, 64)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (f Fixed) MarshalJSON() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The number is rounded with
+// FixedFromFloat.
+func (f *Fixed) UnmarshalJSON(b []byte) error {
+	var v float64
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return f.set(v)
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (f Fixed) MarshalYAML() (interface{}, error) {
+	return f.Float64(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (f *Fixed) UnmarshalYAML(value *yaml.Node) error {
+	var v float64
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+	return f.set(v)
+}
+
+func (f *Fixed) set(v float64) error {
+	if r := v * float64(FixedOne); math.IsNaN(r) || r < math.MinInt32 || r > math.MaxInt32 {
+		return fmt.Errorf("position %v out of range", v)
+	}
+	*f = FixedFromFloat(v)
+	return nil
+}
+
+// FixedPosition is a position on the map in the fixed point format of the
+// game. It is written to JSON and YAML like Position.
+type FixedPosition struct {
+	X Fixed `json:"x" yaml:"x"`
+	Y Fixed `json:"y" yaml:"y"`
+}
+
+// Fixed returns p rounded to the precision of the game. Extra fields of p are
+// dropped.
+func (p Position) Fixed() FixedPosition {
+	return FixedPosition{X: FixedFromFloat(p.X), Y: FixedFromFloat(p.Y)}
+}
+
+// Position returns p as a Position, with exact coordinates.
+func (p FixedPosition) Position() Position {
+	return Position{X: p.X.Float64(), Y: p.Y.Float64()}
+}
+
+// Add returns p moved by o.
+func (p FixedPosition) Add(o FixedPosition) FixedPosition {
+	return FixedPosition{X: p.X + o.X, Y: p.Y + o.Y}
+}
+
+// Sub returns p moved back by o, the offset of p from o.
+func (p FixedPosition) Sub(o FixedPosition) FixedPosition {
+	return FixedPosition{X: p.X - o.X, Y: p.Y - o.Y}
+}
+
+// Rotate returns p turned around the origin clockwise, as seen in the game
+// where y grows downwards, by the number of quarter turns. Negative turns go
+// counterclockwise.
+func (p FixedPosition) Rotate(quarterTurns int) FixedPosition {
+	switch ((quarterTurns % 4) + 4) % 4 {
+	case 1:
+		return FixedPosition{X: -p.Y, Y: p.X}
+	case 2:
+		return FixedPosition{X: -p.X, Y: -p.Y}
+	case 3:
+		return FixedPosition{X: p.Y, Y: -p.X}
+	default:
+		return p
+	}
+}
+
+// Tile returns the tile p is in.
+func (p FixedPosition) Tile() TilePosition {
+	return TilePosition{X: p.X.Floor(), Y: p.Y.Floor()}
+}
+
+// TopLeft returns the top left tile covered by an entity of width by height
+// tiles with its center at p.
+func (p FixedPosition) TopLeft(width, height int) TilePosition {
+	return FixedPosition{
+		X: p.X - FixedFromTiles(width)/2,
+		Y: p.Y - FixedFromTiles(height)/2,
+	}.Tile()
+}
+
+// SnapCenter returns the center nearest to p at which an entity of width by
+// height tiles lines up with the tile grid: the middle of a tile for an odd
+// size, and a tile corner for an even size.
+func (p FixedPosition) SnapCenter(width, height int) FixedPosition {
+	// Round the top left corner to the nearest tile corner.
+	return p.Add(FixedPosition{X: FixedHalf, Y: FixedHalf}).TopLeft(width, height).CenterOf(width, height)
+}
+
+func (p FixedPosition) String() string {
+	return fmt.Sprintf("(%v, %v)", p.X, p.Y)
+}
+
+// TilePosition is the position of a tile on the map, which is also the
+// position of its top left corner. Tiles in blueprints are written at it.
+type TilePosition struct {
+	X int `json:"x" yaml:"x"`
+	Y int `json:"y" yaml:"y"`
+}
+
+// Fixed returns the top left corner of t.
+func (t TilePosition) Fixed() FixedPosition {
+	return FixedPosition{X: FixedFromTiles(t.X), Y: FixedFromTiles(t.Y)}
+}
+
+// Center returns the center of t, the position of an entity of one tile on
+// it.
+func (t TilePosition) Center() FixedPosition {
+	return t.CenterOf(1, 1)
+}
+
+// CenterOf returns the center of an entity of width by height tiles with its
+// top left tile at t.
+func (t TilePosition) CenterOf(width, height int) FixedPosition {
+	return FixedPosition{
+		X: FixedFromTiles(t.X) + FixedFromTiles(width)/2,
+		Y: FixedFromTiles(t.Y) + FixedFromTiles(height)/2,
+	}
+}
+
+// Add returns t moved by o tiles.
+func (t TilePosition) Add(o TilePosition) TilePosition {
+	return TilePosition{X: t.X + o.X, Y: t.Y + o.Y}
+}
@@ -0,0 +1,601 @@
+package blueprint_schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExtraFields holds object keys which are not known to the schema, such as
+// settings of modded entities or fields added in newer game versions, along
+// with their values.
+//
+// Values are what encoding/json would decode into an interface{}, except that
+// integral numbers are kept as int64 so that they survive a round trip
+// without losing precision.
+type ExtraFields map[string]interface{}
+
+// ObjectLayout records how a JSON object was written: the order of its keys,
+// and the text of the values which are not decoded into schema structs, such
+// as numbers, strings and fields unknown to the schema.
+type ObjectLayout struct {
+	members []layoutMember
+}
+
+// layoutMember is a key of an object as it was written.
+type layoutMember struct {
+	name string
+	key  []byte // quoted, as written
+	raw  []byte // the value as written; nil for values decoded into structs
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	extraFieldsType   = reflect.TypeOf(ExtraFields(nil))
+	objectLayoutType  = reflect.TypeOf((*ObjectLayout)(nil))
+)
+
+// UnmarshalLossless decodes JSON data into v just like json.Unmarshal, but
+// also fills in the Extra field of every schema struct with the keys which
+// are not known to the schema, and its Layout field with how the object was
+// written.
+//
+// Together with MarshalLossless, this allows decoding a blueprint, editing it
+// and encoding it again without losing any data. Object keys keep their order,
+// and unchanged numbers and strings their text, so that encoding v without
+// changes gives back data with the whitespace removed, byte for byte.
+func UnmarshalLossless(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return err
+	}
+	fillExtra(reflect.ValueOf(v), compact.Bytes())
+	return nil
+}
+
+// MarshalLossless encodes v into JSON just like json.Marshal, but also writes
+// out the Extra field of every schema struct. Objects decoded by
+// UnmarshalLossless keep the order of their keys, with keys set since then
+// after them; values which did not change keep their text. Other objects have
+// their known fields in the same order as json.Marshal would write them,
+// followed by the extra fields sorted by key.
+func MarshalLossless(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeLossless(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fillExtra walks the decoded value together with the compacted JSON it was
+// decoded from, and stores keys unknown to the structs in their Extra field,
+// and how their objects were written in their Layout field.
+func fillExtra(rv reflect.Value, raw []byte) {
+	if raw == nil || !rv.IsValid() {
+		return
+	}
+	if isOpaque(rv.Type()) {
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return
+		}
+		fillExtra(rv.Elem(), raw)
+	case reflect.Struct:
+		members, ok := splitObject(raw)
+		if !ok {
+			return
+		}
+		layout := &ObjectLayout{members: make([]layoutMember, len(members))}
+		byName := make(map[string]int, len(members))
+		for i, m := range members {
+			name := keyName(m.key)
+			layout.members[i] = layoutMember{name: name, key: m.key, raw: m.value}
+			byName[name] = i
+		}
+
+		known := make(map[string]bool)
+		var extra, layoutField reflect.Value
+		for i := 0; i < rv.NumField(); i++ {
+			f := rv.Type().Field(i)
+			switch f.Type {
+			case extraFieldsType:
+				extra = rv.Field(i)
+				continue
+			case objectLayoutType:
+				layoutField = rv.Field(i)
+				continue
+			}
+			name, _, skip := jsonFieldName(f)
+			if skip {
+				continue
+			}
+			known[name] = true
+			j, ok := byName[name]
+			if !ok {
+				continue
+			}
+			fillExtra(rv.Field(i), members[j].value)
+			if holdsStructs(f.Type) {
+				// The structs keep their own layout.
+				layout.members[j].raw = nil
+			}
+		}
+
+		if extra.IsValid() && extra.CanSet() {
+			fields := ExtraFields{}
+			for _, m := range layout.members {
+				if !known[m.name] {
+					fields[m.name] = decodeGeneric(m.raw)
+				}
+			}
+			if len(fields) > 0 {
+				extra.Set(reflect.ValueOf(fields))
+			} else {
+				extra.Set(reflect.Zero(extraFieldsType))
+			}
+		}
+		if layoutField.IsValid() && layoutField.CanSet() {
+			layoutField.Set(reflect.ValueOf(layout))
+		}
+	case reflect.Slice, reflect.Array:
+		if !holdsStructs(rv.Type().Elem()) {
+			return // nothing to walk into
+		}
+		elems, ok := splitArray(raw)
+		if !ok {
+			return
+		}
+		for i := 0; i < rv.Len() && i < len(elems); i++ {
+			fillExtra(rv.Index(i), elems[i])
+		}
+	case reflect.Map:
+		if rv.IsNil() || rv.Type().Key().Kind() != reflect.String {
+			return
+		}
+		elemType := rv.Type().Elem()
+		if elemType.Kind() == reflect.Interface && rv.CanSet() {
+			// Arbitrary data, such as tags. Replace it with the normalized
+			// tree so that large integers keep their precision.
+			obj, ok := decodeGeneric(raw).(map[string]interface{})
+			if !ok {
+				return
+			}
+			m := reflect.MakeMapWithSize(rv.Type(), len(obj))
+			for k, val := range obj {
+				if val == nil {
+					m.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), reflect.Zero(elemType))
+					continue
+				}
+				m.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), reflect.ValueOf(val))
+			}
+			rv.Set(m)
+			return
+		}
+		if !holdsStructs(elemType) {
+			return // nothing to walk into
+		}
+		members, ok := splitObject(raw)
+		if !ok {
+			return
+		}
+		byName := make(map[string][]byte, len(members))
+		for _, m := range members {
+			byName[keyName(m.key)] = m.value
+		}
+		for _, key := range rv.MapKeys() {
+			// Map elements are not addressable, so work on a copy.
+			elem := reflect.New(elemType).Elem()
+			elem.Set(rv.MapIndex(key))
+			fillExtra(elem, byName[key.String()])
+			rv.SetMapIndex(key, elem)
+		}
+	}
+}
+
+// holdsStructs returns whether values of type t are, or hold, schema structs
+// which fillExtra and encodeLossless walk into.
+func holdsStructs(t reflect.Type) bool {
+	if isOpaque(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return holdsStructs(t.Elem())
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && holdsStructs(t.Elem())
+	default:
+		return false
+	}
+}
+
+// decodeGeneric decodes a JSON value as encoding/json would decode it into an
+// interface{}, but with numbers as normalizeNumbers returns them.
+func decodeGeneric(raw []byte) interface{} {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil
+	}
+	return normalizeNumbers(v)
+}
+
+// normalizeNumbers converts json.Number values in a generic JSON tree into
+// int64 where possible, and float64 otherwise.
+func normalizeNumbers(node interface{}) interface{} {
+	switch n := node.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+			return i
+		}
+		f, _ := strconv.ParseFloat(string(n), 64)
+		return f
+	case map[string]interface{}:
+		for k, v := range n {
+			n[k] = normalizeNumbers(v)
+		}
+		return n
+	case []interface{}:
+		for i, v := range n {
+			n[i] = normalizeNumbers(v)
+		}
+		return n
+	default:
+		return node
+	}
+}
+
+// rawMember is a key and a value of an object in compacted JSON.
+type rawMember struct {
+	key, value []byte
+}
+
+// splitObject returns the members of an object in compacted JSON, or false if
+// raw is not an object.
+func splitObject(raw []byte) ([]rawMember, bool) {
+	if len(raw) < 2 || raw[0] != '{' {
+		return nil, false
+	}
+	var members []rawMember
+	for i := 1; i < len(raw) && raw[i] != '}'; {
+		keyEnd := skipValue(raw, i)
+		valueEnd := skipValue(raw, keyEnd+1) // after the colon
+		members = append(members, rawMember{key: raw[i:keyEnd], value: raw[keyEnd+1 : valueEnd]})
+		i = valueEnd
+		if i < len(raw) && raw[i] == ',' {
+			i++
+		}
+	}
+	return members, true
+}
+
+// splitArray returns the elements of an array in compacted JSON, or false if
+// raw is not an array.
+func splitArray(raw []byte) ([][]byte, bool) {
+	if len(raw) < 2 || raw[0] != '[' {
+		return nil, false
+	}
+	var elems [][]byte
+	for i := 1; i < len(raw) && raw[i] != ']'; {
+		end := skipValue(raw, i)
+		elems = append(elems, raw[i:end])
+		i = end
+		if i < len(raw) && raw[i] == ',' {
+			i++
+		}
+	}
+	return elems, true
+}
+
+// skipValue returns the end of the value starting at i in valid, compacted
+// JSON.
+func skipValue(raw []byte, i int) int {
+	depth := 0
+	for ; i < len(raw); i++ {
+		switch raw[i] {
+		case '"':
+			for i++; i < len(raw) && raw[i] != '"'; i++ {
+				if raw[i] == '\\' {
+					i++
+				}
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+			if depth < 0 {
+				return i // the end of the enclosing object or array
+			}
+		case ',', ':':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// keyName returns the string a quoted object key stands for.
+func keyName(key []byte) string {
+	if bytes.IndexByte(key, '\\') < 0 {
+		return string(key[1 : len(key)-1])
+	}
+	var name string
+	json.Unmarshal(key, &name)
+	return name
+}
+
+// encodeLossless writes rv as JSON into buf, including the Extra fields.
+func encodeLossless(buf *bytes.Buffer, rv reflect.Value) error {
+	if !rv.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	if isOpaque(rv.Type()) {
+		return encodeWithJSON(buf, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeLossless(buf, rv.Elem())
+	case reflect.Struct:
+		return encodeStruct(buf, rv)
+	case reflect.Slice:
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		buf.WriteByte('[')
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeLossless(buf, rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case reflect.Map:
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return encodeWithJSON(buf, rv)
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeWithJSON(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeLossless(buf, rv.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	default:
+		return encodeWithJSON(buf, rv)
+	}
+}
+
+// structMember is a key encodeStruct writes, with its value.
+type structMember struct {
+	name   string
+	value  reflect.Value
+	extra  bool          // whether value is held in the Extra field
+	layout *layoutMember // how it was written, if it was decoded
+}
+
+// encodeStruct writes a struct as a JSON object, honoring the json tags, and
+// appends the keys held in its Extra field. If the struct has a layout, the
+// keys are written in its order, and unchanged values as they were.
+func encodeStruct(buf *bytes.Buffer, rv reflect.Value) error {
+	var extra ExtraFields
+	var layout *ObjectLayout
+	members := make([]structMember, 0, rv.NumField())
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		switch f.Type {
+		case extraFieldsType:
+			extra = rv.Field(i).Interface().(ExtraFields)
+			continue
+		case objectLayoutType:
+			layout = rv.Field(i).Interface().(*ObjectLayout)
+			continue
+		}
+		name, omitEmpty, skip := jsonFieldName(f)
+		if skip || (omitEmpty && isEmptyValue(rv.Field(i))) {
+			continue
+		}
+		members = append(members, structMember{name: name, value: rv.Field(i)})
+	}
+
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		members = append(members, structMember{name: k, value: reflect.ValueOf(extra[k]), extra: true})
+	}
+	if layout != nil {
+		members = layout.order(members)
+	}
+
+	buf.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if m.layout != nil {
+			buf.Write(m.layout.key)
+		} else if err := encodeWithJSON(buf, reflect.ValueOf(m.name)); err != nil {
+			return err
+		}
+		buf.WriteByte(':')
+
+		start := buf.Len()
+		if m.extra {
+			b, err := json.Marshal(extra[m.name])
+			if err != nil {
+				return fmt.Errorf("extra field %s: %w", m.name, err)
+			}
+			buf.Write(b)
+		} else if err := encodeLossless(buf, m.value); err != nil {
+			return fmt.Errorf("field %s: %w", m.name, err)
+		}
+		if m.layout != nil && m.layout.raw != nil && m.unchanged(buf.Bytes()[start:]) {
+			buf.Truncate(start)
+			buf.Write(m.layout.raw)
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// order returns the members in the order their keys were written, followed by
+// the members which were not written, in their own order.
+func (l *ObjectLayout) order(members []structMember) []structMember {
+	ordered := make([]structMember, 0, len(members))
+	used := make([]bool, len(members))
+	for i := range l.members {
+		for j := range members {
+			if !used[j] && members[j].name == l.members[i].name {
+				members[j].layout = &l.members[i]
+				ordered = append(ordered, members[j])
+				used[j] = true
+				break
+			}
+		}
+	}
+	for j := range members {
+		if !used[j] {
+			ordered = append(ordered, members[j])
+		}
+	}
+	return ordered
+}
+
+// unchanged returns whether encoded, the value of m as encoded now, is what
+// the value m was decoded from encodes to. The text the value was written as
+// may differ from both, such as 1.0 for the number 1.
+func (m structMember) unchanged(encoded []byte) bool {
+	raw := m.layout.raw
+	if bytes.Equal(encoded, raw) {
+		return true
+	}
+
+	var was []byte
+	if m.extra {
+		b, err := json.Marshal(decodeGeneric(raw))
+		if err != nil {
+			return false
+		}
+		was = b
+	} else {
+		v := reflect.New(m.value.Type())
+		if err := json.Unmarshal(raw, v.Interface()); err != nil {
+			return false
+		}
+		fillExtra(v, raw)
+		var b bytes.Buffer
+		if err := encodeLossless(&b, v.Elem()); err != nil {
+			return false
+		}
+		was = b.Bytes()
+	}
+	return bytes.Equal(encoded, was)
+}
+
+// encodeWithJSON writes rv using encoding/json.
+func encodeWithJSON(buf *bytes.Buffer, rv reflect.Value) error {
+	b, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// isOpaque returns whether values of type t encode themselves. Such values
+// are not walked into, and are passed to encoding/json as they are.
+func isOpaque(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || (t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(jsonMarshalerType))
+}
+
+// jsonFieldName returns the JSON object key for a struct field, whether it has
+// the omitempty option, and whether it is skipped by encoding/json.
+func jsonFieldName(f reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	if f.PkgPath != "" {
+		return "", false, true // unexported
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// isEmptyValue mirrors the definition of empty values used by encoding/json
+// for the omitempty option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package blueprint_schema
+
+// Values of DeconstructionPlannerSettings.EntityFilterMode and TileFilterMode.
+const (
+	FilterModeWhitelist = 0
+	FilterModeBlacklist = 1
+)
+
+// Values of DeconstructionPlannerSettings.TileSelectionMode.
+const (
+	TileSelectionModeNormal = 0
+	TileSelectionModeAlways = 1
+	TileSelectionModeNever  = 2
+	TileSelectionModeOnly   = 3
+)
@@ -0,0 +1,36 @@
+package blueprint_schema
+
+// Quality is the prototype name of a quality (new in Factorio 2.0). Blueprints
+// leave it out for normal quality, so a nil *Quality and an empty Quality both
+// mean QualityNormal. Mods may add more qualities.
+type Quality string
+
+// Qualities of vanilla Factorio with Space Age.
+const (
+	QualityNormal    Quality = "normal"
+	QualityUncommon  Quality = "uncommon"
+	QualityRare      Quality = "rare"
+	QualityEpic      Quality = "epic"
+	QualityLegendary Quality = "legendary"
+)
+
+// QualityOf returns the quality q points to, or QualityNormal if q is nil or
+// empty.
+func QualityOf(q *Quality) Quality {
+	if q == nil || *q == "" {
+		return QualityNormal
+	}
+	return *q
+}
+
+// IsNormal returns whether q is normal quality.
+func (q Quality) IsNormal() bool {
+	return q == "" || q == QualityNormal
+}
+
+// ItemWithQuality identifies an item of a quality, such as to count items of
+// different qualities separately.
+type ItemWithQuality struct {
+	Name    string
+	Quality Quality
+}
@@ -0,0 +1,99 @@
+package blueprint_schema
+
+import (
+	"fmt"
+)
+
+// RenumberEntities changes the entity numbers of the blueprint to the ones in
+// numbers, which maps old entity numbers to new ones, along with everything
+// referring to entities by number: wires, connections, neighbours and the
+// locomotives of schedules.
+//
+// It returns an error, and leaves the blueprint unchanged, if an entity or a
+// reference to one is not in numbers. Entity numbers in fields unknown to the
+// schema are not changed.
+func (bp *Blueprint) RenumberEntities(numbers map[int]int) error {
+	lookup := func(n int) (int, error) {
+		if m, ok := numbers[n]; ok {
+			return m, nil
+		}
+		return 0, fmt.Errorf("entity %d has no new number", n)
+	}
+
+	// Check everything first, so that nothing is changed on error.
+	for _, e := range bp.Entities {
+		if _, err := lookup(e.EntityNumber); err != nil {
+			return err
+		}
+		for _, n := range e.Neighbours {
+			if _, err := lookup(n); err != nil {
+				return fmt.Errorf("entity %d: neighbour: %w", e.EntityNumber, err)
+			}
+		}
+		for _, data := range connectionData(e.Connections) {
+			if _, err := lookup(data.EntityID); err != nil {
+				return fmt.Errorf("entity %d: connection: %w", e.EntityNumber, err)
+			}
+		}
+	}
+	for i, w := range bp.Wires {
+		if len(w) != 4 {
+			return fmt.Errorf("wire %d: want 4 numbers, got %d", i, len(w))
+		}
+		for _, n := range []int{w[0], w[2]} {
+			if _, err := lookup(n); err != nil {
+				return fmt.Errorf("wire %d: %w", i, err)
+			}
+		}
+	}
+	for i, s := range bp.Schedules {
+		for _, n := range s.Locomotives {
+			if _, err := lookup(n); err != nil {
+				return fmt.Errorf("schedule %d: locomotive: %w", i, err)
+			}
+		}
+	}
+
+	for i := range bp.Entities {
+		e := &bp.Entities[i]
+		e.EntityNumber = numbers[e.EntityNumber]
+		for j, n := range e.Neighbours {
+			e.Neighbours[j] = numbers[n]
+		}
+		for _, data := range connectionData(e.Connections) {
+			data.EntityID = numbers[data.EntityID]
+		}
+	}
+	for _, w := range bp.Wires {
+		w[0], w[2] = numbers[w[0]], numbers[w[2]]
+	}
+	for _, s := range bp.Schedules {
+		for j, n := range s.Locomotives {
+			s.Locomotives[j] = numbers[n]
+		}
+	}
+	return nil
+}
+
+// connectionData returns pointers to all connection data of the 1.1
+// connections of an entity.
+func connectionData(c *Connection) []*ConnectionData {
+	if c == nil {
+		return nil
+	}
+	var out []*ConnectionData
+	add := func(data []ConnectionData) {
+		for i := range data {
+			out = append(out, &data[i])
+		}
+	}
+	for _, p := range []*ConnectionPoint{c.A1, c.A2} {
+		if p != nil {
+			add(p.Red)
+			add(p.Green)
+		}
+	}
+	add(c.Cu0)
+	add(c.Cu1)
+	return out
+}
@@ -0,0 +1,70 @@
+package blueprint_schema
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RequestFilters are the logistic requests of a logistic container. Factorio
+// 1.1 stores them as an array of filters, Factorio 2.0 as an object holding
+// logistic sections. After decoding, Filters is set for the former and
+// Sections for the latter.
+//
+// If both are set, Sections is encoded.
+type RequestFilters struct {
+	// Filters is the Factorio 1.1 form.
+	Filters []LogisticFilter
+
+	// Sections is the Factorio 2.0 form.
+	Sections *LogisticSections
+}
+
+// MarshalJSON implements json.Marshaler. Fields unknown to the schema are
+// kept, as with MarshalLossless.
+func (j RequestFilters) MarshalJSON() ([]byte, error) {
+	if j.Sections != nil {
+		return MarshalLossless(j.Sections)
+	}
+	return MarshalLossless(j.Filters)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Fields unknown to the schema are
+// kept, as with UnmarshalLossless.
+func (j *RequestFilters) UnmarshalJSON(b []byte) error {
+	switch trimmed := bytes.TrimSpace(b); {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		*j = RequestFilters{}
+		return UnmarshalLossless(trimmed, &j.Filters)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		*j = RequestFilters{Sections: &LogisticSections{}}
+		return UnmarshalLossless(trimmed, j.Sections)
+	case bytes.Equal(trimmed, []byte("null")):
+		return nil
+	default:
+		return fmt.Errorf("field request_filters: want an array or an object")
+	}
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (j RequestFilters) MarshalYAML() (interface{}, error) {
+	if j.Sections != nil {
+		return j.Sections, nil
+	}
+	return j.Filters, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (j *RequestFilters) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		*j = RequestFilters{}
+		return value.Decode(&j.Filters)
+	case yaml.MappingNode:
+		*j = RequestFilters{Sections: &LogisticSections{}}
+		return value.Decode(j.Sections)
+	default:
+		return fmt.Errorf("field request_filters: want a sequence or a mapping")
+	}
+}
@@ -0,0 +1,82 @@
+package blueprint_schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GameVersion is the version of the game a blueprint was created with. It
+// packs four 16-bit parts, major, minor, patch and build, into a single
+// number, with major in the highest bits. For example, 281479278886912 is
+// 1.1.110.0.
+//
+// Because of the packing, versions compare correctly as plain numbers.
+type GameVersion uint64
+
+// Versions of the game which changed the blueprint format.
+const (
+	// GameVersion1_1 is 1.1.0.0.
+	GameVersion1_1 GameVersion = 1<<48 | 1<<32
+	// GameVersion2_0 is 2.0.0.0, which changed directions to 16-way, and
+	// introduced wires, quality and logistic sections.
+	GameVersion2_0 GameVersion = 2 << 48
+)
+
+// NewGameVersion packs the four parts into a GameVersion.
+func NewGameVersion(major, minor, patch, build uint16) GameVersion {
+	return GameVersion(major)<<48 | GameVersion(minor)<<32 | GameVersion(patch)<<16 | GameVersion(build)
+}
+
+// ParseGameVersion parses a version in the form returned by String, such as
+// "1.1.110.0". The build part may be left out.
+func ParseGameVersion(s string) (GameVersion, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 && len(parts) != 4 {
+		return 0, fmt.Errorf("game version %q: want 3 or 4 parts, got %d", s, len(parts))
+	}
+	var p [4]uint16
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("game version %q: %w", s, err)
+		}
+		p[i] = uint16(n)
+	}
+	return NewGameVersion(p[0], p[1], p[2], p[3]), nil
+}
+
+// Major returns the first part of the version.
+func (v GameVersion) Major() uint16 { return uint16(v >> 48) }
+
+// Minor returns the second part of the version.
+func (v GameVersion) Minor() uint16 { return uint16(v >> 32) }
+
+// Patch returns the third part of the version.
+func (v GameVersion) Patch() uint16 { return uint16(v >> 16) }
+
+// Build returns the fourth part of the version.
+func (v GameVersion) Build() uint16 { return uint16(v) }
+
+// String returns the version in the form "1.1.110.0".
+func (v GameVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v.Major(), v.Minor(), v.Patch(), v.Build())
+}
+
+// Compare returns -1 if v is older than o, 0 if they are the same, and +1 if
+// v is newer than o.
+func (v GameVersion) Compare(o GameVersion) int {
+	switch {
+	case v < o:
+		return -1
+	case v > o:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AtLeast returns whether v is the same as or newer than o.
+func (v GameVersion) AtLeast(o GameVersion) bool {
+	return v >= o
+}
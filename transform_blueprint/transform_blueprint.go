@@ -0,0 +1,290 @@
+// Package transform_blueprint rotates and mirrors blueprints, such as to get
+// the left-hand and right-hand versions of the same build.
+//
+// Blueprints are turned around their origin, which is a tile corner, so
+// entities of any size stay on the tile grid, and rails on the rail grid:
+//
+//	t := transform_blueprint.Transform{MirrorX: true, QuarterTurns: 1}
+//	if err := transform_blueprint.Apply(bp, t, nil); err != nil {
+//		return err
+//	}
+//
+// Positions and directions of entities and tiles are transformed, as are the
+// orientation of wagons, the pickup and drop positions of inserters, the
+// snapping grid, and, when mirroring, the priorities of splitters and the
+// sides of train stops, rail signals and curved rails.
+//
+// The public interface is unstable.
+package transform_blueprint // badc0de.net/pkg/factorioblueprint/transform_blueprint
+
+import (
+	"fmt"
+	"math"
+
+	"badc0de.net/pkg/factorioblueprint/book_blueprint"
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Transform is a rotation and mirroring around the origin. Mirroring is done
+// first, then the rotation. The zero value changes nothing.
+type Transform struct {
+	// QuarterTurns is the number of quarter turns clockwise. Negative turns
+	// go counterclockwise.
+	QuarterTurns int
+
+	// MirrorX mirrors left to right, swapping east and west.
+	MirrorX bool
+
+	// MirrorY mirrors top to bottom, swapping north and south.
+	MirrorY bool
+}
+
+// Then returns the transform doing t, then o.
+func (t Transform) Then(o Transform) Transform {
+	// Mirroring after a rotation is the reverse rotation after mirroring.
+	q := t.QuarterTurns
+	if o.Mirrors() {
+		q = -q
+	}
+	return Transform{
+		QuarterTurns: ((q+o.QuarterTurns)%4 + 4) % 4,
+		MirrorX:      t.MirrorX != o.MirrorX,
+		MirrorY:      t.MirrorY != o.MirrorY,
+	}
+}
+
+// Mirrors returns whether t turns left-handed things right-handed.
+func (t Transform) Mirrors() bool {
+	return t.MirrorX != t.MirrorY
+}
+
+// Position returns p transformed.
+func (t Transform) Position(p blueprint_schema.FixedPosition) blueprint_schema.FixedPosition {
+	if t.MirrorX {
+		p.X = -p.X
+	}
+	if t.MirrorY {
+		p.Y = -p.Y
+	}
+	return p.Rotate(t.QuarterTurns)
+}
+
+// Box returns b transformed.
+func (t Transform) Box(b prototype_blueprint.Box) prototype_blueprint.Box {
+	lt, rb := t.Position(b.LeftTop), t.Position(b.RightBottom)
+	return prototype_blueprint.Box{LeftTop: lt, RightBottom: lt}.Union(prototype_blueprint.Box{LeftTop: rb, RightBottom: rb})
+}
+
+// Direction returns d transformed.
+func (t Transform) Direction(d blueprint_schema.Direction) blueprint_schema.Direction {
+	return t.direction(d, blueprint_schema.DirectionNorth)
+}
+
+// direction returns d transformed for an entity whose mirror image facing
+// north faces axis when mirrored left to right. That is north for most
+// entities, but not for those which only go on one side of something.
+func (t Transform) direction(d, axis blueprint_schema.Direction) blueprint_schema.Direction {
+	if t.MirrorX {
+		d = (d.MirrorX() + axis).Rotate(0)
+	}
+	if t.MirrorY {
+		d = (d.MirrorY() + axis).Rotate(0)
+	}
+	return d.Rotate(t.QuarterTurns)
+}
+
+// Orientation returns the orientation of a wagon, a fraction of a full turn
+// clockwise from north, transformed.
+func (t Transform) Orientation(o float64) float64 {
+	if t.MirrorX {
+		o = 1 - o
+	}
+	if t.MirrorY {
+		o = 0.5 - o
+	}
+	o = math.Mod(o+float64(t.QuarterTurns)/4, 1)
+	if o < 0 {
+		o++
+	}
+	return o
+}
+
+// mirrorAxes are the directions which entities of a prototype type facing
+// north face when mirrored left to right, where that is not north. Train
+// stops and signals go on the right of the track, so their mirror image is
+// on the left facing the other way.
+//
+// Rails which bend one way come in pairs of neighbouring directions which are
+// mirror images. For curved rails of Factorio 1.1 those are, 8-way, 0 and 1,
+// 2 and 7, and so on. For curved rails of Factorio 2.0, both A and B, they are
+// north and north-northeast, northeast and north-northwest, and so on; for
+// half-diagonal rails, which only face the 8 directions of 1.1, north and
+// northeast, east and northwest, and so on.
+var mirrorAxes = map[string]blueprint_schema.Direction{
+	"train-stop":                  blueprint_schema.DirectionSouth,
+	"rail-signal":                 blueprint_schema.DirectionSouth,
+	"rail-chain-signal":           blueprint_schema.DirectionSouth,
+	"curved-rail":                 blueprint_schema.DirectionNorthEast,
+	"curved-rail-a":               blueprint_schema.DirectionNorthNorthEast,
+	"curved-rail-b":               blueprint_schema.DirectionNorthNorthEast,
+	"elevated-curved-rail-a":      blueprint_schema.DirectionNorthNorthEast,
+	"elevated-curved-rail-b":      blueprint_schema.DirectionNorthNorthEast,
+	"half-diagonal-rail":          blueprint_schema.DirectionNorthEast,
+	"elevated-half-diagonal-rail": blueprint_schema.DirectionNorthEast,
+}
+
+// mirrorable are the prototype types which Factorio 2.0 mirrors with the
+// mirror flag of the entity, keeping the shape of their fluid boxes. Those
+// which cannot be rotated have no fluid boxes to keep.
+var mirrorable = map[string]bool{
+	"assembling-machine": true,
+	"furnace":            true,
+	"rocket-silo":        true,
+}
+
+// Apply transforms bp in place. Entities are looked up in prototypes for
+// whether they can be rotated, and for their sizes when bp snaps to a grid;
+// if prototypes is nil, prototype_blueprint.Vanilla is used.
+//
+// A blueprint which snaps to a grid is moved back so that its top left
+// corner stays where it was, as the game keeps it in the grid cell.
+func Apply(bp *blueprint_schema.Blueprint, t Transform, prototypes *prototype_blueprint.Registry) error {
+	if prototypes == nil {
+		prototypes = prototype_blueprint.Vanilla()
+	}
+	before, hasBounds := prototypes.Bounds(bp)
+
+	for i := range bp.Entities {
+		if err := transformEntity(bp, &bp.Entities[i], t, prototypes); err != nil {
+			return err
+		}
+	}
+	for i := range bp.Tiles {
+		tile := &bp.Tiles[i]
+		center := t.Position(tile.Position.Fixed().Tile().Center())
+		tile.Position = setPosition(tile.Position, center.TopLeft(1, 1).Fixed())
+	}
+
+	if bp.SnapToGrid == nil || !hasBounds {
+		return nil
+	}
+	after, _ := prototypes.Bounds(bp)
+	Translate(bp, before.LeftTop.Sub(after.LeftTop))
+
+	grid := bp.SnapToGrid.Fixed()
+	if bp.PositionRelativeToGrid != nil {
+		// Place the blueprint in its grid cell as it was, transform both,
+		// and take where the blueprint ends up in the transformed cell.
+		w, h := before.Size()
+		offset := bp.PositionRelativeToGrid.Fixed()
+		cell := t.Box(prototype_blueprint.Box{RightBottom: grid})
+		placed := t.Box(prototype_blueprint.Box{LeftTop: offset, RightBottom: offset.Add(blueprint_schema.FixedPosition{X: w, Y: h})})
+		cellW, cellH := cell.Size()
+		offset = placed.LeftTop.Sub(cell.LeftTop)
+		offset = blueprint_schema.FixedPosition{X: mod(offset.X, cellW), Y: mod(offset.Y, cellH)}
+		*bp.PositionRelativeToGrid = setPosition(*bp.PositionRelativeToGrid, offset)
+	}
+	if t.QuarterTurns%2 != 0 {
+		*bp.SnapToGrid = setPosition(*bp.SnapToGrid, blueprint_schema.FixedPosition{X: grid.Y, Y: grid.X})
+	}
+	return nil
+}
+
+// ApplyAll transforms the blueprint at the root of m, or every blueprint in
+// the book at the root of m.
+func ApplyAll(m *blueprint_schema.BlueprintSchemaJSON, t Transform, prototypes *prototype_blueprint.Registry) error {
+	if prototypes == nil {
+		prototypes = prototype_blueprint.Vanilla()
+	}
+	return book_blueprint.Walk(m, func(leaf book_blueprint.Leaf) error {
+		if leaf.Blueprint == nil {
+			return nil
+		}
+		if err := Apply(leaf.Blueprint, t, prototypes); err != nil {
+			return fmt.Errorf("%v: %w", leaf.Path, err)
+		}
+		return nil
+	})
+}
+
+// Translate moves all entities and tiles of bp by offset, which should be
+// whole tiles to keep them on the tile grid, or an even number of tiles to
+// keep rails on the rail grid.
+func Translate(bp *blueprint_schema.Blueprint, offset blueprint_schema.FixedPosition) {
+	for i := range bp.Entities {
+		e := &bp.Entities[i]
+		e.Position = setPosition(e.Position, e.Position.Fixed().Add(offset))
+	}
+	for i := range bp.Tiles {
+		tile := &bp.Tiles[i]
+		tile.Position = setPosition(tile.Position, tile.Position.Fixed().Add(offset))
+	}
+}
+
+func transformEntity(bp *blueprint_schema.Blueprint, e *blueprint_schema.Entity, t Transform, prototypes *prototype_blueprint.Registry) error {
+	p := prototypes.Prototype(e.Name)
+
+	e.Position = setPosition(e.Position, t.Position(e.Position.Fixed()))
+	if e.Direction != nil || p.Rotatable() {
+		d := t.direction(bp.EntityDirection(e), mirrorAxes[p.Type])
+		if err := bp.SetEntityDirection(e, d); err != nil {
+			return err
+		}
+	}
+	if e.Orientation != nil {
+		o := t.Orientation(*e.Orientation)
+		e.Orientation = &o
+	}
+	if e.PickupPosition != nil {
+		*e.PickupPosition = setPosition(*e.PickupPosition, t.Position(e.PickupPosition.Fixed()))
+	}
+	if e.DropPosition != nil {
+		*e.DropPosition = setPosition(*e.DropPosition, t.Position(e.DropPosition.Fixed()))
+	}
+
+	if !t.Mirrors() {
+		return nil
+	}
+	if e.InputPriority != nil {
+		v := blueprint_schema.EntityInputPriorityLeft
+		if *e.InputPriority == v {
+			v = blueprint_schema.EntityInputPriorityRight
+		}
+		e.InputPriority = &v
+	}
+	if e.OutputPriority != nil {
+		v := blueprint_schema.EntityOutputPriorityLeft
+		if *e.OutputPriority == v {
+			v = blueprint_schema.EntityOutputPriorityRight
+		}
+		e.OutputPriority = &v
+	}
+	if mirrorable[p.Type] && p.Rotatable() && bp.Version.AtLeast(blueprint_schema.GameVersion2_0) {
+		if e.Mirror != nil && *e.Mirror {
+			e.Mirror = nil
+		} else {
+			mirror := true
+			e.Mirror = &mirror
+		}
+	}
+	return nil
+}
+
+// setPosition returns old moved to p, keeping its extra fields and the order
+// of its keys.
+func setPosition(old blueprint_schema.Position, p blueprint_schema.FixedPosition) blueprint_schema.Position {
+	pos := p.Position()
+	pos.Extra = old.Extra
+	pos.Layout = old.Layout
+	return pos
+}
+
+// mod returns a modulo m, from 0 to m. A zero m, for a grid without size,
+// returns a.
+func mod(a, m blueprint_schema.Fixed) blueprint_schema.Fixed {
+	if m == 0 {
+		return a
+	}
+	return (a%m + m) % m
+}
@@ -0,0 +1,303 @@
+package transform_blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/prototype_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// setupTransformBlueprint creates a Factorio 2.0 blueprint with something of
+// everything which is transformed.
+//
+// A splitter with priorities feeds a belt going east into an inserter with a
+// custom pickup position, which loads a mirrored assembling machine. A train
+// stop and a wagon are south of them, and a tile of concrete under the belt.
+func setupTransformBlueprint() *blueprint_schema.Blueprint {
+	ptrInt := func(i int) *int { return &i }
+	ptrFloat := func(f float64) *float64 { return &f }
+	yes := true
+	left := blueprint_schema.EntityInputPriorityLeft
+	right := blueprint_schema.EntityOutputPriorityRight
+	return &blueprint_schema.Blueprint{
+		Item:    "blueprint",
+		Version: blueprint_schema.GameVersion2_0,
+		Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "splitter", Position: blueprint_schema.Position{X: -2.5, Y: 0}, Direction: ptrInt(4), InputPriority: &left, OutputPriority: &right},
+			{EntityNumber: 2, Name: "transport-belt", Position: blueprint_schema.Position{X: -1.5, Y: 0.5}, Direction: ptrInt(4)},
+			{EntityNumber: 3, Name: "inserter", Position: blueprint_schema.Position{X: -0.5, Y: 0.5}, Direction: ptrInt(12), PickupPosition: &blueprint_schema.Position{X: -1, Y: 0.25}},
+			{EntityNumber: 4, Name: "assembling-machine-2", Position: blueprint_schema.Position{X: 1.5, Y: 0.5}, Direction: ptrInt(4), Mirror: &yes},
+			{EntityNumber: 5, Name: "train-stop", Position: blueprint_schema.Position{X: 1, Y: 4}, Direction: ptrInt(4)},
+			{EntityNumber: 6, Name: "cargo-wagon", Position: blueprint_schema.Position{X: -3, Y: 6}, Orientation: ptrFloat(0.25)},
+			{EntityNumber: 7, Name: "stone-furnace", Position: blueprint_schema.Position{X: 4, Y: -1}},
+		},
+		Tiles: []blueprint_schema.Tile{
+			{Name: "concrete", Position: blueprint_schema.Position{X: -2, Y: 0}},
+		},
+	}
+}
+
+// transforms are all the different transforms.
+var transforms = func() []Transform {
+	var ts []Transform
+	for q := 0; q < 4; q++ {
+		for _, mx := range []bool{false, true} {
+			for _, my := range []bool{false, true} {
+				ts = append(ts, Transform{QuarterTurns: q, MirrorX: mx, MirrorY: my})
+			}
+		}
+	}
+	return ts
+}()
+
+// Example of mirroring a blueprint left to right.
+func ExampleApply() {
+	bp := setupTransformBlueprint()
+	if err := Apply(bp, Transform{MirrorX: true}, nil); err != nil {
+		panic(err)
+	}
+
+	for _, e := range bp.Entities[:5] {
+		fmt.Println(e.Name, e.Position.X, e.Position.Y, bp.EntityDirection(&e))
+	}
+	fmt.Println(*bp.Entities[0].InputPriority, *bp.Entities[0].OutputPriority)
+	fmt.Println(bp.Entities[2].PickupPosition.X, bp.Entities[2].PickupPosition.Y)
+	fmt.Println(bp.Entities[3].Mirror)
+	fmt.Println(bp.Tiles[0].Position.X, bp.Tiles[0].Position.Y)
+
+	// Output:
+	// splitter 2.5 0 west
+	// transport-belt 1.5 0.5 west
+	// inserter 0.5 0.5 east
+	// assembling-machine-2 -1.5 0.5 west
+	// train-stop -1 4 east
+	// right left
+	// 1 0.25
+	// <nil>
+	// 1 0
+}
+
+// TestTransform_Then tests that doing two transforms one after the other is
+// the same as doing the one returned by Then.
+func TestTransform_Then(t *testing.T) {
+	points := []blueprint_schema.FixedPosition{
+		{X: 256, Y: 0},
+		{X: 100, Y: -300},
+		{X: -7, Y: 512},
+	}
+	for _, first := range transforms {
+		for _, second := range transforms {
+			both := first.Then(second)
+			for _, p := range points {
+				if got, want := both.Position(p), second.Position(first.Position(p)); got != want {
+					t.Errorf("Bad data for %+v then %+v: want = '%v' got '%v'", first, second, want, got)
+				}
+			}
+			for d := blueprint_schema.DirectionNorth; d <= blueprint_schema.DirectionNorthNorthWest; d++ {
+				if got, want := both.Direction(d), second.Direction(first.Direction(d)); got != want {
+					t.Errorf("Bad data for %+v then %+v: want = '%v' got '%v'", first, second, want, got)
+				}
+			}
+			for _, o := range []float64{0, 0.125, 0.25, 0.5625} {
+				if got, want := both.Orientation(o), second.Orientation(first.Orientation(o)); got != want {
+					t.Errorf("Bad data for %+v then %+v: want = '%v' got '%v'", first, second, want, got)
+				}
+			}
+		}
+	}
+}
+
+// TestApply_roundTrip tests that transforms are undone by their reverse.
+func TestApply_roundTrip(t *testing.T) {
+	for _, tr := range transforms {
+		bp := setupTransformBlueprint()
+		if err := Apply(bp, tr, nil); err != nil {
+			t.Fatalf("Apply(%+v) failed: %v", tr, err)
+		}
+		reverse := Transform{QuarterTurns: -tr.QuarterTurns}.Then(Transform{MirrorX: tr.MirrorX, MirrorY: tr.MirrorY})
+		if err := Apply(bp, reverse, nil); err != nil {
+			t.Fatalf("Apply(%+v) failed: %v", reverse, err)
+		}
+
+		got, _ := json.Marshal(bp)
+		want, _ := json.Marshal(setupTransformBlueprint())
+		if string(got) != string(want) {
+			t.Errorf("Bad data for %+v: want = '%s' got '%s'", tr, want, got)
+		}
+	}
+}
+
+// TestApply_mirrorFlag tests that the mirror flag of Factorio 2.0 is set on
+// assembling machines, and written out.
+func TestApply_mirrorFlag(t *testing.T) {
+	bp := setupTransformBlueprint()
+	bp.Entities[3].Mirror = nil
+	if err := Apply(bp, Transform{MirrorY: true}, nil); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	got, _ := json.Marshal(bp.Entities[3])
+	if want := `"mirror":true`; !strings.Contains(string(got), want) {
+		t.Errorf("Bad data: want = '%s' got '%s'", want, got)
+	}
+	if bp.Entities[6].Mirror != nil {
+		t.Errorf("Bad data: want = 'no mirror flag on stone-furnace' got '%v'", *bp.Entities[6].Mirror)
+	}
+}
+
+// TestApply_grid tests that entities of every size stay on the tile grid.
+func TestApply_grid(t *testing.T) {
+	r := prototype_blueprint.Vanilla()
+	for _, name := range []string{"splitter", "assembling-machine-1", "stone-furnace", "boiler", "steam-engine", "rocket-silo", "recycler"} {
+		for d := blueprint_schema.DirectionNorth; d < blueprint_schema.DirectionNorthNorthWest; d += blueprint_schema.DirectionEast {
+			raw := int(d)
+			p := r.Prototype(name)
+			w, h := p.Size(d)
+			e := blueprint_schema.Entity{EntityNumber: 1, Name: name, Direction: &raw, Position: blueprint_schema.TilePosition{X: 3, Y: -2}.CenterOf(w, h).Position()}
+			for _, tr := range transforms {
+				bp := &blueprint_schema.Blueprint{Version: blueprint_schema.GameVersion2_0, Entities: []blueprint_schema.Entity{e}}
+				bp.Entities[0].Direction = &raw
+				if err := Apply(bp, tr, r); err != nil {
+					t.Fatalf("Apply(%+v) failed: %v", tr, err)
+				}
+				got := bp.Entities[0].Position.Fixed()
+				w, h := p.Size(bp.EntityDirection(&bp.Entities[0]))
+				if want := got.SnapCenter(w, h); got != want {
+					t.Errorf("Bad data for %s facing %v, %+v: want = '%v' got '%v'", name, d, tr, want, got)
+				}
+			}
+		}
+	}
+}
+
+// TestApply_rails tests the directions of rails and signals of Factorio 1.1
+// when mirrored.
+func TestApply_rails(t *testing.T) {
+	tcs := []struct {
+		name          string
+		direction     int // 8-way
+		mirrorX       int
+		mirrorY       int
+		quarterTurned int
+	}{
+		{"curved-rail", 0, 1, 5, 2},
+		{"curved-rail", 2, 7, 3, 4},
+		{"curved-rail", 5, 4, 0, 7},
+		{"straight-rail", 1, 7, 3, 3},
+		{"straight-rail", 0, 0, 4, 2},
+		{"rail-signal", 0, 4, 0, 2},
+		{"rail-chain-signal", 3, 1, 5, 5},
+		{"train-stop", 2, 2, 6, 4},
+	}
+	for _, tc := range tcs {
+		for _, want := range []struct {
+			tr        Transform
+			direction int
+		}{
+			{Transform{MirrorX: true}, tc.mirrorX},
+			{Transform{MirrorY: true}, tc.mirrorY},
+			{Transform{QuarterTurns: 1}, tc.quarterTurned},
+		} {
+			d := tc.direction
+			bp := &blueprint_schema.Blueprint{
+				Version:  blueprint_schema.GameVersion1_1,
+				Entities: []blueprint_schema.Entity{{EntityNumber: 1, Name: tc.name, Direction: &d, Position: blueprint_schema.Position{X: 1, Y: 1}}},
+			}
+			if err := Apply(bp, want.tr, nil); err != nil {
+				t.Fatalf("Apply(%+v) failed: %v", want.tr, err)
+			}
+			got := 0
+			if bp.Entities[0].Direction != nil {
+				got = *bp.Entities[0].Direction
+			}
+			if got != want.direction {
+				t.Errorf("Bad data for %s %d, %+v: want = '%d' got '%d'", tc.name, tc.direction, want.tr, want.direction, got)
+			}
+		}
+	}
+}
+
+// TestApply_rails2_0 tests the directions of rails of Factorio 2.0 when
+// mirrored.
+func TestApply_rails2_0(t *testing.T) {
+	tcs := []struct {
+		name          string
+		direction     int // 16-way
+		mirrorX       int
+		mirrorY       int
+		quarterTurned int
+	}{
+		{"curved-rail-a", 0, 1, 9, 4},
+		{"curved-rail-a", 3, 14, 6, 7},
+		{"curved-rail-b", 10, 7, 15, 14},
+		{"elevated-curved-rail-a", 1, 0, 8, 5},
+		{"elevated-curved-rail-b", 15, 2, 10, 3},
+		{"half-diagonal-rail", 0, 2, 10, 4},
+		{"half-diagonal-rail", 4, 14, 6, 8},
+		{"elevated-half-diagonal-rail", 12, 6, 14, 0},
+		{"straight-rail", 2, 14, 6, 6},
+		{"rail-signal", 1, 7, 15, 5},
+	}
+	for _, tc := range tcs {
+		for _, want := range []struct {
+			tr        Transform
+			direction int
+		}{
+			{Transform{MirrorX: true}, tc.mirrorX},
+			{Transform{MirrorY: true}, tc.mirrorY},
+			{Transform{QuarterTurns: 1}, tc.quarterTurned},
+			{Transform{MirrorX: true, MirrorY: true}, (tc.direction + 8) % 16},
+		} {
+			d := tc.direction
+			bp := &blueprint_schema.Blueprint{
+				Version:  blueprint_schema.GameVersion2_0,
+				Entities: []blueprint_schema.Entity{{EntityNumber: 1, Name: tc.name, Direction: &d, Position: blueprint_schema.Position{X: 1, Y: 1}}},
+			}
+			if err := Apply(bp, want.tr, nil); err != nil {
+				t.Fatalf("Apply(%+v) failed: %v", want.tr, err)
+			}
+			got := 0
+			if bp.Entities[0].Direction != nil {
+				got = *bp.Entities[0].Direction
+			}
+			if got != want.direction {
+				t.Errorf("Bad data for %s %d, %+v: want = '%d' got '%d'", tc.name, tc.direction, want.tr, want.direction, got)
+			}
+		}
+	}
+}
+
+// TestApply_snapToGrid tests that a blueprint snapping to a grid stays in
+// its grid cell.
+func TestApply_snapToGrid(t *testing.T) {
+	bp := &blueprint_schema.Blueprint{
+		Version:                blueprint_schema.GameVersion2_0,
+		SnapToGrid:             &blueprint_schema.Position{X: 4, Y: 2},
+		PositionRelativeToGrid: &blueprint_schema.Position{X: 1, Y: 0},
+		Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "wooden-chest", Position: blueprint_schema.Position{X: 0.5, Y: 0.5}},
+			{EntityNumber: 2, Name: "iron-chest", Position: blueprint_schema.Position{X: 1.5, Y: 0.5}},
+		},
+	}
+	if err := Apply(bp, Transform{QuarterTurns: 1}, nil); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+
+	want := []blueprint_schema.Position{{X: 0.5, Y: 0.5}, {X: 0.5, Y: 1.5}}
+	for i, e := range bp.Entities {
+		if !reflect.DeepEqual(e.Position, want[i]) {
+			t.Errorf("Bad data for entity %d: want = '%v' got '%v'", e.EntityNumber, want[i], e.Position)
+		}
+	}
+	if want := (blueprint_schema.Position{X: 2, Y: 4}); !reflect.DeepEqual(*bp.SnapToGrid, want) {
+		t.Errorf("Bad data: want = '%v' got '%v'", want, *bp.SnapToGrid)
+	}
+	// The blueprint was in the middle of the top row; it is now in the middle
+	// of the right column.
+	if want := (blueprint_schema.Position{X: 1, Y: 1}); !reflect.DeepEqual(*bp.PositionRelativeToGrid, want) {
+		t.Errorf("Bad data: want = '%v' got '%v'", want, *bp.PositionRelativeToGrid)
+	}
+}
@@ -0,0 +1,408 @@
+// Package validate_blueprint checks blueprint strings and JSON documents
+// against blueprint.schema.json, and reports every violation with a JSON
+// pointer to the value which broke a rule of the schema.
+//
+// Unlike decoding into the structs of the schema, which stops at the first
+// error and only checks a few required fields, validation looks at the whole
+// document:
+//
+//	violations, err := validate_blueprint.ValidateReader(f)
+//	if err != nil {
+//		return err
+//	}
+//	for _, v := range violations {
+//		fmt.Println(v) // /blueprint/entities/17/direction: type: want integer, got string
+//	}
+//
+// The schema keywords used by blueprint.schema.json are supported: $ref
+// within the document, type, enum, properties, additionalProperties,
+// required, items, minItems, maxItems, minimum, maximum, exclusiveMinimum and
+// exclusiveMaximum. Other keywords are ignored.
+//
+// The public interface is unstable.
+package validate_blueprint // badc0de.net/pkg/factorioblueprint/validate_blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"badc0de.net/pkg/factorioblueprint"
+	"badc0de.net/pkg/factorioblueprint/read_blueprint"
+)
+
+// Violation is a value which breaks a rule of the schema.
+type Violation struct {
+	// Pointer is the JSON pointer (RFC 6901) to the value, such as
+	// /blueprint/entities/17/direction. For a missing required property, it
+	// points to where the property should be.
+	Pointer string
+
+	// Rule is the schema keyword which failed, such as type or required.
+	Rule string
+
+	// Message describes the violation.
+	Message string
+}
+
+func (v Violation) String() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%s: %s: %s", pointer, v.Rule, v.Message)
+}
+
+// Schema is a parsed JSON schema.
+type Schema struct {
+	root map[string]interface{}
+}
+
+var defaultSchema = mustNewSchema(factorioblueprint.SchemaJSON)
+
+// DefaultSchema returns blueprint.schema.json.
+func DefaultSchema() *Schema {
+	return defaultSchema
+}
+
+func mustNewSchema(data []byte) *Schema {
+	s, err := NewSchema(data)
+	if err != nil {
+		panic(fmt.Sprintf("validate_blueprint: %v", err))
+	}
+	return s
+}
+
+// NewSchema parses a JSON schema. It returns an error if the schema is not an
+// object, or if it has a $ref which does not resolve.
+func NewSchema(data []byte) (*Schema, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	s := &Schema{root: root}
+	if err := s.checkRefs(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// checkRefs returns an error for the first $ref in node which does not
+// resolve.
+func (s *Schema) checkRefs(node interface{}) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := n["$ref"].(string); ok {
+			if _, err := s.resolve(ref); err != nil {
+				return err
+			}
+		}
+		for _, k := range sortedKeys(n) {
+			if err := s.checkRefs(n[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, v := range n {
+			if err := s.checkRefs(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns the schema a $ref within the schema document points to.
+func (s *Schema) resolve(ref string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("$ref %q: only references within the schema are supported", ref)
+	}
+	var node interface{} = s.root
+	if p := strings.TrimPrefix(ref, "#"); p != "" {
+		for _, token := range strings.Split(strings.TrimPrefix(p, "/"), "/") {
+			obj, ok := node.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("$ref %q does not resolve", ref)
+			}
+			if node, ok = obj[unescape(token)]; !ok {
+				return nil, fmt.Errorf("$ref %q does not resolve", ref)
+			}
+		}
+	}
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("$ref %q is not a schema", ref)
+	}
+	return obj, nil
+}
+
+// Validate checks a JSON document against blueprint.schema.json.
+func Validate(data []byte) ([]Violation, error) {
+	return defaultSchema.Validate(data)
+}
+
+// ValidateReader checks a blueprint string, or a JSON document as accepted by
+// read_blueprint.AsJSONReader, against blueprint.schema.json.
+func ValidateReader(r io.Reader) ([]Violation, error) {
+	decompressed, err := read_blueprint.AsJSONReader(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(decompressed)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(data)
+}
+
+// Validate checks a JSON document against the schema. The error is only set
+// if data is not JSON; violations of the schema are returned in the order
+// they appear in the document, with object keys sorted.
+func (s *Schema) Validate(data []byte) ([]Violation, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return s.ValidateValue(doc), nil
+}
+
+// ValidateValue checks a value decoded from JSON against the schema. Numbers
+// may be float64 or json.Number, as decoded with json.Decoder.UseNumber.
+func (s *Schema) ValidateValue(doc interface{}) []Violation {
+	v := &validator{schema: s}
+	v.validate(s.root, doc, "")
+	return v.violations
+}
+
+// validator collects violations while walking a document.
+type validator struct {
+	schema     *Schema
+	violations []Violation
+}
+
+func (v *validator) report(pointer, rule, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Pointer: pointer, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(schema map[string]interface{}, value interface{}, pointer string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		// Draft 4 ignores the keywords next to $ref.
+		resolved, err := v.schema.resolve(ref)
+		if err != nil {
+			v.report(pointer, "$ref", "%v", err)
+			return
+		}
+		v.validate(resolved, value, pointer)
+		return
+	}
+
+	if t, ok := schema["type"]; ok {
+		if want := typeNames(t); !matchesType(value, want) {
+			v.report(pointer, "type", "want %s, got %s", strings.Join(want, " or "), typeOf(value))
+			// The other keywords would only repeat the same problem.
+			return
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !inEnum(value, enum) {
+		v.report(pointer, "enum", "%s is not one of %s", encode(value), encode(enum))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, val, pointer)
+	case []interface{}:
+		v.validateArray(schema, val, pointer)
+	case json.Number, float64:
+		v.validateNumber(schema, val, pointer)
+	}
+}
+
+func (v *validator) validateObject(schema map[string]interface{}, obj map[string]interface{}, pointer string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				v.report(pointer+"/"+escape(name), "required", "missing required property %s", name)
+			}
+		}
+	}
+	for _, k := range sortedKeys(obj) {
+		if sub, ok := properties[k].(map[string]interface{}); ok {
+			v.validate(sub, obj[k], pointer+"/"+escape(k))
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case map[string]interface{}:
+			v.validate(additional, obj[k], pointer+"/"+escape(k))
+		case bool:
+			if !additional {
+				v.report(pointer+"/"+escape(k), "additionalProperties", "property %s is not allowed", k)
+			}
+		}
+	}
+}
+
+func (v *validator) validateArray(schema map[string]interface{}, arr []interface{}, pointer string) {
+	if n, ok := schema["minItems"].(float64); ok && float64(len(arr)) < n {
+		v.report(pointer, "minItems", "want at least %v items, got %d", n, len(arr))
+	}
+	if n, ok := schema["maxItems"].(float64); ok && float64(len(arr)) > n {
+		v.report(pointer, "maxItems", "want at most %v items, got %d", n, len(arr))
+	}
+	switch items := schema["items"].(type) {
+	case map[string]interface{}:
+		for i, item := range arr {
+			v.validate(items, item, pointer+"/"+strconv.Itoa(i))
+		}
+	case []interface{}:
+		for i, item := range arr {
+			if i >= len(items) {
+				break
+			}
+			if sub, ok := items[i].(map[string]interface{}); ok {
+				v.validate(sub, item, pointer+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+}
+
+func (v *validator) validateNumber(schema map[string]interface{}, value interface{}, pointer string) {
+	n, ok := toFloat(value)
+	if !ok {
+		return
+	}
+	if lo, ok := schema["minimum"].(float64); ok {
+		if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && n <= lo {
+			v.report(pointer, "minimum", "%s is not greater than %v", encode(value), lo)
+		} else if n < lo {
+			v.report(pointer, "minimum", "%s is less than %v", encode(value), lo)
+		}
+	}
+	if hi, ok := schema["maximum"].(float64); ok {
+		if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && n >= hi {
+			v.report(pointer, "maximum", "%s is not less than %v", encode(value), hi)
+		} else if n > hi {
+			v.report(pointer, "maximum", "%s is greater than %v", encode(value), hi)
+		}
+	}
+}
+
+// typeNames returns the types allowed by the type keyword, which is either a
+// single type or an array of them.
+func typeNames(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var names []string
+		for _, name := range t {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func matchesType(value interface{}, types []string) bool {
+	got := typeOf(value)
+	for _, t := range types {
+		if t == got || (t == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON schema type of a value decoded from JSON. Numbers
+// without a fractional part are integers.
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number, float64:
+		if n, ok := value.(json.Number); ok {
+			if _, err := n.Int64(); err == nil {
+				return "integer"
+			}
+		}
+		if f, ok := toFloat(value); ok && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, e := range enum {
+		if f, ok := toFloat(value); ok {
+			if g, ok := e.(float64); ok && f == g {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(value, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// encode returns the JSON of a value, for messages.
+func encode(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escape escapes a JSON pointer reference token.
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// unescape reverses escape.
+func unescape(token string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+}
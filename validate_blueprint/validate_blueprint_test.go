@@ -0,0 +1,103 @@
+package validate_blueprint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Example of validating a JSON document with a few mistakes.
+func ExampleValidate() {
+	violations, err := Validate([]byte(`{"blueprint": {
+		"item": "blueprint",
+		"version": "1.1",
+		"entities": [{"entity_number": 1, "position": {"x": 0.5, "y": "a"}}],
+		"wires": [[1, 2, 3]]
+	}}`))
+	if err != nil {
+		panic(err)
+	}
+	for _, v := range violations {
+		fmt.Println(v)
+	}
+
+	// Output:
+	// /blueprint/icons: required: missing required property icons
+	// /blueprint/entities/0/name: required: missing required property name
+	// /blueprint/entities/0/position/y: type: want number, got string
+	// /blueprint/version: type: want integer, got string
+	// /blueprint/wires/0: minItems: want at least 4 items, got 3
+}
+
+// TestValidateReader_corpus checks that the blueprints used in tests of other
+// packages follow the schema.
+func TestValidateReader_corpus(t *testing.T) {
+	files, err := filepath.Glob("../read_blueprint/testdata/lossless/*.json")
+	if err != nil {
+		t.Fatalf("Failed to list test data: %v", err)
+	}
+	files = append(files, "../read_blueprint/simple.txt", "../migrate_blueprint/testdata/library_1_1.json")
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file, err)
+		}
+		violations, err := ValidateReader(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("ValidateReader(%s) failed: %v", file, err)
+		}
+		if len(violations) != 0 {
+			t.Fatalf("Bad data: want no violations in %s got '%v'", file, violations)
+		}
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := NewSchema([]byte(`{
+		"type": "object",
+		"properties": {
+			"a/b": {"$ref": "#/definitions/small"},
+			"mode": {"type": "string", "enum": ["on", "off"]},
+			"counts": {"type": "object", "additionalProperties": {"type": "integer"}},
+			"closed": {"type": "object", "additionalProperties": false}
+		},
+		"definitions": {
+			"small": {"type": "number", "minimum": 0, "maximum": 1, "exclusiveMaximum": true}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("NewSchema() failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		doc  string
+		want []string
+	}{
+		{`{"a/b": 0.5, "mode": "on", "counts": {"x": 1}, "closed": {}}`, nil},
+		{`{"a/b": 1}`, []string{"/a~1b: maximum: 1 is not less than 1"}},
+		{`{"a/b": -2}`, []string{"/a~1b: minimum: -2 is less than 0"}},
+		{`{"mode": "auto"}`, []string{`/mode: enum: "auto" is not one of ["on","off"]`}},
+		{`{"counts": {"x": 1.5}}`, []string{"/counts/x: type: want integer, got number"}},
+		{`{"closed": {"x": 1}}`, []string{"/closed/x: additionalProperties: property x is not allowed"}},
+		{`[]`, []string{"/: type: want object, got array"}},
+	} {
+		violations, err := s.Validate([]byte(tc.doc))
+		if err != nil {
+			t.Fatalf("Validate(%s) failed: %v", tc.doc, err)
+		}
+		got := fmt.Sprint(violations)
+		if want := fmt.Sprint(tc.want); got != want && !(len(tc.want) == 0 && len(violations) == 0) {
+			t.Fatalf("Bad data: want = '%v' got '%v'", want, got)
+		}
+	}
+}
+
+func TestNewSchema_badRef(t *testing.T) {
+	_, err := NewSchema([]byte(`{"properties": {"a": {"$ref": "#/definitions/missing"}}}`))
+	if err == nil || !strings.Contains(err.Error(), "does not resolve") {
+		t.Fatalf("Bad data: want ref error got '%v'", err)
+	}
+}
@@ -0,0 +1,349 @@
+// Package wire_blueprint turns the different ways wires are stored in a
+// blueprint into a single list of wires, and back.
+//
+// Factorio 1.1 stores circuit wires in the connections of each entity, once on
+// each end of the wire, and copper wires between poles in the neighbours of
+// each entity. Power switches keep their copper wires in the Cu0 and Cu1
+// connections instead. Factorio 2.0 stores all wires in a single wires array
+// on the blueprint, as tuples of [entity, connector, entity, connector].
+//
+// The public interface is unstable.
+package wire_blueprint // badc0de.net/pkg/factorioblueprint/wire_blueprint
+
+import (
+	"fmt"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// Connector identifies a point on an entity a wire can be attached to. The
+// values match defines.wire_connector_id of Factorio 2.0, which is what the
+// wires array uses.
+type Connector int
+
+const (
+	ConnectorCircuitRed             Connector = 1
+	ConnectorCircuitGreen           Connector = 2
+	ConnectorCombinatorInputRed     Connector = 1
+	ConnectorCombinatorInputGreen   Connector = 2
+	ConnectorCombinatorOutputRed    Connector = 3
+	ConnectorCombinatorOutputGreen  Connector = 4
+	ConnectorPoleCopper             Connector = 5
+	ConnectorPowerSwitchLeftCopper  Connector = 5
+	ConnectorPowerSwitchRightCopper Connector = 6
+)
+
+// WireType is the kind of wire attached to a connector.
+type WireType int
+
+const (
+	WireTypeUnknown WireType = iota
+	WireTypeRed
+	WireTypeGreen
+	WireTypeCopper
+)
+
+// String returns a human readable name of the wire type.
+func (t WireType) String() string {
+	switch t {
+	case WireTypeRed:
+		return "red"
+	case WireTypeGreen:
+		return "green"
+	case WireTypeCopper:
+		return "copper"
+	default:
+		return fmt.Sprintf("WireType(%d)", int(t))
+	}
+}
+
+// WireType returns the kind of wire which can be attached to the connector.
+func (c Connector) WireType() WireType {
+	switch c {
+	case ConnectorCircuitRed, ConnectorCombinatorOutputRed:
+		return WireTypeRed
+	case ConnectorCircuitGreen, ConnectorCombinatorOutputGreen:
+		return WireTypeGreen
+	case ConnectorPoleCopper, ConnectorPowerSwitchRightCopper:
+		return WireTypeCopper
+	default:
+		return WireTypeUnknown
+	}
+}
+
+// End is one end of a wire.
+type End struct {
+	EntityNumber int
+	Connector    Connector
+}
+
+// less orders ends by entity number, then by connector.
+func (e End) less(o End) bool {
+	if e.EntityNumber != o.EntityNumber {
+		return e.EntityNumber < o.EntityNumber
+	}
+	return e.Connector < o.Connector
+}
+
+// Wire connects two connectors. Wires are not directed: a wire from A to B is
+// the same wire as the one from B to A.
+type Wire struct {
+	A, B End
+}
+
+// Type returns the kind of the wire, based on the connector of its first end.
+func (w Wire) Type() WireType {
+	return w.A.Connector.WireType()
+}
+
+// normalized returns the wire with the lesser end first, so that the same wire
+// read from either end compares equal.
+func (w Wire) normalized() Wire {
+	if w.B.less(w.A) {
+		return Wire{A: w.B, B: w.A}
+	}
+	return w
+}
+
+// String returns the wire in the same shape as used in the 2.0 wires array.
+func (w Wire) String() string {
+	return fmt.Sprintf("[%d, %d, %d, %d]", w.A.EntityNumber, w.A.Connector, w.B.EntityNumber, w.B.Connector)
+}
+
+// FromBlueprint returns all wires in the blueprint, no matter whether they are
+// stored in the 2.0 wires array or in the 1.1 connections and neighbours of
+// entities. Every wire is returned once, in the order it was first seen.
+func FromBlueprint(bp *blueprint_schema.Blueprint) ([]Wire, error) {
+	var wires []Wire
+	seen := make(map[Wire]bool)
+	add := func(w Wire) {
+		if n := w.normalized(); !seen[n] {
+			seen[n] = true
+			wires = append(wires, w)
+		}
+	}
+
+	for i, tuple := range bp.Wires {
+		if len(tuple) != 4 {
+			return nil, fmt.Errorf("wire %d: got %d values, want 4", i, len(tuple))
+		}
+		add(Wire{
+			A: End{EntityNumber: tuple[0], Connector: Connector(tuple[1])},
+			B: End{EntityNumber: tuple[2], Connector: Connector(tuple[3])},
+		})
+	}
+
+	for _, e := range bp.Entities {
+		for _, neighbour := range e.Neighbours {
+			add(Wire{
+				A: End{EntityNumber: e.EntityNumber, Connector: ConnectorPoleCopper},
+				B: End{EntityNumber: neighbour, Connector: ConnectorPoleCopper},
+			})
+		}
+
+		if e.Connections == nil {
+			continue
+		}
+		for circuitID, point := range []*blueprint_schema.ConnectionPoint{e.Connections.A1, e.Connections.A2} {
+			if point == nil {
+				continue
+			}
+			for _, data := range point.Red {
+				add(Wire{
+					A: End{EntityNumber: e.EntityNumber, Connector: circuitConnector(circuitID+1, WireTypeRed)},
+					B: End{EntityNumber: data.EntityID, Connector: circuitConnector(circuitIDOf(data), WireTypeRed)},
+				})
+			}
+			for _, data := range point.Green {
+				add(Wire{
+					A: End{EntityNumber: e.EntityNumber, Connector: circuitConnector(circuitID+1, WireTypeGreen)},
+					B: End{EntityNumber: data.EntityID, Connector: circuitConnector(circuitIDOf(data), WireTypeGreen)},
+				})
+			}
+		}
+		for _, data := range e.Connections.Cu0 {
+			add(Wire{
+				A: End{EntityNumber: e.EntityNumber, Connector: ConnectorPowerSwitchLeftCopper},
+				B: End{EntityNumber: data.EntityID, Connector: copperConnectorOf(data)},
+			})
+		}
+		for _, data := range e.Connections.Cu1 {
+			add(Wire{
+				A: End{EntityNumber: e.EntityNumber, Connector: ConnectorPowerSwitchRightCopper},
+				B: End{EntityNumber: data.EntityID, Connector: copperConnectorOf(data)},
+			})
+		}
+	}
+
+	return wires, nil
+}
+
+// circuitIDOf returns the circuit connector ID of the connected entity, which
+// defaults to 1 when not set.
+func circuitIDOf(data blueprint_schema.ConnectionData) int {
+	if data.CircuitID == nil {
+		return 1
+	}
+	return *data.CircuitID
+}
+
+// copperConnectorOf returns the copper connector of the entity at the far end
+// of a copper wire of a power switch. The wire ID is the side of that entity
+// if it is a power switch too: 0 for the left side, which shares its ID with
+// the connector of poles, and 1 for the right side.
+func copperConnectorOf(data blueprint_schema.ConnectionData) Connector {
+	if data.WireID != nil && *data.WireID == 1 {
+		return ConnectorPowerSwitchRightCopper
+	}
+	return ConnectorPoleCopper
+}
+
+// circuitConnector returns the connector for a 1.1 circuit connector ID (1 for
+// input or the only point, 2 for combinator output) and wire type.
+func circuitConnector(circuitID int, t WireType) Connector {
+	c := ConnectorCircuitRed
+	if t == WireTypeGreen {
+		c = ConnectorCircuitGreen
+	}
+	if circuitID == 2 {
+		c += 2 // combinator output
+	}
+	return c
+}
+
+// circuitIDAndType is the inverse of circuitConnector.
+func circuitIDAndType(c Connector) (int, WireType) {
+	switch c {
+	case ConnectorCircuitRed:
+		return 1, WireTypeRed
+	case ConnectorCircuitGreen:
+		return 1, WireTypeGreen
+	case ConnectorCombinatorOutputRed:
+		return 2, WireTypeRed
+	case ConnectorCombinatorOutputGreen:
+		return 2, WireTypeGreen
+	default:
+		return 0, WireTypeUnknown
+	}
+}
+
+// SetWires stores the wires in the 2.0 wires array of the blueprint, and
+// removes connections and neighbours from all entities.
+func SetWires(bp *blueprint_schema.Blueprint, wires []Wire) {
+	bp.Wires = nil
+	for _, w := range wires {
+		bp.Wires = append(bp.Wires, blueprint_schema.Wire{
+			w.A.EntityNumber, int(w.A.Connector), w.B.EntityNumber, int(w.B.Connector),
+		})
+	}
+	for i := range bp.Entities {
+		bp.Entities[i].Connections = nil
+		bp.Entities[i].Neighbours = nil
+	}
+}
+
+// SetConnections stores the wires in the 1.1 connections and neighbours of the
+// entities, and removes the 2.0 wires array from the blueprint. Circuit wires
+// and copper wires between poles are recorded on both of their ends, as the
+// game does.
+//
+// Copper connectors of poles and of the left side of power switches share the
+// same ID. Entities named "power-switch" are treated as power switches, and
+// their copper wires are stored in Cu0 and Cu1, with the side of the entity at
+// the other end as wire ID. A copper wire between two power switches is
+// recorded on both, one to a pole only on the switch.
+func SetConnections(bp *blueprint_schema.Blueprint, wires []Wire) error {
+	bp.Wires = nil
+
+	byNumber := make(map[int]*blueprint_schema.Entity, len(bp.Entities))
+	for i := range bp.Entities {
+		bp.Entities[i].Connections = nil
+		bp.Entities[i].Neighbours = nil
+		byNumber[bp.Entities[i].EntityNumber] = &bp.Entities[i]
+	}
+
+	for _, w := range wires {
+		a, b := byNumber[w.A.EntityNumber], byNumber[w.B.EntityNumber]
+		if a == nil || b == nil {
+			return fmt.Errorf("wire %v: refers to an entity not in the blueprint", w)
+		}
+
+		if w.Type() == WireTypeCopper {
+			if !isPowerSwitch(a) && !isPowerSwitch(b) {
+				a.Neighbours = append(a.Neighbours, w.B.EntityNumber)
+				b.Neighbours = append(b.Neighbours, w.A.EntityNumber)
+				continue
+			}
+			if isPowerSwitch(a) {
+				addCopperToSwitch(a, w.A.Connector, w.B)
+			}
+			if isPowerSwitch(b) {
+				addCopperToSwitch(b, w.B.Connector, w.A)
+			}
+			continue
+		}
+
+		if err := addCircuit(a, w.A, w.B); err != nil {
+			return fmt.Errorf("wire %v: %w", w, err)
+		}
+		if err := addCircuit(b, w.B, w.A); err != nil {
+			return fmt.Errorf("wire %v: %w", w, err)
+		}
+	}
+	return nil
+}
+
+// isPowerSwitch returns whether the entity keeps its copper wires in Cu0 and
+// Cu1 rather than in neighbours.
+func isPowerSwitch(e *blueprint_schema.Entity) bool {
+	return e.Name == "power-switch"
+}
+
+// addCopperToSwitch records a copper wire from connector c of a power switch
+// to the other end.
+func addCopperToSwitch(e *blueprint_schema.Entity, c Connector, other End) {
+	if e.Connections == nil {
+		e.Connections = &blueprint_schema.Connection{}
+	}
+	wireID := 0
+	if other.Connector == ConnectorPowerSwitchRightCopper {
+		wireID = 1
+	}
+	data := blueprint_schema.ConnectionData{EntityID: other.EntityNumber, WireID: &wireID}
+	if c == ConnectorPowerSwitchRightCopper {
+		e.Connections.Cu1 = append(e.Connections.Cu1, data)
+	} else {
+		e.Connections.Cu0 = append(e.Connections.Cu0, data)
+	}
+}
+
+// addCircuit records a circuit wire on the entity at the 'from' end.
+func addCircuit(e *blueprint_schema.Entity, from, to End) error {
+	fromID, fromType := circuitIDAndType(from.Connector)
+	toID, toType := circuitIDAndType(to.Connector)
+	if fromType == WireTypeUnknown || toType != fromType {
+		return fmt.Errorf("connectors %d and %d are not a circuit wire of one color", from.Connector, to.Connector)
+	}
+
+	if e.Connections == nil {
+		e.Connections = &blueprint_schema.Connection{}
+	}
+	point := &e.Connections.A1
+	if fromID == 2 {
+		point = &e.Connections.A2
+	}
+	if *point == nil {
+		*point = &blueprint_schema.ConnectionPoint{}
+	}
+
+	data := blueprint_schema.ConnectionData{EntityID: to.EntityNumber}
+	if toID != 1 {
+		data.CircuitID = &toID
+	}
+	if fromType == WireTypeRed {
+		(*point).Red = append((*point).Red, data)
+	} else {
+		(*point).Green = append((*point).Green, data)
+	}
+	return nil
+}
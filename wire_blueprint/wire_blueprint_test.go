@@ -0,0 +1,221 @@
+package wire_blueprint
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+)
+
+// setupConnectionsBlueprint creates a blueprint with wires in the Factorio 1.1
+// form.
+//
+// Two poles are connected with copper, the second pole is connected to the
+// input of an arithmetic combinator with red wire, the output of the combinator
+// is connected to a lamp with green wire, and a power switch is connected to
+// the second pole on its left side.
+func setupConnectionsBlueprint() *blueprint_schema.Blueprint {
+	ptrInt := func(i int) *int { return &i }
+	return &blueprint_schema.Blueprint{
+		Item:    "blueprint",
+		Version: 281479273986304,
+		Entities: []blueprint_schema.Entity{
+			{
+				EntityNumber: 1,
+				Name:         "small-electric-pole",
+				Neighbours:   []int{2},
+			},
+			{
+				EntityNumber: 2,
+				Name:         "small-electric-pole",
+				Neighbours:   []int{1},
+				Connections: &blueprint_schema.Connection{
+					A1: &blueprint_schema.ConnectionPoint{
+						Red: []blueprint_schema.ConnectionData{{EntityID: 3, CircuitID: ptrInt(1)}},
+					},
+				},
+			},
+			{
+				EntityNumber: 3,
+				Name:         "arithmetic-combinator",
+				Connections: &blueprint_schema.Connection{
+					A1: &blueprint_schema.ConnectionPoint{
+						Red: []blueprint_schema.ConnectionData{{EntityID: 2}},
+					},
+					A2: &blueprint_schema.ConnectionPoint{
+						Green: []blueprint_schema.ConnectionData{{EntityID: 4}},
+					},
+				},
+			},
+			{
+				EntityNumber: 4,
+				Name:         "small-lamp",
+				Connections: &blueprint_schema.Connection{
+					A1: &blueprint_schema.ConnectionPoint{
+						Green: []blueprint_schema.ConnectionData{{EntityID: 3, CircuitID: ptrInt(2)}},
+					},
+				},
+			},
+			{
+				EntityNumber: 5,
+				Name:         "power-switch",
+				Connections: &blueprint_schema.Connection{
+					Cu0: []blueprint_schema.ConnectionData{{EntityID: 2, WireID: ptrInt(0)}},
+				},
+			},
+		},
+	}
+}
+
+// sortedWires returns the wires in a stable order for comparison.
+func sortedWires(wires []Wire) []Wire {
+	out := make([]Wire, len(wires))
+	for i, w := range wires {
+		out[i] = w.normalized()
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].A != out[j].A {
+			return out[i].A.less(out[j].A)
+		}
+		return out[i].B.less(out[j].B)
+	})
+	return out
+}
+
+// Example of reading wires of a 1.1 blueprint and storing them in the 2.0 form.
+func ExampleSetWires() {
+	bp := setupConnectionsBlueprint()
+
+	wires, err := FromBlueprint(bp)
+	if err != nil {
+		panic(err)
+	}
+	for _, w := range wires {
+		fmt.Printf("%v %v\n", w.Type(), w)
+	}
+
+	SetWires(bp, wires)
+	fmt.Println(bp.Wires)
+
+	// Output:
+	// copper [1, 5, 2, 5]
+	// red [2, 1, 3, 1]
+	// green [3, 4, 4, 2]
+	// copper [5, 5, 2, 5]
+	// [[1 5 2 5] [2 1 3 1] [3 4 4 2] [5 5 2 5]]
+}
+
+// TestSetConnections checks that converting to the 2.0 form and back to the 1.1
+// form keeps the same wires.
+func TestSetConnections(t *testing.T) {
+	bp := setupConnectionsBlueprint()
+	want, err := FromBlueprint(bp)
+	if err != nil {
+		t.Fatalf("FromBlueprint() failed: %v", err)
+	}
+
+	SetWires(bp, want)
+	for _, e := range bp.Entities {
+		if e.Connections != nil || e.Neighbours != nil {
+			t.Errorf("entity %d still has 1.1 wires after SetWires", e.EntityNumber)
+		}
+	}
+	got, err := FromBlueprint(bp)
+	if err != nil {
+		t.Fatalf("FromBlueprint() after SetWires failed: %v", err)
+	}
+	if !reflect.DeepEqual(sortedWires(got), sortedWires(want)) {
+		t.Errorf("wires after SetWires: got %v, want %v", got, want)
+	}
+
+	if err := SetConnections(bp, got); err != nil {
+		t.Fatalf("SetConnections() failed: %v", err)
+	}
+	if bp.Wires != nil {
+		t.Errorf("blueprint still has 2.0 wires after SetConnections: %v", bp.Wires)
+	}
+	got, err = FromBlueprint(bp)
+	if err != nil {
+		t.Fatalf("FromBlueprint() after SetConnections failed: %v", err)
+	}
+	if !reflect.DeepEqual(sortedWires(got), sortedWires(want)) {
+		t.Errorf("wires after SetConnections: got %v, want %v", got, want)
+	}
+
+	// Circuit wires are recorded on both ends, like the game does.
+	if c := bp.Entities[3].Connections; c == nil || c.A1 == nil || len(c.A1.Green) != 1 || c.A1.Green[0].EntityID != 3 {
+		t.Errorf("lamp connections: got %+v", c)
+	}
+	// The power switch keeps its copper wire in Cu0, the pole does not list it.
+	if c := bp.Entities[4].Connections; c == nil || len(c.Cu0) != 1 || c.Cu0[0].EntityID != 2 {
+		t.Errorf("power switch connections: got %+v", c)
+	}
+	if n := bp.Entities[1].Neighbours; !reflect.DeepEqual(n, []int{1}) {
+		t.Errorf("pole neighbours: got %v, want [1]", n)
+	}
+}
+
+// TestSetConnections_powerSwitches checks that copper wires of power switches
+// keep their sides, whether they go to a pole or to another switch.
+func TestSetConnections_powerSwitches(t *testing.T) {
+	ptrInt := func(i int) *int { return &i }
+	// Pole 1 is on the left side of switch 2, pole 4 on the right side of
+	// switch 3. The switches are wired left to right and right to left.
+	bp := &blueprint_schema.Blueprint{
+		Item:    "blueprint",
+		Version: 281479273986304,
+		Entities: []blueprint_schema.Entity{
+			{EntityNumber: 1, Name: "small-electric-pole"},
+			{
+				EntityNumber: 2,
+				Name:         "power-switch",
+				Connections: &blueprint_schema.Connection{
+					Cu0: []blueprint_schema.ConnectionData{{EntityID: 1, WireID: ptrInt(0)}, {EntityID: 3, WireID: ptrInt(1)}},
+					Cu1: []blueprint_schema.ConnectionData{{EntityID: 3, WireID: ptrInt(0)}},
+				},
+			},
+			{
+				EntityNumber: 3,
+				Name:         "power-switch",
+				Connections: &blueprint_schema.Connection{
+					Cu0: []blueprint_schema.ConnectionData{{EntityID: 2, WireID: ptrInt(1)}},
+					Cu1: []blueprint_schema.ConnectionData{{EntityID: 2, WireID: ptrInt(0)}, {EntityID: 4, WireID: ptrInt(0)}},
+				},
+			},
+			{EntityNumber: 4, Name: "small-electric-pole"},
+		},
+	}
+	want := make([]*blueprint_schema.Connection, len(bp.Entities))
+	for i, e := range bp.Entities {
+		want[i] = e.Connections
+	}
+
+	wires, err := FromBlueprint(bp)
+	if err != nil {
+		t.Fatalf("FromBlueprint() failed: %v", err)
+	}
+	if got, want := fmt.Sprint(wires), "[[2, 5, 1, 5] [2, 5, 3, 6] [2, 6, 3, 5] [3, 6, 4, 5]]"; got != want {
+		t.Fatalf("Bad data: want = '%s' got '%s'", want, got)
+	}
+
+	if err := SetConnections(bp, wires); err != nil {
+		t.Fatalf("SetConnections() failed: %v", err)
+	}
+	for i, e := range bp.Entities {
+		if !reflect.DeepEqual(e.Connections, want[i]) || e.Neighbours != nil {
+			t.Errorf("Bad data for entity %d: want = '%+v' got '%+v' and neighbours '%v'", e.EntityNumber, want[i], e.Connections, e.Neighbours)
+		}
+	}
+}
+
+// TestFromBlueprint_badWire checks that malformed wire tuples are reported.
+func TestFromBlueprint_badWire(t *testing.T) {
+	bp := &blueprint_schema.Blueprint{
+		Wires: []blueprint_schema.Wire{{1, 1, 2}},
+	}
+	if _, err := FromBlueprint(bp); err == nil {
+		t.Errorf("FromBlueprint() with a 3-tuple wire: got no error")
+	}
+}
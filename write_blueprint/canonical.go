@@ -0,0 +1,205 @@
+package write_blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"badc0de.net/pkg/factorioblueprint/book_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+
+	"github.com/klauspost/compress/zlib"
+)
+
+// canonicalLevel is the zlib compression level of canonical strings.
+const canonicalLevel = zlib.BestCompression
+
+// FromStructCanonical works like FromStructLossless, but writes the canonical
+// string of the blueprint, so that the same design always yields the same
+// string and strings can be compared by hash:
+//
+//   - blueprints are put in canonical form by Canonicalize;
+//   - object keys are sorted and there is no insignificant whitespace;
+//   - the JSON is compressed at a fixed level.
+//
+// m is not changed.
+func FromStructCanonical(w io.Writer, m blueprint_schema.BlueprintSchemaJSON) error {
+	b, err := CanonicalJSON(m)
+	if err != nil {
+		return err
+	}
+
+	encoder := &blueprintEncoder{w: w, level: canonicalLevel}
+	if _, err := encoder.Write(b); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to close: %w", err)
+	}
+	return nil
+}
+
+// CanonicalJSON returns the JSON written by FromStructCanonical, before
+// compression. m is not changed.
+func CanonicalJSON(m blueprint_schema.BlueprintSchemaJSON) ([]byte, error) {
+	// Work on a copy.
+	b, err := blueprint_schema.MarshalLossless(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	var c blueprint_schema.BlueprintSchemaJSON
+	if err := blueprint_schema.UnmarshalLossless(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to copy blueprint: %w", err)
+	}
+	if err := Canonicalize(&c); err != nil {
+		return nil, err
+	}
+	if b, err = blueprint_schema.MarshalLossless(c); err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	// Sort the object keys by going through generic JSON, keeping the text
+	// of numbers.
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	var out bytes.Buffer
+	e := json.NewEncoder(&out)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+// Canonicalize puts every blueprint in m, including those in books, in
+// canonical form:
+//
+//   - entities are sorted by position, top to bottom and then left to right,
+//     then by name, and numbered again from 1;
+//   - tiles are sorted the same way;
+//   - wires, connections and neighbours are sorted, with the lower end of each
+//     wire first;
+//   - positions are rounded to 1/256 of a tile, the precision of the game,
+//     and -0 becomes 0.
+func Canonicalize(m *blueprint_schema.BlueprintSchemaJSON) error {
+	return book_blueprint.Walk(m, func(leaf book_blueprint.Leaf) error {
+		if leaf.Blueprint == nil {
+			return nil
+		}
+		if err := CanonicalizeBlueprint(leaf.Blueprint); err != nil {
+			if len(leaf.Path) > 0 {
+				return fmt.Errorf("%v: %w", leaf.Path, err)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// CanonicalizeBlueprint puts a single blueprint in canonical form, as
+// described for Canonicalize.
+func CanonicalizeBlueprint(bp *blueprint_schema.Blueprint) error {
+	for i := range bp.Entities {
+		normalizePosition(&bp.Entities[i].Position)
+	}
+	for i := range bp.Tiles {
+		normalizePosition(&bp.Tiles[i].Position)
+	}
+
+	sort.SliceStable(bp.Entities, func(i, j int) bool {
+		a, b := &bp.Entities[i], &bp.Entities[j]
+		if a.Position.Y != b.Position.Y {
+			return a.Position.Y < b.Position.Y
+		}
+		if a.Position.X != b.Position.X {
+			return a.Position.X < b.Position.X
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.EntityNumber < b.EntityNumber
+	})
+	sort.SliceStable(bp.Tiles, func(i, j int) bool {
+		a, b := &bp.Tiles[i], &bp.Tiles[j]
+		if a.Position.Y != b.Position.Y {
+			return a.Position.Y < b.Position.Y
+		}
+		if a.Position.X != b.Position.X {
+			return a.Position.X < b.Position.X
+		}
+		return a.Name < b.Name
+	})
+
+	numbers := make(map[int]int, len(bp.Entities))
+	for i, e := range bp.Entities {
+		if _, ok := numbers[e.EntityNumber]; ok {
+			return fmt.Errorf("entity number %d is used twice", e.EntityNumber)
+		}
+		numbers[e.EntityNumber] = i + 1
+	}
+	if err := bp.RenumberEntities(numbers); err != nil {
+		return err
+	}
+
+	for i := range bp.Entities {
+		e := &bp.Entities[i]
+		sort.Ints(e.Neighbours)
+		if c := e.Connections; c != nil {
+			for _, p := range []*blueprint_schema.ConnectionPoint{c.A1, c.A2} {
+				if p != nil {
+					sortConnectionData(p.Red)
+					sortConnectionData(p.Green)
+				}
+			}
+			sortConnectionData(c.Cu0)
+			sortConnectionData(c.Cu1)
+		}
+	}
+
+	for _, w := range bp.Wires {
+		if w[0] > w[2] || (w[0] == w[2] && w[1] > w[3]) {
+			w[0], w[1], w[2], w[3] = w[2], w[3], w[0], w[1]
+		}
+	}
+	sort.Slice(bp.Wires, func(i, j int) bool {
+		a, b := bp.Wires[i], bp.Wires[j]
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// normalizePosition rounds p to 1/256 of a tile, and turns -0 into 0.
+func normalizePosition(p *blueprint_schema.Position) {
+	fixed := p.Fixed().Position()
+	p.X, p.Y = fixed.X, fixed.Y
+}
+
+func sortConnectionData(data []blueprint_schema.ConnectionData) {
+	key := func(p *int) int {
+		if p == nil {
+			return -1
+		}
+		return *p
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		a, b := &data[i], &data[j]
+		if a.EntityID != b.EntityID {
+			return a.EntityID < b.EntityID
+		}
+		if key(a.CircuitID) != key(b.CircuitID) {
+			return key(a.CircuitID) < key(b.CircuitID)
+		}
+		return key(a.WireID) < key(b.WireID)
+	})
+}
@@ -2,8 +2,13 @@ package write_blueprint
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"sync"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
 
 	"github.com/klauspost/compress/zlib"
 )
@@ -11,6 +16,10 @@ import (
 type blueprintEncoder struct {
 	w io.Writer
 
+	b64 io.WriteCloser // b64 is the base64 encoder writing into w.
+	zw  *zlib.Writer   // zw is the zlib compressor writing into b64.
+
+	level        int // level is the zlib compression level.
 	wroteVersion bool
 }
 
@@ -25,28 +34,64 @@ func (b *blueprintEncoder) Write(p []byte) (n int, err error) {
 
 		// This means we did not wrap the passed writer yet.
 		// Build a compressor and wrap it with encoder.
-		b.w = base64.NewEncoder(base64.StdEncoding, b.w)
-		b.w = zlib.NewWriter(b.w)
+		b.b64 = base64.NewEncoder(base64.StdEncoding, b.w)
+		b.zw, err = newZlibWriter(b.b64, b.level)
+		if err != nil {
+			return 0, fmt.Errorf("failed to start compressing with zlib: %w", err)
+		}
+	}
+
+	if b.zw == nil {
+		return 0, errors.New("write after close")
 	}
 
 	// Pass the rest of the data through the compression and encoding.
-	return b.w.Write(p)
+	return b.zw.Write(p)
 }
 
+// Close finalizes the zlib stream and then the base64 encoding, which may
+// still hold up to two bytes that do not fill a full base64 quantum. The
+// underlying writer is not closed.
 func (b *blueprintEncoder) Close() error {
-	// See if our writer is a closer.
-	if c, ok := b.w.(io.Closer); ok {
-		return c.Close()
+	if !b.wroteVersion || b.zw == nil {
+		return nil
+	}
+	if err := b.zw.Close(); err != nil {
+		return fmt.Errorf("failed to close zlib writer: %w", err)
+	}
+	zlibWriters[b.level-zlib.HuffmanOnly].Put(b.zw)
+	b.zw = nil
+	if err := b.b64.Close(); err != nil {
+		return fmt.Errorf("failed to close base64 encoder: %w", err)
 	}
 	return nil
 }
 
+// Flush flushes the zlib stream. Bytes that do not fill a full base64 quantum
+// are held back until Close.
 func (b *blueprintEncoder) Flush() error {
-	// See if our writer is a flusher.
-	if f, ok := b.w.(Flusher); ok {
-		return f.Flush()
+	if !b.wroteVersion || b.zw == nil {
+		return nil
 	}
-	return nil
+	return b.zw.Flush()
+}
+
+// zlibWriters holds zlib writers for reuse, one pool for each compression
+// level from HuffmanOnly to BestCompression, as each writer allocates buffers
+// of hundreds of kilobytes.
+var zlibWriters [zlib.BestCompression - zlib.HuffmanOnly + 1]sync.Pool
+
+// newZlibWriter returns a zlib writer into w, reusing one from zlibWriters if
+// there is one. It is put back into zlibWriters by Close.
+func newZlibWriter(w io.Writer, level int) (*zlib.Writer, error) {
+	if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+		return nil, fmt.Errorf("invalid compression level %d", level)
+	}
+	if zw, ok := zlibWriters[level-zlib.HuffmanOnly].Get().(*zlib.Writer); ok {
+		zw.Reset(w)
+		return zw, nil
+	}
+	return zlib.NewWriterLevel(w, level)
 }
 
 type Flusher interface {
@@ -69,8 +114,28 @@ type WriteCloseFlusher interface {
 // prefix rune / byte), even if you otherwise do not close or flush the writer.
 //
 // Close is more likely to work; please use it unless you really can't do
-// without it. Encode->Decode tests did not work when Flush was used.
+// without it. Flush only flushes zlib: base64 can only encode full groups of
+// three bytes, so up to two trailing bytes stay buffered until Close, and the
+// string read after a Flush is usually not decodable.
 func AsStringWriter(w io.Writer) WriteCloseFlusher {
 	// The actual wrapping is done in the blueprintEncoder's Writer.
-	return &blueprintEncoder{w: w}
+	return &blueprintEncoder{w: w, level: zlib.DefaultCompression}
+}
+
+// FromJSON reads blueprint JSON from r, decodes it into the blueprint schema
+// and writes the blueprint string to w.
+//
+// Both raw and pretty-printed JSON are accepted. Decoding into the schema
+// checks required fields. Fields unknown to the schema are kept and written
+// out again.
+func FromJSON(w io.Writer, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read JSON: %w", err)
+	}
+	var m blueprint_schema.BlueprintSchemaJSON
+	if err := blueprint_schema.UnmarshalLossless(data, &m); err != nil {
+		return fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return FromStructLossless(w, m)
 }
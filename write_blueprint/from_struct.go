@@ -19,11 +19,38 @@ func FromStruct(w io.Writer, m blueprint_schema.BlueprintSchemaJSON) error {
 		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
 
-	// Flush or close in order for zlib to encode the block. Flush should be
-	// enough.
-	if err := encoder.Flush(); err != nil {
-		return fmt.Errorf("failed to flush: %w", err)
+	// Close in order for zlib to encode the final block and for base64 to
+	// write out the last partial quantum. Flush is not enough.
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to close: %w", err)
 	}
 
 	return nil
 }
+
+// FromStructLossless works like FromStruct, but also writes out the fields
+// which are not known to the schema, as kept by
+// read_blueprint.AsStructLossless or blueprint_schema.UnmarshalLossless.
+func FromStructLossless(w io.Writer, m blueprint_schema.BlueprintSchemaJSON) error {
+	b, err := blueprint_schema.MarshalLossless(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	encoder := AsStringWriter(w)
+	if _, err := encoder.Write(b); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to close: %w", err)
+	}
+
+	return nil
+}
+
+// FromBlueprint writes a single blueprint, which is not in a book, to the
+// writer like FromStructLossless does, such as one put together by
+// compose_blueprint.
+func FromBlueprint(w io.Writer, bp *blueprint_schema.Blueprint) error {
+	return FromStructLossless(w, blueprint_schema.BlueprintSchemaJSON{Blueprint: bp})
+}
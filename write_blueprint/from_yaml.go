@@ -0,0 +1,22 @@
+package write_blueprint
+
+import (
+	"fmt"
+	"io"
+
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FromYAML reads a blueprint in YAML format from r, such as the one printed by
+// blueprintread -fmt=yaml, decodes it into the blueprint schema and writes the
+// blueprint string to w. Fields unknown to the schema are kept and written out
+// again.
+func FromYAML(w io.Writer, r io.Reader) error {
+	var m blueprint_schema.BlueprintSchemaJSON
+	if err := yaml.NewDecoder(r).Decode(&m); err != nil {
+		return fmt.Errorf("failed to decode YAML: %w", err)
+	}
+	return FromStructLossless(w, m)
+}
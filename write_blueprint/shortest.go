@@ -0,0 +1,160 @@
+package write_blueprint
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"badc0de.net/pkg/factorioblueprint/book_blueprint"
+	"badc0de.net/pkg/factorioblueprint/schema/blueprint_schema"
+
+	"github.com/klauspost/compress/zlib"
+)
+
+// shortestLevels are the zlib compression levels FromStructShortest tries.
+// Huffman only coding and no compression at all are included, as they are
+// sometimes shorter for tiny blueprints.
+var shortestLevels = []int{
+	zlib.BestCompression, 8, 7, 6, 5, 4, 3, 2, zlib.BestSpeed,
+	zlib.HuffmanOnly, zlib.NoCompression,
+}
+
+// SizeReport tells how much shorter FromStructShortest made a string.
+type SizeReport struct {
+	// DefaultLength is the length of the string FromStructLossless writes.
+	DefaultLength int
+
+	// Length is the length of the string written.
+	Length int
+
+	// Level is the zlib compression level which gave the shortest string.
+	Level int
+}
+
+// Saved returns how many bytes shorter the string is than the default one.
+func (r SizeReport) Saved() int {
+	return r.DefaultLength - r.Length
+}
+
+func (r SizeReport) String() string {
+	return fmt.Sprintf("%d chars, %d saved of %d", r.Length, r.Saved(), r.DefaultLength)
+}
+
+// FromStructShortest works like FromStructLossless, but writes the shortest
+// string it can find, such as for posts with a length limit. Fields which are
+// equal to their defaults in the game are dropped as by DropDefaults, and
+// every compression level of zlib is tried.
+//
+// m is not changed.
+func FromStructShortest(w io.Writer, m blueprint_schema.BlueprintSchemaJSON) (SizeReport, error) {
+	var report SizeReport
+
+	var def bytes.Buffer
+	if err := FromStructLossless(&def, m); err != nil {
+		return report, err
+	}
+	report.DefaultLength = def.Len()
+
+	// Work on a copy.
+	b, err := blueprint_schema.MarshalLossless(m)
+	if err != nil {
+		return report, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	var c blueprint_schema.BlueprintSchemaJSON
+	if err := blueprint_schema.UnmarshalLossless(b, &c); err != nil {
+		return report, fmt.Errorf("failed to copy blueprint: %w", err)
+	}
+	if err := DropDefaults(&c); err != nil {
+		return report, err
+	}
+	if b, err = blueprint_schema.MarshalLossless(c); err != nil {
+		return report, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	var shortest []byte
+	for _, level := range shortestLevels {
+		var buf bytes.Buffer
+		encoder := &blueprintEncoder{w: &buf, level: level}
+		if _, err := encoder.Write(b); err != nil {
+			return report, fmt.Errorf("failed to write: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return report, fmt.Errorf("failed to close: %w", err)
+		}
+		if shortest == nil || buf.Len() < len(shortest) {
+			shortest = buf.Bytes()
+			report.Level = level
+		}
+	}
+	// Never do worse than the default string.
+	if len(shortest) > def.Len() {
+		shortest = def.Bytes()
+		report.Level = zlib.DefaultCompression
+	}
+	report.Length = len(shortest)
+
+	if _, err := w.Write(shortest); err != nil {
+		return report, fmt.Errorf("failed to write: %w", err)
+	}
+	return report, nil
+}
+
+// DropDefaults removes fields which are equal to their defaults in the game
+// from every blueprint in m, including those in books:
+//
+//   - direction 0 and normal quality of entities;
+//   - empty items, request filters, tags and control behavior;
+//   - the alpha of colors when it is 1.
+func DropDefaults(m *blueprint_schema.BlueprintSchemaJSON) error {
+	return book_blueprint.Walk(m, func(leaf book_blueprint.Leaf) error {
+		if leaf.Blueprint != nil {
+			return DropDefaultsBlueprint(leaf.Blueprint)
+		}
+		return nil
+	})
+}
+
+// DropDefaultsBlueprint removes fields which are equal to their defaults from
+// a single blueprint, as described for DropDefaults.
+func DropDefaultsBlueprint(bp *blueprint_schema.Blueprint) error {
+	dropAlpha(bp.LabelColor)
+	for i := range bp.Entities {
+		e := &bp.Entities[i]
+		if e.Direction != nil && *e.Direction == 0 {
+			e.Direction = nil
+		}
+		if e.Quality != nil && e.Quality.IsNormal() {
+			e.Quality = nil
+		}
+		if e.RecipeQuality != nil && e.RecipeQuality.IsNormal() {
+			e.RecipeQuality = nil
+		}
+		if e.Items != nil && len(e.Items.Requests) == 0 && len(e.Items.InsertPlans) == 0 {
+			e.Items = nil
+		}
+		if e.RequestFilters != nil && len(e.RequestFilters.Filters) == 0 && e.RequestFilters.Sections == nil {
+			e.RequestFilters = nil
+		}
+		if len(e.Tags) == 0 {
+			e.Tags = nil
+		}
+		if e.ControlBehavior != nil {
+			b, err := blueprint_schema.MarshalLossless(e.ControlBehavior)
+			if err != nil {
+				return fmt.Errorf("entity %d: %w", e.EntityNumber, err)
+			}
+			if string(b) == "{}" {
+				e.ControlBehavior = nil
+			}
+		}
+		dropAlpha(e.Color)
+	}
+	return nil
+}
+
+// dropAlpha removes the alpha of c if it is opaque, which is the default.
+func dropAlpha(c *blueprint_schema.Color) {
+	if c != nil && c.A != nil && *c.A == 1 {
+		c.A = nil
+	}
+}
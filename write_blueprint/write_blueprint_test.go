@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 
 	"badc0de.net/pkg/factorioblueprint/read_blueprint"
@@ -55,7 +56,7 @@ func ExampleFromStruct() {
 	fmt.Print(buf.String())
 
 	// Output:
-	// 0eJx0zk1qAzEMBeB9j/HWLkx+yI+WvUYpxU5EEXhkYyslg/Hdy8Sb2XQnnqSP1xDig3MRNVADq4kJV9Bnw10K30ySgk5urJZvfcyBC2jnoH5mEKx4rTkVew8cDQ45VRlvDU/Q5LCApt6/HOSWdOCid36+mCo/6uN6/B9oS15zMZ4xmHWiTXWH6ANHED422S+X+uqxv+yO5+v+fLheTofp2PvbHwAAAP//
+	// 0eJx0zk1qAzEMBeB9j/HWLkx+yI+WvUYpxU5EEXhkYyslg/Hdy8Sb2XQnntCn1xDig3MRNVADq4kJV9Bnw10K30ySgk5urJZvfcyBC2jnoH5mEKx4rTkVew8cDQ45VRlnDU/Q5LCApt6/HOSWdOCid36+mCo/6uP6/T/QlrzmYjxjMOtEm+oO0QeOIHxssl8u9dVjf9kdz9f9+XC9nA7Tsfe3vwEAVixSVA==
 }
 
 // Example of encoding an existing string into a blueprint string. It will only
@@ -91,8 +92,8 @@ func ExampleAsStringWriter() {
 		panic(err)
 	}
 
-	// Mandatory flush or close before reading the buffer.
-	if err := encoder.Flush(); err != nil {
+	// Mandatory close before reading the buffer.
+	if err := encoder.Close(); err != nil {
 		panic(err)
 	}
 
@@ -100,7 +101,7 @@ func ExampleAsStringWriter() {
 	fmt.Print(buf.String())
 
 	// Output:
-	// 0eJxckN1q8zAMho/tqzDvcT5I2tIfHX63McZIWjEEjhNsdTQY3/twmsK2Ex08D9IrKVuDwd95jhIUZI1zuRbnwEFFhRPoLVtjXmT5CPdx4AjqmicO/cggaOxDmqeo/wb2ik3OUxKVKYAyHqC2wQJqy2ZvEvn61MeVlPdqINcp1OCVZST5DL0HuQxdZgY5iPKI5hXu/qaXxkHCjR8g1xVrzDa3dtGPi1fq+4E9CP9/4y+OaV1td+4Op8vutL+cj/v2UP9TbPkGAAD/
+	// 0eJxcj01qwzAQRtfSKYZv7YKdhPzMstcopdjJUAZk2UhKiRG6e7FjQ5uNFu+heTPZGnTuLmNQn8DWEOX5IYL4pEklgj+yNWYj05e/950EcFM9sW97ASOF1sdxCOmtE5ewynGImnTw4IwHuK4wgeuy2psGuT71cSHlczbQ6+Dn8MIyon771oEpI02jgAmapEe1xem1XiqC+ps8wNQUa8w6d/7Ffy5eqGs7cWC8/8c/EuKy2u7cHE6X3Wl/OR/39cEaomLL7wAuhFcU
 }
 
 // Example of how to construct human-readable blueprint JSON string from a
@@ -216,3 +217,243 @@ func TestDecodeable(t *testing.T) {
 		t.Fatalf("decoded string is different: %v", string(b))
 	}
 }
+
+// Test that YAML as printed by blueprintread -fmt=yaml can be turned back into
+// a blueprint string.
+func TestFromYAML(t *testing.T) {
+	// Blueprint in the same shape as printed by yaml.Marshal of the schema.
+	const blueprint = `blueprint:
+    entities:
+        - direction: 6
+          entity_number: 1
+          name: transport-belt
+          position:
+            x: 0
+            "y": 0
+    icons:
+        - index: 1
+          signal:
+            name: transport-belt
+            type: item
+    item: blueprint
+    label: Blueprint
+    version: 281479273986304
+`
+
+	var buf bytes.Buffer
+	if err := FromYAML(&buf, strings.NewReader(blueprint)); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	// Read this back.
+	decompressed, err := read_blueprint.AsJSONReader(&buf)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	m, err := read_blueprint.AsStruct(decompressed)
+	if err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+
+	if m.Blueprint == nil {
+		t.Fatalf("no blueprint in decoded data")
+	}
+	if m.Blueprint.Label == nil || *m.Blueprint.Label != "Blueprint" {
+		t.Errorf("Bad data: want = 'Blueprint' got '%v'", m.Blueprint.Label)
+	}
+	if len(m.Blueprint.Entities) != 1 || m.Blueprint.Entities[0].Name != "transport-belt" {
+		t.Fatalf("Bad data: want one 'transport-belt' got '%v'", m.Blueprint.Entities)
+	}
+	if d := m.Blueprint.Entities[0].Direction; d == nil || *d != 6 {
+		t.Errorf("Bad data: want direction = 6 got '%v'", d)
+	}
+	if m.Blueprint.Version != 281479273986304 {
+		t.Errorf("Bad data: want version = 281479273986304 got '%v'", m.Blueprint.Version)
+	}
+}
+
+// Test that required fields are checked when encoding from JSON.
+func TestFromJSON_missingRequired(t *testing.T) {
+	const blueprint = `{"blueprint": {"item": "blueprint", "entities": [], "version": 1}}`
+
+	var buf bytes.Buffer
+	if err := FromJSON(&buf, strings.NewReader(blueprint)); err == nil {
+		t.Fatalf("expected error for missing icons, got string %q", buf.String())
+	}
+}
+
+// Example of the canonical JSON of a blueprint: entities are sorted and
+// numbered again, wires follow them, and keys are sorted.
+func ExampleCanonicalJSON() {
+	var m blueprint_schema.BlueprintSchemaJSON
+	err := blueprint_schema.UnmarshalLossless([]byte(`{"blueprint": {
+		"item": "blueprint", "version": 562949954076673, "icons": [],
+		"entities": [
+			{"entity_number": 7, "name": "small-lamp", "position": {"x": 2.5, "y": 0.5}},
+			{"entity_number": 3, "name": "constant-combinator", "position": {"x": 0.5, "y": -0.0}}
+		],
+		"wires": [[7, 1, 3, 1]]
+	}}`), &m)
+	if err != nil {
+		panic(err)
+	}
+
+	b, err := CanonicalJSON(m)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(b))
+
+	// Output:
+	// {"blueprint":{"entities":[{"entity_number":1,"name":"constant-combinator","position":{"x":0.5,"y":0}},{"entity_number":2,"name":"small-lamp","position":{"x":2.5,"y":0.5}}],"icons":[],"item":"blueprint","version":562949954076673,"wires":[[1,1,2,1]]}}
+}
+
+// TestFromStructCanonical checks that the same design, written differently,
+// gives the same string.
+func TestFromStructCanonical(t *testing.T) {
+	decode := func(data string) blueprint_schema.BlueprintSchemaJSON {
+		t.Helper()
+		var m blueprint_schema.BlueprintSchemaJSON
+		if err := blueprint_schema.UnmarshalLossless([]byte(data), &m); err != nil {
+			t.Fatalf("Failed to decode JSON: %v", err)
+		}
+		return m
+	}
+	a := decode(`{"blueprint": {"item": "blueprint", "version": 281479278886912, "icons": [], "entities": [
+		{"entity_number": 1, "name": "inserter", "position": {"x": 1.5, "y": 0.5}, "neighbours": [3, 2]},
+		{"entity_number": 2, "name": "small-electric-pole", "position": {"x": 0.5, "y": 0.5}, "neighbours": [1]},
+		{"entity_number": 3, "name": "small-electric-pole", "position": {"x": 0.5, "y": 2.5}, "neighbours": [1]}
+	], "tiles": [{"name": "stone-path", "position": {"x": 1, "y": 0}}, {"name": "stone-path", "position": {"x": 0, "y": 0}}]}}`)
+	b := decode(`{"blueprint": {
+		"icons": [],
+		"entities": [
+			{"position": {"y": 2.5, "x": 0.5}, "name": "small-electric-pole", "entity_number": 10, "neighbours": [30]},
+			{"position": {"y": 0.5000001, "x": 0.5}, "name": "small-electric-pole", "entity_number": 20, "neighbours": [30]},
+			{"position": {"y": 0.5, "x": 1.5}, "name": "inserter", "entity_number": 30, "neighbours": [20, 10]}
+		],
+		"tiles": [{"name": "stone-path", "position": {"x": 0, "y": -0.0}}, {"name": "stone-path", "position": {"x": 1, "y": 0}}],
+		"item": "blueprint", "version": 281479278886912
+	}}`)
+
+	var bufA, bufB bytes.Buffer
+	if err := FromStructCanonical(&bufA, a); err != nil {
+		t.Fatalf("FromStructCanonical() failed: %v", err)
+	}
+	if err := FromStructCanonical(&bufB, b); err != nil {
+		t.Fatalf("FromStructCanonical() failed: %v", err)
+	}
+	if bufA.String() != bufB.String() {
+		t.Fatalf("Bad data: want the same string got '%v' and '%v'", bufA.String(), bufB.String())
+	}
+	if b.Blueprint.Entities[0].EntityNumber != 10 {
+		t.Fatalf("Bad data: FromStructCanonical changed its argument: '%+v'", b.Blueprint.Entities[0])
+	}
+
+	m, err := read_blueprint.AsStruct(mustDecompress(t, &bufA))
+	if err != nil {
+		t.Fatalf("Failed to decode canonical string: %v", err)
+	}
+	if e := m.Blueprint.Entities[1]; e.Name != "inserter" || fmt.Sprint(e.Neighbours) != "[1 3]" {
+		t.Fatalf("Bad data: want inserter 2 with neighbours [1 3] got '%+v'", e)
+	}
+}
+
+func mustDecompress(t *testing.T, r io.Reader) io.Reader {
+	t.Helper()
+	decompressed, err := read_blueprint.AsJSONReader(r)
+	if err != nil {
+		t.Fatalf("Failed to decompress JSON: %v", err)
+	}
+	return decompressed
+}
+
+// TestFromStructShortest checks that defaults are dropped and that the string
+// is not longer than the default one.
+func TestFromStructShortest(t *testing.T) {
+	var m blueprint_schema.BlueprintSchemaJSON
+	err := blueprint_schema.UnmarshalLossless([]byte(`{"blueprint": {"item": "blueprint", "version": 562949954076673, "icons": [],
+		"label_color": {"r": 1, "g": 0.5, "b": 0, "a": 1},
+		"entities": [
+			{"entity_number": 1, "name": "inserter", "position": {"x": 0.5, "y": 0.5}, "direction": 0, "quality": "normal", "control_behavior": {}, "tags": {}},
+			{"entity_number": 2, "name": "inserter", "position": {"x": 1.5, "y": 0.5}, "direction": 4, "quality": "rare"}
+		]}}`), &m)
+	if err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	var buf bytes.Buffer
+	report, err := FromStructShortest(&buf, m)
+	if err != nil {
+		t.Fatalf("FromStructShortest() failed: %v", err)
+	}
+	if report.Length != buf.Len() || report.Saved() <= 0 {
+		t.Fatalf("Bad data: want a shorter string of %d chars got '%v'", buf.Len(), report)
+	}
+	if m.Blueprint.Entities[0].Direction == nil {
+		t.Fatalf("Bad data: FromStructShortest changed its argument")
+	}
+
+	got, err := read_blueprint.AsStructLossless(mustDecompress(t, &buf))
+	if err != nil {
+		t.Fatalf("Failed to decode shortest string: %v", err)
+	}
+	e := got.Blueprint.Entities[0]
+	if e.Direction != nil || e.Quality != nil || e.ControlBehavior != nil || e.Tags != nil {
+		t.Fatalf("Bad data: want defaults dropped got '%+v'", e)
+	}
+	if e := got.Blueprint.Entities[1]; *e.Direction != 4 || *e.Quality != blueprint_schema.QualityRare {
+		t.Fatalf("Bad data: want direction and quality kept got '%+v'", e)
+	}
+	if c := got.Blueprint.LabelColor; c.A != nil || c.G != 0.5 {
+		t.Fatalf("Bad data: want label color without alpha got '%+v'", c)
+	}
+}
+
+// FuzzAsStringWriter checks that any data written into a blueprint string,
+// in chunks of any size, is read back unchanged.
+func FuzzAsStringWriter(f *testing.F) {
+	f.Add([]byte(`{"blueprint": {"item": "blueprint", "icons": [], "version": 1}}`), 3)
+	f.Add([]byte("not JSON at all"), 1)
+	f.Add([]byte{}, 0)
+	f.Add(bytes.Repeat([]byte("ab"), 10000), 4096)
+	f.Fuzz(func(t *testing.T, data []byte, chunk int) {
+		if chunk <= 0 {
+			chunk = len(data) + 1
+		}
+
+		var buf bytes.Buffer
+		encoder := AsStringWriter(&buf)
+		for rest := data; len(rest) > 0; {
+			n := chunk
+			if n > len(rest) {
+				n = len(rest)
+			}
+			if _, err := encoder.Write(rest[:n]); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			rest = rest[n:]
+		}
+		if err := encoder.Close(); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		if len(data) == 0 {
+			// Nothing written, not even the version byte.
+			if buf.Len() != 0 {
+				t.Fatalf("Bad data: want empty string got '%s'", buf.Bytes())
+			}
+			return
+		}
+
+		decompressed, err := read_blueprint.AsJSONReader(&buf)
+		if err != nil {
+			t.Fatalf("Failed to decompress: %v", err)
+		}
+		got, err := io.ReadAll(decompressed)
+		if err != nil {
+			t.Fatalf("Failed to read decompressed data: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Bad data: want = '%q' got '%q'", data, got)
+		}
+	})
+}